@@ -0,0 +1,233 @@
+package gosqm
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/blang/gosqm/sqm"
+	"io"
+	"sort"
+	"strings"
+)
+
+// AddonDB maps lowercase classnames to the CfgPatches class of the addon
+// defining them.
+type AddonDB map[string]string
+
+// Add maps a classname to a CfgPatches class.
+func (db AddonDB) Add(classname, patch string) {
+	db[strings.ToLower(classname)] = patch
+}
+
+// Lookup returns the CfgPatches class of a classname, case insensitive.
+func (db AddonDB) Lookup(classname string) (string, bool) {
+	patch, found := db[strings.ToLower(classname)]
+	return patch, found
+}
+
+// ReadAddonDB reads a database with one classname and its CfgPatches class
+// per line, separated by whitespace. Empty lines and lines starting with #
+// are ignored.
+func ReadAddonDB(r io.Reader) (AddonDB, error) {
+	db := make(AddonDB)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Line %d: expected classname and CfgPatches class, got %q", line, text)
+		}
+		db.Add(fields[0], fields[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Write writes the database in the format read by ReadAddonDB, sorted by
+// classname.
+func (db AddonDB) Write(w io.Writer) error {
+	classnames := make([]string, 0, len(db))
+	for classname := range db {
+		classnames = append(classnames, classname)
+	}
+	sort.Strings(classnames)
+	for _, classname := range classnames {
+		if _, err := fmt.Fprintf(w, "%s %s\n", classname, db[classname]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddConfig adds the units[] and weapons[] of every addon in the CfgPatches
+// class of config. config is the base class returned by the sqm parser.
+func (db AddonDB) AddConfig(config *sqm.Class) {
+	for _, c := range config.Classes {
+		if !strings.EqualFold(c.Name, "CfgPatches") {
+			continue
+		}
+		for _, patch := range c.Classes {
+			for _, arrprop := range patch.Arrprops {
+				switch strings.ToLower(arrprop.Name) {
+				case "units", "weapons":
+					for _, classname := range arrprop.Values {
+						db.Add(classname, patch.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// AddConfigSource adds the addons of a config.cpp. Only its CfgPatches
+// class is parsed, after comments and preprocessor lines are removed.
+// Macros inside CfgPatches and inheritance are not supported.
+func (db AddonDB) AddConfigSource(src string) error {
+	patches := extractClass(stripComments(src), "CfgPatches")
+	if patches == "" {
+		return fmt.Errorf("No CfgPatches class found")
+	}
+	class, err := sqm.MakeParser(patches).Run()
+	if err != nil {
+		return err
+	}
+	db.AddConfig(class)
+	return nil
+}
+
+// stripComments removes comments and preprocessor lines outside of strings
+func stripComments(src string) string {
+	var buf strings.Builder
+	inString, lineStart := false, true
+	for i := 0; i < len(src); i++ {
+		ch := src[i]
+		switch {
+		case inString:
+			if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case lineStart && ch == '#', ch == '/' && strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			if i < len(src) {
+				buf.WriteByte('\n')
+			}
+			lineStart = true
+			continue
+		case ch == '/' && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return buf.String()
+			}
+			i += end + 3
+			buf.WriteByte(' ')
+			continue
+		}
+		buf.WriteByte(ch)
+		if ch == '\n' {
+			lineStart = true
+		} else if ch != ' ' && ch != '\t' && ch != '\r' {
+			lineStart = false
+		}
+	}
+	return buf.String()
+}
+
+// extractClass returns the definition of the first class named name
+// including its closing semicolon, or an empty string if there is none
+func extractClass(src, name string) string {
+	lower := strings.ToLower(src)
+	needle := "class " + strings.ToLower(name)
+	for offset := 0; ; {
+		i := strings.Index(lower[offset:], needle)
+		if i < 0 {
+			return ""
+		}
+		start := offset + i
+		rest := strings.TrimLeft(src[start+len(needle):], " \t\r\n")
+		if !strings.HasPrefix(rest, "{") {
+			offset = start + len(needle)
+			continue
+		}
+		open := len(src) - len(rest)
+		depth, inString := 0, false
+		for j := open; j < len(src); j++ {
+			switch ch := src[j]; {
+			case inString:
+				if ch == '"' {
+					inString = false
+				}
+			case ch == '"':
+				inString = true
+			case ch == '{':
+				depth++
+			case ch == '}':
+				depth--
+				if depth == 0 {
+					return src[start:j+1] + ";"
+				}
+			}
+		}
+		return ""
+	}
+}
+
+// UpdateAddons rewrites Addons and AddonsAuto of every stage to the
+// CfgPatches classes of the vehicles used in it, in order of first use.
+// It returns the sorted classnames which are not in db. Listed addons
+// unknown to db are always kept after them, as they might be required by
+// the terrain, markers or classnames missing from db.
+func (mf *MissionFile) UpdateAddons(db AddonDB) []string {
+	known := make(map[string]bool)
+	for _, patch := range db {
+		known[strings.ToLower(patch)] = true
+	}
+	unmapped := make(map[string]bool)
+	for _, stage := range mf.Stages() {
+		m := stage.Mission
+		addons := vehicleAddons(m.allVehicles(), db, unmapped)
+		for _, list := range [][]string{m.Addons, m.AddonsAuto} {
+			for _, patch := range list {
+				if !known[strings.ToLower(patch)] {
+					addons = mergeAddons(addons, []string{patch})
+				}
+			}
+		}
+		m.Addons = addons
+		m.AddonsAuto = append([]string(nil), addons...)
+	}
+	return sortedClassnames(unmapped)
+}
+
+// vehicleAddons returns the CfgPatches classes of the vehicles in order of
+// first use and adds the classnames missing in db to unmapped
+func vehicleAddons(vehicles []*Vehicle, db AddonDB, unmapped map[string]bool) []string {
+	var addons []string
+	for _, v := range vehicles {
+		if v.Classname == "" {
+			continue
+		}
+		if patch, found := db.Lookup(v.Classname); found {
+			addons = mergeAddons(addons, []string{patch})
+		} else {
+			unmapped[v.Classname] = true
+		}
+	}
+	return addons
+}
+
+func sortedClassnames(set map[string]bool) []string {
+	var classnames []string
+	for classname := range set {
+		classnames = append(classnames, classname)
+	}
+	sort.Strings(classnames)
+	return classnames
+}
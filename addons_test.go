@@ -0,0 +1,105 @@
+package gosqm
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+const addonConfig = `#include "script_component.hpp"
+// units of the addon
+class CfgPatches {
+	class my_units {
+		units[] = {"My_Soldier", "My_Car"}; /* vehicles */
+		weapons[] = {};
+		requiredAddons[] = {"CAData"};
+		author = "http://example.com/{";
+	};
+};
+class CfgVehicles {
+	class Car;
+	class My_Car: Car {};
+};
+`
+
+func TestAddonDB(t *testing.T) {
+	Convey("Given a database file", t, func() {
+		db, err := ReadAddonDB(strings.NewReader("# comment\nUSMC_Soldier CA_Characters2\n\nHMMWV   CA_Wheeled\n"))
+		So(err, ShouldBeNil)
+
+		Convey("Classnames are looked up case insensitive", func() {
+			patch, found := db.Lookup("usmc_soldier")
+			So(found, ShouldBeTrue)
+			So(patch, ShouldEqual, "CA_Characters2")
+			_, found = db.Lookup("Unknown")
+			So(found, ShouldBeFalse)
+		})
+		Convey("It is written sorted", func() {
+			var buf bytes.Buffer
+			So(db.Write(&buf), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "hmmwv CA_Wheeled\nusmc_soldier CA_Characters2\n")
+		})
+	})
+	Convey("Given an invalid database file", t, func() {
+		_, err := ReadAddonDB(strings.NewReader("USMC_Soldier\n"))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "Line 1")
+	})
+	Convey("Given a config.cpp", t, func() {
+		db := make(AddonDB)
+		So(db.AddConfigSource(addonConfig), ShouldBeNil)
+
+		Convey("Units of CfgPatches are added", func() {
+			So(db, ShouldResemble, AddonDB{"my_soldier": "my_units", "my_car": "my_units"})
+		})
+	})
+	Convey("Given a config.cpp without CfgPatches", t, func() {
+		So(make(AddonDB).AddConfigSource("class CfgVehicles {};"), ShouldNotBeNil)
+	})
+}
+
+func TestUpdateAddons(t *testing.T) {
+	Convey("Given a mission file and a database", t, func() {
+		db := AddonDB{"usmc_soldier": "CA_Characters2", "hmmwv": "CA_Wheeled", "t72": "CA_Tracked"}
+		mf := &MissionFile{
+			Mission: &Mission{
+				Addons:     []string{"CA_Tracked", "CA_Characters2", "my_mod"},
+				AddonsAuto: []string{"CA_Tracked"},
+				Groups: []*Group{
+					&Group{Units: []*Vehicle{&Vehicle{Classname: "USMC_Soldier"}, &Vehicle{Classname: "USMC_Soldier"}}},
+				},
+				Vehicles: []*Vehicle{&Vehicle{Classname: "HMMWV"}},
+			},
+			Intro: &Mission{
+				Addons:   []string{"my_mod", "CA_Tracked"},
+				Vehicles: []*Vehicle{&Vehicle{Classname: "My_Soldier"}, &Vehicle{Classname: "HMMWV"}},
+			},
+		}
+
+		Convey("Addons are set to the used addons", func() {
+			unmapped := mf.UpdateAddons(db)
+			So(mf.Mission.Addons, ShouldResemble, []string{"CA_Characters2", "CA_Wheeled", "my_mod"})
+			So(mf.Mission.AddonsAuto, ShouldResemble, []string{"CA_Characters2", "CA_Wheeled", "my_mod"})
+
+			Convey("Unknown addons are kept", func() {
+				So(unmapped, ShouldResemble, []string{"My_Soldier"})
+				So(mf.Intro.Addons, ShouldResemble, []string{"CA_Wheeled", "my_mod"})
+			})
+		})
+	})
+	Convey("Given a mission whose classnames all map", t, func() {
+		db := AddonDB{"hmmwv": "CA_Wheeled", "t72": "CA_Tracked"}
+		mf := &MissionFile{
+			Mission: &Mission{
+				Addons:   []string{"takistan", "CA_Tracked", "CA_Wheeled"},
+				Vehicles: []*Vehicle{&Vehicle{Classname: "HMMWV"}},
+			},
+		}
+
+		Convey("The world addon is kept and unused known addons are dropped", func() {
+			So(mf.UpdateAddons(db), ShouldBeEmpty)
+			So(mf.Mission.Addons, ShouldResemble, []string{"CA_Wheeled", "takistan"})
+		})
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/blang/gosqm"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// addonsCmd sets the addons of a mission file to the ones its vehicles
+// need. The exit code is 1 if a classname is missing from the database.
+var addonsCmd = &command{
+	name:  "addons",
+	usage: addonsUsage,
+	run:   runAddons,
+}
+
+const addonsUsage = "addons -db <file>[,<file>] [-w] [-format text|binary|json] <file>  set addOns from a database or config.cpp files"
+
+func runAddons(args []string) int {
+	fs := flag.NewFlagSet("addons", flag.ExitOnError)
+	f := addCommonFlags(fs, "text", "binary", "json")
+	dbFlag := fs.String("db", "", "comma separated addon databases or config.cpp files")
+	writeFlag := fs.Bool("w", false, "write result to the mission file instead of stdout")
+	fs.Parse(args)
+	if fs.NArg() != 1 || *dbFlag == "" {
+		return usageError(addonsUsage)
+	}
+	if err := f.check(); err != nil {
+		return fail("%s", err)
+	}
+	db := make(gosqm.AddonDB)
+	for _, filename := range strings.Split(*dbFlag, ",") {
+		part, err := readAddonDB(filename)
+		if err != nil {
+			return fail("Could not read %s: %s", filename, err)
+		}
+		for classname, patch := range part {
+			db[classname] = patch
+		}
+	}
+
+	filename := fs.Arg(0)
+	mf, _, err := f.readMissionFile(filename)
+	if err != nil {
+		return fail("Could not read %s: %s", filename, err)
+	}
+	unmapped := mf.UpdateAddons(db)
+
+	data, err := encodeClass(gosqm.NewEncoder(nil).EncodeToClass(mf), f.format)
+	if err != nil {
+		return fail("Could not encode %s: %s", filename, err)
+	}
+	if *writeFlag {
+		f.output = filename
+	}
+	if err := f.write(data); err != nil {
+		return fail("Could not write %s: %s", f.output, err)
+	}
+
+	for _, classname := range unmapped {
+		fmt.Fprintf(os.Stderr, "Unmapped classname %s\n", classname)
+	}
+	if len(unmapped) > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// readAddonDB reads an addon database, files ending with .cpp are read as
+// addon configs
+func readAddonDB(filename string) (gosqm.AddonDB, error) {
+	if strings.HasSuffix(strings.ToLower(filename), ".cpp") {
+		buf, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		db := make(gosqm.AddonDB)
+		return db, db.AddConfigSource(string(buf))
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return gosqm.ReadAddonDB(f)
+}
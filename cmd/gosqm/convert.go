@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+)
+
+// convertCmd converts mission files between the text, binarized and JSON
+// formats, the input format is detected.
+var convertCmd = &command{
+	name:  "convert",
+	usage: convertUsage,
+	run:   runConvert,
+}
+
+const convertUsage = "convert -format text|binary|json [-o file] <file>  convert between text, binarized and JSON mission files"
+
+func runConvert(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	f := addCommonFlags(fs, "text", "binary", "json")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError(convertUsage)
+	}
+	if err := f.check(); err != nil {
+		return fail("%s", err)
+	}
+	filename := fs.Arg(0)
+	class, err := f.readInput(filename)
+	if err != nil {
+		return fail("Could not read %s: %s", filename, err)
+	}
+	data, err := encodeClass(class, f.format)
+	if err != nil {
+		return fail("Could not encode %s: %s", filename, err)
+	}
+	if err := f.write(data); err != nil {
+		return fail("Could not write %s: %s", f.output, err)
+	}
+	return exitOK
+}
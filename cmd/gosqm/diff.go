@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"github.com/blang/gosqm"
+)
+
+// diffCmd prints the entity level changes between two versions of a
+// mission. The exit code is 1 if they differ, like diff(1).
+var diffCmd = &command{
+	name:  "diff",
+	usage: diffUsage,
+	run:   runDiff,
+}
+
+const diffUsage = "diff [-format text|json] <old> <new>  print added, removed and changed entities, exit code 1 if they differ"
+
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	f := addCommonFlags(fs, "text", "json")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return usageError(diffUsage)
+	}
+	if err := f.check(); err != nil {
+		return fail("%s", err)
+	}
+	var versions [2]*gosqm.MissionFile
+	for i, filename := range fs.Args() {
+		var err error
+		if versions[i], _, err = f.readMissionFile(filename); err != nil {
+			return fail("Could not read %s: %s", filename, err)
+		}
+	}
+	changes := gosqm.DiffMissions(versions[0], versions[1])
+	var buf bytes.Buffer
+	var err error
+	if f.format == "json" {
+		err = gosqm.WriteChangesJSON(&buf, changes)
+	} else {
+		err = gosqm.WriteChanges(&buf, changes)
+	}
+	if err == nil {
+		err = f.write(buf.Bytes())
+	}
+	if err != nil {
+		return fail("Could not write %s: %s", f.output, err)
+	}
+	if len(changes) > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
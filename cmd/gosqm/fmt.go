@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+)
+
+// fmtCmd rewrites mission files in the canonical text format.
+var fmtCmd = &command{
+	name:  "fmt",
+	usage: fmtUsage,
+	run:   runFmt,
+}
+
+const fmtUsage = "fmt [-w] [-l] <file>...  format mission files, -w rewrites them, -l lists the ones which change, both only for text files"
+
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	f := addCommonFlags(fs, "text")
+	writeFlag := fs.Bool("w", false, "write result to the mission files instead of the output")
+	listFlag := fs.Bool("l", false, "list the files whose formatting differs")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		return usageError(fmtUsage)
+	}
+	if err := f.check(); err != nil {
+		return fail("%s", err)
+	}
+	var out bytes.Buffer
+	for _, filename := range fs.Args() {
+		old, err := readFile(filename)
+		if err != nil {
+			return fail("Could not read %s: %s", filename, err)
+		}
+		if *listFlag || *writeFlag {
+			if filename == "-" {
+				return fail("Cannot use -w or -l with stdin")
+			}
+			if format := inputFormat(old); format != "text" {
+				return fail("Cannot use -w or -l with %s, it is a %s file", filename, format)
+			}
+		}
+		class, err := decodeClass(old)
+		if err == nil {
+			err = f.checkVersion(class)
+		}
+		if err != nil {
+			return fail("Could not read %s: %s", filename, err)
+		}
+		data, err := encodeClass(class, "text")
+		if err != nil {
+			return fail("Could not encode %s: %s", filename, err)
+		}
+		if *listFlag || *writeFlag {
+			if bytes.Equal(old, data) {
+				continue
+			}
+			if *listFlag {
+				fmt.Fprintln(&out, filename)
+			}
+			if *writeFlag {
+				if err := ioutil.WriteFile(filename, data, 0666); err != nil {
+					return fail("Could not write %s: %s", filename, err)
+				}
+			}
+			continue
+		}
+		out.Write(data)
+	}
+	if err := f.write(out.Bytes()); err != nil {
+		return fail("Could not write %s: %s", f.output, err)
+	}
+	return exitOK
+}
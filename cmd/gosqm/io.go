@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/blang/gosqm"
+	"github.com/blang/gosqm/sqm"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// armaVersions are the version properties of the mission files of each
+// Arma version, Arma 3 missions are 12 from the 2D editor and 51 to 54
+// from the Eden editor
+var armaVersions = map[string][]string{
+	"2": {"11"},
+	"3": {"12", "51", "52", "53", "54"},
+}
+
+// commonFlags are the flags shared by the commands: the output file, the
+// output format and the Arma version the input files must be written for.
+type commonFlags struct {
+	output  string
+	format  string
+	arma    string
+	formats []string
+}
+
+// addCommonFlags adds the shared flags to fs, the first format is the
+// default.
+func addCommonFlags(fs *flag.FlagSet, formats ...string) *commonFlags {
+	f := &commonFlags{formats: formats}
+	fs.StringVar(&f.output, "o", "-", "output file, - for stdout")
+	fs.StringVar(&f.format, "format", formats[0], "output format: "+strings.Join(formats, ", "))
+	fs.StringVar(&f.arma, "arma", "", "Arma version of the input files: 2 or 3, empty accepts all")
+	return f
+}
+
+// check validates the values of the shared flags
+func (f *commonFlags) check() error {
+	f.format = strings.ToLower(f.format)
+	found := false
+	for _, format := range f.formats {
+		found = found || format == f.format
+	}
+	if !found {
+		return fmt.Errorf("Unknown format %q, expected one of %s", f.format, strings.Join(f.formats, ", "))
+	}
+	if _, known := armaVersions[f.arma]; f.arma != "" && !known {
+		return fmt.Errorf("Unknown Arma version %q", f.arma)
+	}
+	return nil
+}
+
+// write writes the output of a command to the output file or stdout
+func (f *commonFlags) write(data []byte) error {
+	if f.output == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(f.output, data, 0666)
+}
+
+// readFile reads a file, - is stdin
+func readFile(filename string) ([]byte, error) {
+	if filename == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// inputFormat returns the format of a mission file: text, binary or json
+func inputFormat(buf []byte) string {
+	switch {
+	case sqm.IsBinary(buf):
+		return "binary"
+	case bytes.HasPrefix(bytes.TrimSpace(buf), []byte("{")):
+		return "json"
+	}
+	return "text"
+}
+
+// decodeClass decodes a mission file in text, binary or JSON format
+func decodeClass(buf []byte) (*sqm.Class, error) {
+	switch inputFormat(buf) {
+	case "binary":
+		return sqm.DecodeBinary(bytes.NewReader(buf))
+	case "json":
+		return sqm.DecodeJSON(bytes.NewReader(buf))
+	}
+	return sqm.MakeParser(string(buf)).Run()
+}
+
+// readClass reads a mission file in text, binary or JSON format, - is
+// stdin
+func readClass(filename string) (*sqm.Class, error) {
+	buf, err := readFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return decodeClass(buf)
+}
+
+// readInput reads a mission file and checks its version against -arma
+func (f *commonFlags) readInput(filename string) (*sqm.Class, error) {
+	class, err := readClass(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.checkVersion(class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// checkVersion checks the version of a mission file against -arma
+func (f *commonFlags) checkVersion(class *sqm.Class) error {
+	if f.arma == "" {
+		return nil
+	}
+	version := ""
+	for _, p := range class.Props {
+		if p.Name == "version" {
+			version = p.Value
+		}
+	}
+	for _, v := range armaVersions[f.arma] {
+		if v == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("Version %q is not a mission file of Arma %s", version, f.arma)
+}
+
+// readMissionFile reads and decodes a mission file and returns the decoding
+// warnings
+func (f *commonFlags) readMissionFile(filename string) (*gosqm.MissionFile, []error, error) {
+	class, err := f.readInput(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := gosqm.NewParser()
+	mf, err := p.Parse(class)
+	return mf, p.Warnings(), err
+}
+
+// encodeClass encodes a class in the text, binary or JSON format
+func encodeClass(class *sqm.Class, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "binary":
+		err = sqm.EncodeBinary(&buf, class)
+	case "json":
+		err = sqm.EncodeJSON(&buf, class)
+	default:
+		err = sqm.NewEncoder(&buf).Encode(class)
+	}
+	return buf.Bytes(), err
+}
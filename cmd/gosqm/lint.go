@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/blang/gosqm/lint"
+)
+
+// lintCmd checks mission files with the default lint rules. The exit code
+// is 1 if a diagnostic reaches the -fail severity, which makes it usable
+// as a CI step.
+var lintCmd = &command{
+	name:  "lint",
+	usage: lintUsage,
+	run:   runLint,
+}
+
+const lintUsage = "lint [-format text|json] [-fail error] [-show warning] [-addons db] <file>...  check mission files, exit code 1 on failure"
+
+// lintResult is a diagnostic written by -format json
+type lintResult struct {
+	File     string `json:"file"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	f := addCommonFlags(fs, "text", "json")
+	failFlag := fs.String("fail", "error", "lowest severity which fails the check: info, warning or error")
+	showFlag := fs.String("show", "warning", "lowest severity which is printed: info, warning or error")
+	addonsFlag := fs.String("addons", "", "addon database, enables the missing-addon rule")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		return usageError(lintUsage)
+	}
+	if err := f.check(); err != nil {
+		return fail("%s", err)
+	}
+	failSeverity, err := lint.ParseSeverity(*failFlag)
+	if err != nil {
+		return fail("%s", err)
+	}
+	show, err := lint.ParseSeverity(*showFlag)
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	linter := lint.New(lint.DefaultRules()...)
+	if *addonsFlag != "" {
+		db, err := readAddonDB(*addonsFlag)
+		if err != nil {
+			return fail("Could not read %s: %s", *addonsFlag, err)
+		}
+		linter.Rules = append(linter.Rules, lint.MissingAddons(db))
+	}
+	code := exitOK
+	var buf bytes.Buffer
+	results := []lintResult{}
+	for _, filename := range fs.Args() {
+		mf, warnings, err := f.readMissionFile(filename)
+		if err != nil {
+			return fail("Could not read %s: %s", filename, err)
+		}
+		diags := append(lint.FromWarnings(warnings), linter.Lint(mf)...)
+		for _, d := range diags {
+			if d.Severity < show {
+				continue
+			}
+			if f.format == "json" {
+				results = append(results, lintResult{filename, d.Rule, d.Severity.String(), d.Path, d.Message})
+			} else {
+				fmt.Fprintf(&buf, "%s:%s\n", filename, d)
+			}
+		}
+		if lint.Max(diags) >= failSeverity {
+			code = exitFailure
+		}
+	}
+	if f.format == "json" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fail("%s", err)
+		}
+		buf.Write(append(data, '\n'))
+	}
+	if err := f.write(buf.Bytes()); err != nil {
+		return fail("Could not write %s: %s", f.output, err)
+	}
+	return code
+}
@@ -0,0 +1,83 @@
+// Command gosqm checks, converts and edits Arma mission files.
+//
+// Every command accepts -o to write its output to a file, -format to choose
+// the output format and -arma to reject mission files of other Arma
+// versions. Input files are read in text, binary or JSON format, - reads
+// stdin.
+//
+// The exit code is 0 on success, 1 if a check failed, e.g. lint errors,
+// differing files or merge conflicts, and 2 on invalid arguments or files
+// which cannot be read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// exit codes of the commands
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitError   = 2
+)
+
+// command is a gosqm subcommand, run returns the exit code
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) int
+}
+
+var commands = []*command{
+	parseCmd,
+	fmtCmd,
+	lintCmd,
+	statsCmd,
+	diffCmd,
+	convertCmd,
+	queryCmd,
+	addonsCmd,
+	orbatCmd,
+	mergeCmd,
+	mergeDriverCmd,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: gosqm <command> [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\n", cmd.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nCommon flags:\n  -o file  -format name  -arma 2|3\n\nExit codes: 0 success, 1 check failed, 2 error\n")
+}
+
+// usageError prints the usage of a command and returns exitError
+func usageError(usage string) int {
+	fmt.Fprintf(os.Stderr, "Usage: gosqm %s\n", usage)
+	return exitError
+}
+
+// fail prints an error and returns exitError
+func fail(format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	return exitError
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(exitError)
+	}
+	name := flag.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			os.Exit(cmd.run(flag.Args()[1:]))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n", name)
+	usage()
+	os.Exit(exitError)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/blang/gosqm"
+	"github.com/blang/gosqm/sqm"
+	"os"
+)
+
+// mergeCmd merges two versions of a mission file with their common base.
+// Unlike merge-driver it writes the result to the output and accepts
+// every input format.
+var mergeCmd = &command{
+	name:  "merge",
+	usage: mergeUsage,
+	run:   runMerge,
+}
+
+const mergeUsage = "merge [-format text|binary|json] [-o file] <base> <ours> <theirs>  three-way merge, exit code 1 on conflicts"
+
+func runMerge(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	f := addCommonFlags(fs, "text", "binary", "json")
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		return usageError(mergeUsage)
+	}
+	if err := f.check(); err != nil {
+		return fail("%s", err)
+	}
+	var classes [3]*sqm.Class
+	for i, filename := range fs.Args() {
+		class, err := f.readInput(filename)
+		if err != nil {
+			return fail("Could not read %s: %s", filename, err)
+		}
+		classes[i] = class
+	}
+
+	merged, conflicts := gosqm.MergeClasses(classes[0], classes[1], classes[2])
+	data, err := encodeClass(merged, f.format)
+	if err != nil {
+		return fail("Could not encode merge result: %s", err)
+	}
+	if err := f.write(data); err != nil {
+		return fail("Could not write %s: %s", f.output, err)
+	}
+	printConflicts(conflicts)
+	if len(conflicts) > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// printConflicts reports merge conflicts on stderr
+func printConflicts(conflicts []sqm.Conflict) {
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "CONFLICT %s (base: %s, ours: %s, theirs: %s)\n", c, conflictValue(c.Base, c.HasBase), conflictValue(c.Ours, c.HasOurs), conflictValue(c.Theirs, c.HasTheirs))
+	}
+}
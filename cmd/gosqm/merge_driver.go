@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"github.com/blang/gosqm"
+	"github.com/blang/gosqm/sqm"
+	"io/ioutil"
+)
+
+// mergeDriverCmd implements a git merge driver, register it with
+//
+//	git config merge.sqm.driver "gosqm merge-driver %O %A %B"
+//
+// and a line "*.sqm merge=sqm" in .gitattributes.
+var mergeDriverCmd = &command{
+	name:  "merge-driver",
+	usage: mergeDriverUsage,
+	run:   runMergeDriver,
+}
+
+const mergeDriverUsage = "merge-driver <base> <ours> <theirs>  three-way merge, result is written to <ours>"
+
+func runMergeDriver(args []string) int {
+	fs := flag.NewFlagSet("merge-driver", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		return usageError(mergeDriverUsage)
+	}
+	var classes [3]*sqm.Class
+	for i, filename := range fs.Args() {
+		class, err := readClass(filename)
+		if err != nil {
+			return fail("Could not read %s: %s", filename, err)
+		}
+		classes[i] = class
+	}
+
+	merged, conflicts := gosqm.MergeClasses(classes[0], classes[1], classes[2])
+	var buf bytes.Buffer
+	if err := sqm.NewEncoder(&buf).Encode(merged); err != nil {
+		return fail("Could not encode merge result: %s", err)
+	}
+	if err := ioutil.WriteFile(fs.Arg(1), buf.Bytes(), 0666); err != nil {
+		return fail("Could not write %s: %s", fs.Arg(1), err)
+	}
+
+	printConflicts(conflicts)
+	if len(conflicts) > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func conflictValue(v string, exists bool) string {
+	if !exists {
+		return "<none>"
+	}
+	return v
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"github.com/blang/gosqm/orbat"
+	"strings"
+)
+
+// orbatCmd prints the slot list of the playable units of a mission.
+var orbatCmd = &command{
+	name:  "orbat",
+	usage: orbatUsage,
+	run:   runOrbat,
+}
+
+const orbatUsage = "orbat [-format markdown|html|csv|json] [-callsigns a,b] <file>  print the slot list of the playable units"
+
+func runOrbat(args []string) int {
+	fs := flag.NewFlagSet("orbat", flag.ExitOnError)
+	f := addCommonFlags(fs, "markdown", "html", "csv", "json")
+	callsignsFlag := fs.String("callsigns", "", "comma separated group callsigns per side, the game's names are used for the rest")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError(orbatUsage)
+	}
+	if err := f.check(); err != nil {
+		return fail("%s", err)
+	}
+	var options orbat.Options
+	if *callsignsFlag != "" {
+		options.Callsigns = orbat.ListCallsigns(strings.Split(*callsignsFlag, ",")...)
+	}
+
+	filename := fs.Arg(0)
+	mf, _, err := f.readMissionFile(filename)
+	if err != nil {
+		return fail("Could not read %s: %s", filename, err)
+	}
+	var buf bytes.Buffer
+	err = orbat.New(mf, options).Write(&buf, f.format)
+	if err == nil {
+		err = f.write(buf.Bytes())
+	}
+	if err != nil {
+		return fail("Could not write %s: %s", f.output, err)
+	}
+	return exitOK
+}
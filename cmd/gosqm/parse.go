@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+)
+
+// parseCmd checks the syntax of mission files and reports the warnings of
+// the decoder. The exit code is 1 if a file cannot be parsed or decoded.
+var parseCmd = &command{
+	name:  "parse",
+	usage: parseUsage,
+	run:   runParse,
+}
+
+const parseUsage = "parse [-format text|json] <file>...  check the syntax, exit code 1 on errors"
+
+// parseResult is the result of a file written by -format json
+type parseResult struct {
+	File     string   `json:"file"`
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings"`
+}
+
+func runParse(args []string) int {
+	fs := flag.NewFlagSet("parse", flag.ExitOnError)
+	f := addCommonFlags(fs, "text", "json")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		return usageError(parseUsage)
+	}
+	if err := f.check(); err != nil {
+		return fail("%s", err)
+	}
+	code := exitOK
+	results := []parseResult{}
+	for _, filename := range fs.Args() {
+		result := parseResult{File: filename, Warnings: []string{}}
+		_, warnings, err := f.readMissionFile(filename)
+		if err != nil {
+			result.Error = err.Error()
+			code = exitFailure
+		}
+		for _, w := range warnings {
+			result.Warnings = append(result.Warnings, w.Error())
+		}
+		results = append(results, result)
+	}
+
+	var buf bytes.Buffer
+	if f.format == "json" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fail("%s", err)
+		}
+		buf.Write(append(data, '\n'))
+	} else {
+		for _, r := range results {
+			for _, w := range r.Warnings {
+				fmt.Fprintf(&buf, "%s: warning: %s\n", r.File, w)
+			}
+			if r.Error != "" {
+				fmt.Fprintf(&buf, "%s: error: %s\n", r.File, r.Error)
+			} else {
+				fmt.Fprintf(&buf, "%s: ok\n", r.File)
+			}
+		}
+	}
+	if err := f.write(buf.Bytes()); err != nil {
+		return fail("Could not write %s: %s", f.output, err)
+	}
+	return code
+}
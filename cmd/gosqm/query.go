@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/blang/gosqm/sqm"
+)
+
+// queryCmd prints the classes of a mission file matching a selector like
+// Mission/Groups/*/Vehicles/*[player].
+var queryCmd = &command{
+	name:  "query",
+	usage: queryUsage,
+	run:   runQuery,
+}
+
+const queryUsage = "query [-format paths|text|json] <selector> <file>  print the classes matching a selector, exit code 1 if none match"
+
+// queryResult is a matching class written by -format json
+type queryResult struct {
+	Path  string     `json:"path"`
+	Class *sqm.Class `json:"class"`
+}
+
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	f := addCommonFlags(fs, "paths", "text", "json")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return usageError(queryUsage)
+	}
+	if err := f.check(); err != nil {
+		return fail("%s", err)
+	}
+	sel, err := sqm.ParseSelector(fs.Arg(0))
+	if err != nil {
+		return fail("Invalid selector %q: %s", fs.Arg(0), err)
+	}
+	filename := fs.Arg(1)
+	class, err := f.readInput(filename)
+	if err != nil {
+		return fail("Could not read %s: %s", filename, err)
+	}
+	matches := sel.Select(class)
+
+	var buf bytes.Buffer
+	switch f.format {
+	case "paths":
+		for _, c := range matches {
+			fmt.Fprintln(&buf, c.Path())
+		}
+	case "text":
+		for _, c := range matches {
+			if err := sqm.NewEncoder(&buf).Encode(&sqm.Class{Classes: []*sqm.Class{c}}); err != nil {
+				return fail("Could not encode %s: %s", c.Path(), err)
+			}
+		}
+	case "json":
+		results := []queryResult{}
+		for _, c := range matches {
+			results = append(results, queryResult{c.Path(), c})
+		}
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fail("%s", err)
+		}
+		buf.Write(append(data, '\n'))
+	}
+	if err := f.write(buf.Bytes()); err != nil {
+		return fail("Could not write %s: %s", f.output, err)
+	}
+	if len(matches) == 0 {
+		return exitFailure
+	}
+	return exitOK
+}
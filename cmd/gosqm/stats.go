@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"github.com/blang/gosqm/stats"
+)
+
+// statsCmd prints a summary of a mission file.
+var statsCmd = &command{
+	name:  "stats",
+	usage: statsUsage,
+	run:   runStats,
+}
+
+const statsUsage = "stats [-format text|json] [-addons db] <file>  print entity counts, slots and script sizes"
+
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	f := addCommonFlags(fs, "text", "json")
+	addonsFlag := fs.String("addons", "", "addon database, enables counts per addon")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError(statsUsage)
+	}
+	if err := f.check(); err != nil {
+		return fail("%s", err)
+	}
+	var options stats.Options
+	if *addonsFlag != "" {
+		db, err := readAddonDB(*addonsFlag)
+		if err != nil {
+			return fail("Could not read %s: %s", *addonsFlag, err)
+		}
+		options.AddonDB = db
+	}
+
+	filename := fs.Arg(0)
+	mf, _, err := f.readMissionFile(filename)
+	if err != nil {
+		return fail("Could not read %s: %s", filename, err)
+	}
+	s := stats.New(mf, options)
+	var buf bytes.Buffer
+	if f.format == "json" {
+		err = s.WriteJSON(&buf)
+	} else {
+		err = s.WriteText(&buf)
+	}
+	if err == nil {
+		err = f.write(buf.Bytes())
+	}
+	if err != nil {
+		return fail("Could not write %s: %s", f.output, err)
+	}
+	return exitOK
+}
@@ -0,0 +1,149 @@
+package gosqm
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Composition is a reusable selection of entities with positions relative
+// to the composition center and the addons they need.
+// A composition is stored as mission file with only a Mission stage.
+type Composition struct {
+	Addons   []string
+	Entities Entities
+}
+
+// NewComposition copies e into a composition centered at center.
+// References to entities which are not part of e are dropped. The addons
+// are looked up in db by the classnames of the copied vehicles, without a
+// db the composition has no addons.
+func NewComposition(e Entities, center Vec3, db AddonDB) *Composition {
+	copies := (&Mission{}).CopyEntities(e)
+	copies.Transform(Translation(center.Scale(-1)))
+	c := &Composition{Entities: copies}
+	if db != nil {
+		c.UpdateAddons(db)
+	}
+	return c
+}
+
+// UpdateAddons sets the addons to the CfgPatches classes of the vehicles in
+// the composition, in order of first use. It returns the sorted classnames
+// which are not in db.
+func (c *Composition) UpdateAddons(db AddonDB) []string {
+	var vehicles []*Vehicle
+	for _, g := range c.Entities.Groups {
+		vehicles = append(vehicles, g.Units...)
+	}
+	vehicles = append(vehicles, c.Entities.Vehicles...)
+	unmapped := make(map[string]bool)
+	c.Addons = vehicleAddons(vehicles, db, unmapped)
+	return sortedClassnames(unmapped)
+}
+
+// Encode writes the composition as mission file.
+func (c *Composition) Encode(w io.Writer) error {
+	m := &Mission{Addons: c.Addons, AddonsAuto: c.Addons}
+	m.Groups = c.Entities.Groups
+	m.Vehicles = c.Entities.Vehicles
+	m.Markers = c.Entities.Markers
+	m.Sensors = c.Entities.Sensors
+	mf := &MissionFile{Version: "11", Mission: m}
+	return NewEncoder(w, EncoderOptions{OmitEmptyStages: true}).Encode(mf)
+}
+
+// DecodeComposition reads a composition written by Encode. Any mission file
+// can be read, its Mission stage becomes the composition. The addons are
+// looked up in db if it is not nil, otherwise the addons listed in the file
+// are kept, which for a full mission are all addons of the mission.
+func DecodeComposition(r io.Reader, db AddonDB, options ...DecoderOptions) (*Composition, error) {
+	mf, err := NewDecoder(r, options...).Decode()
+	if err != nil {
+		return nil, err
+	}
+	if mf.Mission == nil {
+		return nil, fmt.Errorf("Composition has no Mission stage")
+	}
+	c := &Composition{Entities: mf.Mission.Entities()}
+	if db != nil {
+		c.UpdateAddons(db)
+		return c, nil
+	}
+	c.Addons = mergeAddons(c.Addons, mf.Mission.Addons)
+	c.Addons = mergeAddons(c.Addons, mf.Mission.AddonsAuto)
+	return c, nil
+}
+
+// InsertComposition adds a copy of the composition to the mission, rotated
+// clockwise by angle degrees and moved to pos, and returns the inserted
+// entities. Names of vehicles, markers and sensors which are already used
+// in the mission get a numeric suffix, references in init lines and
+// conditions are not updated. Vehicle ids are assigned by the encoder.
+// The addons of the composition are added to the mission.
+func (m *Mission) InsertComposition(c *Composition, pos Vec3, angle float64) Entities {
+	used := make(map[string]bool)
+	for _, v := range m.allVehicles() {
+		used[strings.ToLower(v.Name)] = true
+	}
+	for _, marker := range m.Markers {
+		used[strings.ToLower(marker.Name)] = true
+	}
+	for _, s := range m.Sensors {
+		used[strings.ToLower(s.Name)] = true
+	}
+
+	inserted := m.CopyEntities(c.Entities)
+	for _, g := range inserted.Groups {
+		g.ID = ""
+		for _, v := range g.Units {
+			v.Name = uniqueName(v.Name, used)
+		}
+	}
+	for _, v := range inserted.Vehicles {
+		v.Name = uniqueName(v.Name, used)
+	}
+	for _, marker := range inserted.Markers {
+		marker.Name = uniqueName(marker.Name, used)
+	}
+	for _, s := range inserted.Sensors {
+		s.Name = uniqueName(s.Name, used)
+	}
+	inserted.Transform(Rotation(Vec3{}, angle).Then(Translation(pos)))
+
+	m.Addons = mergeAddons(m.Addons, c.Addons)
+	m.AddonsAuto = mergeAddons(m.AddonsAuto, c.Addons)
+	return inserted
+}
+
+// uniqueName returns name or, if it is used, name with the first free
+// suffix _2, _3, ... and marks the result as used. Empty names are kept.
+func uniqueName(name string, used map[string]bool) string {
+	if name == "" {
+		return name
+	}
+	unique := name
+	for i := 2; used[strings.ToLower(unique)]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	used[strings.ToLower(unique)] = true
+	return unique
+}
+
+// mergeAddons appends the addons missing in list, case insensitive
+func mergeAddons(list []string, addons []string) []string {
+	for _, addon := range addons {
+		found := false
+		for _, a := range list {
+			if strings.EqualFold(a, addon) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, addon)
+		}
+	}
+	return list
+}
@@ -0,0 +1,91 @@
+package gosqm
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestComposition(t *testing.T) {
+	Convey("Given a mission with a checkpoint", t, func() {
+		leader := &Vehicle{Name: "guard", Classname: "B_Soldier_F", Position: Vec3{110, 100, 0}, Angle: 90}
+		waypoint := &Waypoint{Position: Vec3{100, 110, 0}}
+		group := &Group{Side: SideWest, ID: "7", Units: []*Vehicle{leader}, Waypoints: []*Waypoint{waypoint}}
+		marker := &Marker{Name: "checkpoint", Position: Vec3{100, 100, 0}}
+		leader.Markers = []*Marker{marker}
+		sensor := &Sensor{Name: "trigger", Position: Vec3{100, 90, 0}, Angle: 350}
+		waypoint.SyncSensor(sensor)
+		source := &Mission{Addons: []string{"A3_Characters_F"}, AddonsAuto: []string{"A3_Structures_F"}}
+		source.Groups = []*Group{group}
+		source.Markers = []*Marker{marker}
+		source.Sensors = []*Sensor{sensor}
+		db := AddonDB{}
+		db.Add("B_Soldier_F", "A3_Characters_F")
+		c := NewComposition(source.Entities(), Vec3{100, 100, 0}, db)
+
+		Convey("Positions are relative to the center", func() {
+			So(c.Entities.Groups[0].Units[0].Position, ShouldResemble, Vec3{10, 0, 0})
+			So(c.Entities.Sensors[0].Position, ShouldResemble, Vec3{0, -10, 0})
+			So(leader.Position, ShouldResemble, Vec3{110, 100, 0})
+		})
+		Convey("Only the addons of the selected classnames are needed", func() {
+			So(c.Addons, ShouldResemble, []string{"A3_Characters_F"})
+			So(NewComposition(source.Entities(), Vec3{}, nil).Addons, ShouldBeEmpty)
+		})
+		Convey("The composition survives encoding", func() {
+			var buf bytes.Buffer
+			So(c.Encode(&buf), ShouldBeNil)
+			decoded, err := DecodeComposition(&buf, nil)
+			So(err, ShouldBeNil)
+			So(decoded.Addons, ShouldResemble, c.Addons)
+			So(decoded.Entities.Groups, ShouldHaveLength, 1)
+			So(decoded.Entities.Groups[0].Units[0].Markers, ShouldHaveLength, 1)
+			So(decoded.Entities.Groups[0].Waypoints[0].SyncSensors, ShouldHaveLength, 1)
+			So(decoded.Entities.Sensors[0].Position, ShouldResemble, Vec3{0, -10, 0})
+		})
+		Convey("Reading a full mission as composition", func() {
+			var buf bytes.Buffer
+			So(NewEncoder(&buf).Encode(&MissionFile{Version: "11", Mission: source}), ShouldBeNil)
+			decoded, err := DecodeComposition(&buf, db)
+			So(err, ShouldBeNil)
+			So(decoded.Addons, ShouldResemble, []string{"A3_Characters_F"})
+		})
+		Convey("Inserting it into a mission", func() {
+			existing := &Vehicle{Name: "Guard", Classname: "B_Soldier_F"}
+			target := &Mission{Addons: []string{"a3_characters_f"}, Vehicles: []*Vehicle{existing}}
+			inserted := target.InsertComposition(c, Vec3{1000, 2000, 5}, 90)
+
+			Convey("Entities are rotated clockwise and moved", func() {
+				v := inserted.Groups[0].Units[0]
+				So(v.Position.X, ShouldAlmostEqual, 1000, 1e-9)
+				So(v.Position.Y, ShouldAlmostEqual, 1990, 1e-9)
+				So(v.Position.Z, ShouldAlmostEqual, 5, 1e-9)
+				So(v.Angle, ShouldAlmostEqual, 180, 1e-9)
+				So(inserted.Sensors[0].Position.X, ShouldAlmostEqual, 990, 1e-9)
+				So(inserted.Sensors[0].Angle, ShouldAlmostEqual, 80, 1e-9)
+			})
+			Convey("Colliding names are renamed", func() {
+				So(inserted.Groups[0].Units[0].Name, ShouldEqual, "guard_2")
+				So(inserted.Markers[0].Name, ShouldEqual, "checkpoint")
+				So(existing.Name, ShouldEqual, "Guard")
+			})
+			Convey("References point to the inserted copies", func() {
+				g := inserted.Groups[0]
+				So(g.ID, ShouldEqual, "")
+				So(g.Units[0].Markers[0] == inserted.Markers[0], ShouldBeTrue)
+				So(g.Waypoints[0].SyncSensors[0] == inserted.Sensors[0], ShouldBeTrue)
+				So(target.DanglingReferences(), ShouldBeEmpty)
+			})
+			Convey("Addons are merged", func() {
+				So(target.Addons, ShouldResemble, []string{"a3_characters_f"})
+				So(target.AddonsAuto, ShouldResemble, []string{"A3_Characters_F"})
+			})
+			Convey("A second insert gets fresh names", func() {
+				again := target.InsertComposition(c, Vec3{}, 0)
+				So(again.Groups[0].Units[0].Name, ShouldEqual, "guard_3")
+				So(again.Markers[0].Name, ShouldEqual, "checkpoint_2")
+				So(target.Groups, ShouldHaveLength, 2)
+			})
+		})
+	})
+}
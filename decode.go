@@ -1,32 +1,95 @@
 package gosqm
 
 import (
+	"context"
 	"github.com/blang/gosqm/sqm"
 	"io"
 	"io/ioutil"
 )
 
+// DecoderOptions controls how the parser handles warnings.
+type DecoderOptions struct {
+	// Strict turns unknown properties and classes into errors.
+	Strict bool
+	// OnWarning is called for every warning as soon as it is found.
+	OnWarning func(warning error)
+	// MaxWarnings limits the number of warnings kept, further warnings are
+	// only passed to OnWarning and decoding continues. Zero means no limit.
+	MaxWarnings int
+	// Suppress drops the warnings and strict errors of these contexts.
+	Suppress []Context
+}
+
+func (o *DecoderOptions) suppressed(context Context) bool {
+	for _, c := range o.Suppress {
+		if c == context {
+			return true
+		}
+	}
+	return false
+}
+
+// warningContext returns the context a warning was found in
+func warningContext(warning error) Context {
+	switch e := warning.(type) {
+	case *UnkownPropertyError:
+		return e.Context
+	case *UnkownClassError:
+		return e.Context
+	case *InvalidValueError:
+		return e.Context
+	case *DanglingReferenceError:
+		return e.Context
+	case *MissingClassError:
+		return e.Context
+	case *MissingPropertyError:
+		return e.Context
+	}
+	return ""
+}
+
 type Decoder struct {
-	r io.Reader
+	r        io.Reader
+	options  DecoderOptions
+	warnings []error
 }
 
-func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{
+// NewDecoder returns a decoder reading from r, the first options are used
+// if given.
+func NewDecoder(r io.Reader, options ...DecoderOptions) *Decoder {
+	d := &Decoder{
 		r: r,
 	}
+	if len(options) > 0 {
+		d.options = options[0]
+	}
+	return d
 }
 
 func (d *Decoder) Decode() (*MissionFile, error) {
+	return d.DecodeContext(context.Background())
+}
+
+// DecodeContext decodes like Decode but aborts parsing once ctx is done.
+func (d *Decoder) DecodeContext(ctx context.Context) (*MissionFile, error) {
+	d.warnings = nil
 	b, err := ioutil.ReadAll(d.r)
 	if err != nil {
 		return nil, err
 	}
 	bufstr := string(b)
 	p := sqm.MakeParser(bufstr)
-	class, perr := p.Run()
+	class, perr := p.RunContext(ctx)
 	if perr != nil {
 		return nil, perr
 	}
-	mp := NewParser()
-	return mp.Parse(class)
+	mp := NewParser(d.options)
+	mf, err := mp.Parse(class)
+	d.warnings = mp.Warnings()
+	return mf, err
+}
+
+// Warnings returns the warnings of the last decoding.
+func (d *Decoder) Warnings() []error {
+	return d.warnings
 }
@@ -0,0 +1,199 @@
+package gosqm
+
+import (
+	"bytes"
+	"github.com/blang/gosqm/sqm"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const optionsMission = `version=11;
+class Mission
+{
+	unknownProp=1;
+	class Intel
+	{
+		year=2010;
+	};
+	class Groups
+	{
+		items=1;
+		class Item0
+		{
+			side="WEST";
+			class Vehicles
+			{
+				items=1;
+				class Item0
+				{
+					position[]={1,2,3};
+					id=0;
+					side="WEST";
+					vehicle="USMC_Soldier";
+					leader=1;
+					rank="GENERAL";
+				};
+			};
+		};
+	};
+	class UnknownClass
+	{
+	};
+};
+`
+
+func TestDecoderOptions(t *testing.T) {
+	Convey("Given a mission with unknown entries and an invalid value", t, func() {
+		Convey("The lenient decoder exposes the warnings", func() {
+			dec := NewDecoder(strings.NewReader(optionsMission))
+			mf, err := dec.Decode()
+			So(err, ShouldBeNil)
+			So(mf, ShouldNotBeNil)
+			So(dec.Warnings(), ShouldHaveLength, 3)
+		})
+		Convey("The strict decoder fails on the first unknown entry", func() {
+			dec := NewDecoder(strings.NewReader(optionsMission), DecoderOptions{Strict: true})
+			mf, err := dec.Decode()
+			So(mf, ShouldBeNil)
+			So(err, ShouldHaveSameTypeAs, &UnkownPropertyError{})
+			So(err.(*UnkownPropertyError).Property.Name, ShouldEqual, "unknownProp")
+		})
+		Convey("Suppressed contexts are neither warnings nor strict errors", func() {
+			dec := NewDecoder(strings.NewReader(optionsMission), DecoderOptions{Strict: true, Suppress: []Context{ContextMission}})
+			_, err := dec.Decode()
+			So(err, ShouldBeNil)
+			So(dec.Warnings(), ShouldHaveLength, 1)
+			So(dec.Warnings()[0], ShouldHaveSameTypeAs, &InvalidValueError{})
+		})
+		Convey("The callback is called for every warning", func() {
+			var warnings []error
+			dec := NewDecoder(strings.NewReader(optionsMission), DecoderOptions{OnWarning: func(w error) {
+				warnings = append(warnings, w)
+			}})
+			_, err := dec.Decode()
+			So(err, ShouldBeNil)
+			So(warnings, ShouldResemble, dec.Warnings())
+		})
+		Convey("MaxWarnings caps the warnings kept but not the decoding", func() {
+			var warnings []error
+			dec := NewDecoder(strings.NewReader(optionsMission), DecoderOptions{MaxWarnings: 2, OnWarning: func(w error) {
+				warnings = append(warnings, w)
+			}})
+			mf, err := dec.Decode()
+			So(err, ShouldBeNil)
+			So(mf.Mission.Groups, ShouldHaveLength, 1)
+			So(dec.Warnings(), ShouldHaveLength, 2)
+			So(warnings, ShouldHaveLength, 3)
+		})
+		Convey("The strict decoder fails on unknown group attributes", func() {
+			mission := strings.Replace(optionsMission, "side=\"WEST\";\n\t\t\tclass Vehicles", "side=\"WEST\";\n\t\t\tcustom=1;\n\t\t\tclass Vehicles", 1)
+			dec := NewDecoder(strings.NewReader(mission), DecoderOptions{Strict: true, Suppress: []Context{ContextMission}})
+			_, err := dec.Decode()
+			So(err, ShouldHaveSameTypeAs, &UnkownPropertyError{})
+			So(err.(*UnkownPropertyError).Property.Name, ShouldEqual, "custom")
+			So(err.(*UnkownPropertyError).Context, ShouldEqual, Context(ContextGroup))
+		})
+		Convey("Options passed to Parse replace the parser options", func() {
+			class, err := sqm.MakeParser(optionsMission).Run()
+			So(err, ShouldBeNil)
+			p := NewParser(DecoderOptions{Strict: true})
+			_, err = p.Parse(class)
+			So(err, ShouldNotBeNil)
+			_, err = p.Parse(class, DecoderOptions{})
+			So(err, ShouldBeNil)
+			So(p.Warnings(), ShouldHaveLength, 3)
+		})
+	})
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	Convey("Given a group with a short position and without vehicles", t, func() {
+		dec := NewDecoder(strings.NewReader(`class Mission { class Groups { class Item0 { side="WEST"; class Waypoints { class Item0 { position[]={1,2}; }; }; }; }; };`))
+		mf, err := dec.Decode()
+		So(err, ShouldBeNil)
+
+		Convey("The group is decoded and the problems are reported", func() {
+			So(mf.Mission.Groups, ShouldHaveLength, 1)
+			So(mf.Mission.Groups[0].Waypoints, ShouldHaveLength, 1)
+			So(dec.Warnings(), ShouldHaveLength, 2)
+			So(dec.Warnings()[0], ShouldHaveSameTypeAs, &InvalidValueError{})
+			So(dec.Warnings()[0].(*InvalidValueError).Context, ShouldEqual, Context(ContextWaypoint))
+			missing, ok := dec.Warnings()[1].(*MissingClassError)
+			So(ok, ShouldBeTrue)
+			So(missing.Name, ShouldEqual, "Vehicles")
+			So(missing.Context, ShouldEqual, Context(ContextGroup))
+			So(missing.Error(), ShouldEqual, "Missing class Vehicles in class Item0 in context Group")
+		})
+	})
+}
+
+// randomClass builds a class tree from names and values known to the
+// parser, including nil entries and cycles
+func randomClass(r *rand.Rand, parents []*sqm.Class) *sqm.Class {
+	classNames := []string{"Mission", "Intro", "OutroWin", "OutroLoose", "Intel", "Groups", "Vehicles", "Waypoints", "Markers", "Sensors", "Effects", "Item0", "Item1", "Unknown"}
+	propNames := []string{"version", "side", "id", "vehicle", "text", "name", "type", "markerType", "a", "b", "angle", "azimut", "skill", "leader", "player", "rank", "lock", "special", "idVehicle", "idObject", "year", "month", "day", "hour", "minute", "startWeather", "activationBy", "combatMode", "unknown"}
+	arrNames := []string{"position", "synchronizations", "markers", "addOns", "addOnsAuto", "unknown"}
+	values := []string{"", "0", "1", "-1", "2.5", "1e400", "NaN", "Inf", "abc", "WEST", "RECTANGLE", "99999999999999999999", "13", "0.000001"}
+
+	c := &sqm.Class{Name: classNames[r.Intn(len(classNames))]}
+	for i := r.Intn(4); i > 0; i-- {
+		if r.Intn(50) == 0 {
+			c.Props = append(c.Props, nil)
+			continue
+		}
+		c.Props = append(c.Props, &sqm.Property{propNames[r.Intn(len(propNames))], sqm.PropType(r.Intn(2)), values[r.Intn(len(values))]})
+	}
+	for i := r.Intn(3); i > 0; i-- {
+		if r.Intn(50) == 0 {
+			c.Arrprops = append(c.Arrprops, nil)
+			continue
+		}
+		arrprop := &sqm.ArrayProperty{Name: arrNames[r.Intn(len(arrNames))]}
+		for j := r.Intn(5); j > 0; j-- {
+			arrprop.Values = append(arrprop.Values, values[r.Intn(len(values))])
+		}
+		c.Arrprops = append(c.Arrprops, arrprop)
+	}
+	if len(parents) < 6 {
+		parents = append(parents, c)
+		for i := r.Intn(4); i > 0; i-- {
+			switch r.Intn(100) {
+			case 0:
+				c.Classes = append(c.Classes, nil)
+			case 1:
+				c.Classes = append(c.Classes, parents[r.Intn(len(parents))])
+			default:
+				c.Classes = append(c.Classes, randomClass(r, parents))
+			}
+		}
+	}
+	return c
+}
+
+func TestParseNeverPanics(t *testing.T) {
+	Convey("Given random classes", t, func() {
+		r := rand.New(rand.NewSource(1))
+		Convey("Parsing never panics", func() {
+			So(func() {
+				for i := 0; i < 5000; i++ {
+					class := randomClass(r, nil)
+					options := DecoderOptions{Strict: r.Intn(2) == 0, MaxWarnings: r.Intn(3)}
+					NewParser(options).Parse(&sqm.Class{Name: "mission", Classes: []*sqm.Class{class}})
+					NewParser().Parse(class)
+				}
+			}, ShouldNotPanic)
+		})
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	if b, err := ioutil.ReadFile("testdata/mission.sqm"); err == nil {
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		NewDecoder(bytes.NewReader(data)).Decode()
+	})
+}
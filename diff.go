@@ -0,0 +1,508 @@
+package gosqm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is an entity which was added, removed or changed between two
+// versions of a mission. Entity describes it like "Group 3 (WEST): unit
+// 'medic'", groups and waypoints are numbered from 1 in the version they
+// are part of, the new one for changes. Moved is the distance in metres the
+// entity moved on the map plane.
+type Change struct {
+	Stage  string        `json:"stage"`
+	Kind   ChangeKind    `json:"kind"`
+	Entity string        `json:"entity"`
+	Moved  float64       `json:"moved,omitempty"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// FieldChange is a changed attribute of an entity.
+type FieldChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// scriptFields are the attributes which are too long to print their values
+var scriptFields = map[string]bool{
+	"init":              true,
+	"text":              true,
+	"condition":         true,
+	"onActivation":      true,
+	"onDeactivation":    true,
+	"presenceCondition": true,
+	"addons":            true,
+}
+
+func (f FieldChange) String() string {
+	if scriptFields[f.Name] || len(f.Old) > 32 || len(f.New) > 32 {
+		return f.Name + " changed"
+	}
+	return fmt.Sprintf("%s %s→%s", f.Name, quoteEmpty(f.Old), quoteEmpty(f.New))
+}
+
+func quoteEmpty(s string) string {
+	if s == "" {
+		return `""`
+	}
+	return s
+}
+
+func (c *Change) String() string {
+	s := c.Entity
+	if c.Stage != StageMission {
+		s = c.Stage + ": " + s
+	}
+	if c.Kind != ChangeChanged {
+		return s + " " + string(c.Kind)
+	}
+	var parts []string
+	if c.Moved > 0 {
+		parts = append(parts, "moved "+FormatNumber(math.Round(c.Moved*10)/10)+" m")
+	}
+	for _, f := range c.Fields {
+		parts = append(parts, f.String())
+	}
+	return s + " " + strings.Join(parts, ", ")
+}
+
+// WriteChanges writes a change per line.
+func WriteChanges(w io.Writer, changes []*Change) error {
+	for _, c := range changes {
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteChangesJSON writes the changes as indented JSON array.
+func WriteChangesJSON(w io.Writer, changes []*Change) error {
+	if changes == nil {
+		changes = []*Change{}
+	}
+	buf, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
+
+// matchDistance is the distance in metres up to which unnamed entities of
+// the same class are considered the same
+const matchDistance = 50
+
+// DiffMissions compares two versions of a mission file entity by entity.
+// Groups are matched by id, the names of their units or the position of
+// their leader, units, vehicles and sensors by name or by class and
+// position, markers by name and waypoints by their index.
+// Changes are listed by stage in file order, a stage which is missing in
+// one version is compared as empty stage.
+func DiffMissions(a, b *MissionFile) []*Change {
+	var changes []*Change
+	for _, name := range StageNames {
+		d := &differ{stage: name}
+		d.mission(emptyIfNil(a.Stage(name)), emptyIfNil(b.Stage(name)))
+		changes = append(changes, d.changes...)
+	}
+	return changes
+}
+
+func emptyIfNil(m *Mission) *Mission {
+	if m == nil {
+		return &Mission{}
+	}
+	return m
+}
+
+type differ struct {
+	stage   string
+	changes []*Change
+}
+
+func (d *differ) add(kind ChangeKind, entity string) {
+	d.changes = append(d.changes, &Change{Stage: d.stage, Kind: kind, Entity: entity})
+}
+
+// changed records the changed fields of an entity if there are any
+func (d *differ) changed(entity string, oldPos, newPos Vec3, fields []FieldChange) {
+	moved := oldPos.Dist2D(newPos)
+	if moved < 0.01 {
+		moved = 0
+	}
+	if moved == 0 && len(fields) == 0 {
+		return
+	}
+	d.changes = append(d.changes, &Change{Stage: d.stage, Kind: ChangeChanged, Entity: entity, Moved: moved, Fields: fields})
+}
+
+func (d *differ) mission(a, b *Mission) {
+	fields := compareFields([][3]string{
+		{"addons", strings.Join(a.Addons, ","), strings.Join(b.Addons, ",")},
+		{"randomSeed", a.RandomSeed, b.RandomSeed},
+	})
+	d.changed("Mission", Vec3{}, Vec3{}, fields)
+
+	pairs, removed, added := match(groupInfos(a.Groups), groupInfos(b.Groups))
+	for _, i := range removed {
+		d.add(ChangeRemoved, groupLabel(a.Groups[i], i))
+	}
+	for _, p := range pairs {
+		d.group(a.Groups[p[0]], b.Groups[p[1]], groupLabel(b.Groups[p[1]], p[1]))
+	}
+	for _, i := range added {
+		d.add(ChangeAdded, groupLabel(b.Groups[i], i))
+	}
+
+	d.vehicles(a.Vehicles, b.Vehicles, "")
+
+	pairs, removed, added = match(markerInfos(a.Markers), markerInfos(b.Markers))
+	for _, i := range removed {
+		d.add(ChangeRemoved, markerLabel(a.Markers[i]))
+	}
+	for _, p := range pairs {
+		d.marker(a.Markers[p[0]], b.Markers[p[1]])
+	}
+	for _, i := range added {
+		d.add(ChangeAdded, markerLabel(b.Markers[i]))
+	}
+
+	pairs, removed, added = match(sensorInfos(a.Sensors), sensorInfos(b.Sensors))
+	for _, i := range removed {
+		d.add(ChangeRemoved, sensorLabel(a.Sensors[i], i))
+	}
+	for _, p := range pairs {
+		d.sensor(a.Sensors[p[0]], b.Sensors[p[1]], sensorLabel(b.Sensors[p[1]], p[1]))
+	}
+	for _, i := range added {
+		d.add(ChangeAdded, sensorLabel(b.Sensors[i], i))
+	}
+}
+
+func (d *differ) group(a, b *Group, label string) {
+	d.changed(label, Vec3{}, Vec3{}, compareFields([][3]string{
+		{"side", a.Side.String(), b.Side.String()},
+	}))
+	d.vehicles(a.Units, b.Units, label+": ")
+
+	for i := 0; i < len(a.Waypoints) || i < len(b.Waypoints); i++ {
+		switch {
+		case i >= len(b.Waypoints):
+			d.add(ChangeRemoved, fmt.Sprintf("%s: waypoint %d", label, i+1))
+		case i >= len(a.Waypoints):
+			d.add(ChangeAdded, fmt.Sprintf("%s: waypoint %d", label, i+1))
+		default:
+			wa, wb := a.Waypoints[i], b.Waypoints[i]
+			d.changed(fmt.Sprintf("%s: waypoint %d", label, i+1), wa.Position, wb.Position, compareFields([][3]string{
+				{"type", wa.Type.String(), wb.Type.String()},
+				{"combatMode", wa.CombatMode.String(), wb.CombatMode.String()},
+				{"formation", wa.Formation.String(), wb.Formation.String()},
+				{"speed", wa.Speed.String(), wb.Speed.String()},
+				{"combat", wa.Combat.String(), wb.Combat.String()},
+				{"description", wa.Description, wb.Description},
+				{"condition", wa.Condition, wb.Condition},
+				{"onActivation", wa.OnActivation, wb.OnActivation},
+				{"timeout", waypointTimeout(wa), waypointTimeout(wb)},
+			}))
+		}
+	}
+}
+
+// vehicles compares the units of a group or the empty vehicles of a stage
+func (d *differ) vehicles(a, b []*Vehicle, prefix string) {
+	pairs, removed, added := match(vehicleInfos(a), vehicleInfos(b))
+	for _, i := range removed {
+		d.add(ChangeRemoved, prefix+vehicleLabel(a[i], i, prefix != ""))
+	}
+	for _, p := range pairs {
+		va, vb := a[p[0]], b[p[1]]
+		d.changed(prefix+vehicleLabel(vb, p[1], prefix != ""), va.Position, vb.Position, compareFields([][3]string{
+			{"name", va.Name, vb.Name},
+			{"classname", va.Classname, vb.Classname},
+			{"angle", FormatNumber(va.Angle), FormatNumber(vb.Angle)},
+			{"skill", formatOptional(va.Skill), formatOptional(vb.Skill)},
+			{"rank", va.Rank.String(), vb.Rank.String()},
+			{"side", va.Side.String(), vb.Side.String()},
+			{"leader", strconv.FormatBool(va.IsLeader), strconv.FormatBool(vb.IsLeader)},
+			{"player", va.Player, vb.Player},
+			{"description", va.Description, vb.Description},
+			{"special", va.Special.String(), vb.Special.String()},
+			{"lock", va.Lock.String(), vb.Lock.String()},
+			{"health", formatOptional(va.Health), formatOptional(vb.Health)},
+			{"fuel", formatOptional(va.Fuel), formatOptional(vb.Fuel)},
+			{"ammo", formatOptional(va.Ammo), formatOptional(vb.Ammo)},
+			{"presence", va.Presence, vb.Presence},
+			{"presenceCondition", va.PresenceCond, vb.PresenceCond},
+			{"init", va.Init, vb.Init},
+		}))
+	}
+	for _, i := range added {
+		d.add(ChangeAdded, prefix+vehicleLabel(b[i], i, prefix != ""))
+	}
+}
+
+func (d *differ) marker(a, b *Marker) {
+	d.changed(markerLabel(b), a.Position, b.Position, compareFields([][3]string{
+		{"type", a.Type, b.Type},
+		{"markerType", a.MarkerType.String(), b.MarkerType.String()},
+		{"text", a.Text, b.Text},
+		{"color", a.ColorName, b.ColorName},
+		{"fill", a.FillName, b.FillName},
+		{"size", a.Size.String(), b.Size.String()},
+		{"angle", FormatNumber(a.Angle), FormatNumber(b.Angle)},
+	}))
+}
+
+func (d *differ) sensor(a, b *Sensor, label string) {
+	d.changed(label, a.Position, b.Position, compareFields([][3]string{
+		{"name", a.Name, b.Name},
+		{"size", a.Size.String(), b.Size.String()},
+		{"angle", FormatNumber(a.Angle), FormatNumber(b.Angle)},
+		{"rectangular", strconv.FormatBool(a.IsRectangle), strconv.FormatBool(b.IsRectangle)},
+		{"activationBy", a.ActivationBy.String(), b.ActivationBy.String()},
+		{"activationType", a.ActivationType, b.ActivationType},
+		{"repeating", strconv.FormatBool(a.IsRepeating), strconv.FormatBool(b.IsRepeating)},
+		{"timeout", timeout(a.TimeoutMin, a.TimeoutMid, a.TimeoutMax), timeout(b.TimeoutMin, b.TimeoutMid, b.TimeoutMax)},
+		{"type", a.Type, b.Type},
+		{"text", a.Text, b.Text},
+		{"condition", a.Condition, b.Condition},
+		{"onActivation", a.OnActivation, b.OnActivation},
+		{"onDeactivation", a.OnDeactivation, b.OnDeactivation},
+	}))
+}
+
+func timeout(min, mid, max string) string {
+	if min == "" && mid == "" && max == "" {
+		return ""
+	}
+	return min + "/" + mid + "/" + max
+}
+
+// waypointTimeout formats the timeouts of a waypoint like timeout
+func waypointTimeout(w *Waypoint) string {
+	if w.TimeoutMin == 0 && w.TimeoutMid == 0 && w.TimeoutMax == 0 {
+		return ""
+	}
+	return timeout(FormatNumber(w.TimeoutMin), FormatNumber(w.TimeoutMid), FormatNumber(w.TimeoutMax))
+}
+
+// formatOptional formats an optional number, unset numbers are empty
+func formatOptional(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return FormatNumber(*f)
+}
+
+// compareFields returns the fields given as name, old and new value which
+// differ
+func compareFields(fields [][3]string) []FieldChange {
+	var changes []FieldChange
+	for _, f := range fields {
+		if f[1] != f[2] {
+			changes = append(changes, FieldChange{f[0], f[1], f[2]})
+		}
+	}
+	return changes
+}
+
+func groupLabel(g *Group, i int) string {
+	return fmt.Sprintf("Group %d (%s)", i+1, g.Side)
+}
+
+func vehicleLabel(v *Vehicle, i int, unit bool) string {
+	kind := "vehicle"
+	if unit {
+		kind = "unit"
+	}
+	if v.Name != "" {
+		return fmt.Sprintf("%s '%s'", kind, v.Name)
+	}
+	return fmt.Sprintf("%s %d (%s)", kind, i+1, v.Classname)
+}
+
+func markerLabel(m *Marker) string {
+	return fmt.Sprintf("Marker '%s'", m.Name)
+}
+
+func sensorLabel(s *Sensor, i int) string {
+	if s.Name != "" {
+		return fmt.Sprintf("Sensor '%s'", s.Name)
+	}
+	return fmt.Sprintf("Sensor %d", i+1)
+}
+
+// matchInfo describes an entity for matching, entities with the same
+// non-empty keys are the same, others are matched by class and position
+type matchInfo struct {
+	keys     []string
+	class    string
+	position Vec3
+}
+
+func groupInfos(groups []*Group) []matchInfo {
+	infos := make([]matchInfo, len(groups))
+	for i, g := range groups {
+		info := matchInfo{class: g.Side.String()}
+		if g.ID != "" {
+			info.keys = append(info.keys, "id:"+g.ID)
+		}
+		for _, v := range g.Units {
+			if v.Name != "" {
+				info.keys = append(info.keys, "unit:"+strings.ToLower(v.Name))
+			}
+		}
+		if leader := g.Leader(); leader != nil {
+			info.position = leader.Position
+		}
+		infos[i] = info
+	}
+	return infos
+}
+
+func vehicleInfos(vehicles []*Vehicle) []matchInfo {
+	infos := make([]matchInfo, len(vehicles))
+	for i, v := range vehicles {
+		infos[i] = matchInfo{class: strings.ToLower(v.Classname), position: v.Position}
+		if v.Name != "" {
+			infos[i].keys = []string{strings.ToLower(v.Name)}
+		}
+	}
+	return infos
+}
+
+func markerInfos(markers []*Marker) []matchInfo {
+	infos := make([]matchInfo, len(markers))
+	for i, m := range markers {
+		infos[i] = matchInfo{class: strings.ToLower(m.Type), position: m.Position}
+		if m.Name != "" {
+			infos[i].keys = []string{strings.ToLower(m.Name)}
+		}
+	}
+	return infos
+}
+
+func sensorInfos(sensors []*Sensor) []matchInfo {
+	infos := make([]matchInfo, len(sensors))
+	for i, s := range sensors {
+		infos[i] = matchInfo{class: s.ActivationBy.String(), position: s.Position}
+		if s.Name != "" {
+			infos[i].keys = []string{strings.ToLower(s.Name)}
+		}
+	}
+	return infos
+}
+
+// match pairs the entities of two versions, first by a common key, then
+// the nearest entities of the same class within matchDistance. Pairs are
+// returned in the order of a, removed and added entities by index.
+func match(a, b []matchInfo) (pairs [][2]int, removed, added []int) {
+	partner := make([]int, len(a))
+	taken := make([]bool, len(b))
+	for i := range partner {
+		partner[i] = -1
+	}
+
+	owner := make(map[string]int)
+	for j := len(b) - 1; j >= 0; j-- {
+		for _, key := range b[j].keys {
+			owner[key] = j
+		}
+	}
+	for i, info := range a {
+		for _, key := range info.keys {
+			if j, found := owner[key]; found && !taken[j] {
+				partner[i] = j
+				taken[j] = true
+				break
+			}
+		}
+	}
+
+	// pair the closest entities first, candidates are looked up in a grid
+	idx := NewSpatialIndex(matchDistance)
+	for j, info := range b {
+		if !taken[j] {
+			idx.add(&SpatialEntry{Entity: j, Position: info.position})
+		}
+	}
+	var candidates []matchCandidate
+	for i, info := range a {
+		if partner[i] >= 0 || hasKey(owner, info.keys) {
+			continue
+		}
+		idx.visit(info.position.XY(), matchDistance, func(e *SpatialEntry) {
+			j := e.Entity.(int)
+			if b[j].class != info.class {
+				return
+			}
+			if dist := info.position.Dist2D(b[j].position); dist <= matchDistance {
+				candidates = append(candidates, matchCandidate{dist, i, j})
+			}
+		})
+	}
+	sort.Slice(candidates, func(x, y int) bool {
+		cx, cy := candidates[x], candidates[y]
+		if cx.dist != cy.dist {
+			return cx.dist < cy.dist
+		}
+		if cx.a != cy.a {
+			return cx.a < cy.a
+		}
+		return cx.b < cy.b
+	})
+	for _, c := range candidates {
+		if partner[c.a] < 0 && !taken[c.b] {
+			partner[c.a] = c.b
+			taken[c.b] = true
+		}
+	}
+
+	for i, j := range partner {
+		if j < 0 {
+			removed = append(removed, i)
+		} else {
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+	for j := range b {
+		if !taken[j] {
+			added = append(added, j)
+		}
+	}
+	return pairs, removed, added
+}
+
+// matchCandidate is a pair of entities within matchDistance
+type matchCandidate struct {
+	dist float64
+	a, b int
+}
+
+// hasKey returns true if one of the keys is used in the other version, the
+// entity was then replaced and is not matched by position
+func hasKey(owner map[string]int, keys []string) bool {
+	for _, key := range keys {
+		if _, found := owner[key]; found {
+			return true
+		}
+	}
+	return false
+}
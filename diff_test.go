@@ -0,0 +1,120 @@
+package gosqm
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+func diffStrings(changes []*Change) []string {
+	var lines []string
+	for _, c := range changes {
+		lines = append(lines, c.String())
+	}
+	return lines
+}
+
+func TestDiffMissions(t *testing.T) {
+	Convey("Given two versions of a mission", t, func() {
+		old := &Mission{
+			Groups: []*Group{
+				&Group{Side: SideEast, Units: []*Vehicle{&Vehicle{Name: "ivan", Classname: "RU_Soldier"}}},
+				&Group{Side: SideWest, Units: []*Vehicle{
+					&Vehicle{Name: "lead", Classname: "USMC_Soldier_SL"},
+					&Vehicle{Name: "medic", Classname: "USMC_Soldier_Medic", Skill: Number(0.6)},
+				}, Waypoints: []*Waypoint{&Waypoint{Type: WaypointMove}}},
+			},
+			Vehicles: []*Vehicle{&Vehicle{Classname: "HMMWV", Position: Vec3{1000, 1000, 0}}},
+			Markers:  []*Marker{&Marker{Name: "obj_alpha", Text: "Alpha"}, &Marker{Name: "respawn_west"}},
+			Sensors:  []*Sensor{&Sensor{Condition: "this"}},
+		}
+		changed := &Mission{
+			Groups: []*Group{
+				&Group{Side: SideWest, Units: []*Vehicle{
+					&Vehicle{Name: "lead", Classname: "USMC_Soldier_SL"},
+					&Vehicle{Name: "medic", Classname: "USMC_Soldier_Medic", Skill: Number(0.8), Position: Vec3{120, 0, 0}},
+					&Vehicle{Classname: "USMC_Soldier_AR"},
+				}, Waypoints: []*Waypoint{&Waypoint{Type: WaypointMove}, &Waypoint{}}},
+			},
+			Vehicles: []*Vehicle{&Vehicle{Classname: "HMMWV", Position: Vec3{1010, 1000, 0}}},
+			Markers:  []*Marker{&Marker{Name: "obj_alpha", Text: "Objective Alpha"}, &Marker{Name: "respawn_west"}},
+			Sensors:  []*Sensor{&Sensor{Condition: "true", Position: Vec3{10, 0, 0}}},
+		}
+		a := &MissionFile{Mission: old}
+		b := &MissionFile{Mission: changed, Intro: &Mission{Markers: []*Marker{&Marker{Name: "intro"}}}}
+		changes := DiffMissions(a, b)
+
+		Convey("Changes are reported in domain terms", func() {
+			So(diffStrings(changes), ShouldResemble, []string{
+				"Group 1 (EAST) removed",
+				"Group 1 (WEST): unit 'medic' moved 120 m, skill 0.6→0.8",
+				"Group 1 (WEST): unit 3 (USMC_Soldier_AR) added",
+				"Group 1 (WEST): waypoint 2 added",
+				"vehicle 1 (HMMWV) moved 10 m",
+				"Marker 'obj_alpha' text changed",
+				"Sensor 1 moved 10 m, condition changed",
+				"Intro: Marker 'intro' added",
+			})
+		})
+		Convey("Field values are kept", func() {
+			So(changes[1].Moved, ShouldEqual, 120)
+			So(changes[1].Fields, ShouldResemble, []FieldChange{{"skill", "0.6", "0.8"}})
+			So(changes[5].Fields, ShouldResemble, []FieldChange{{"text", "Alpha", "Objective Alpha"}})
+		})
+		Convey("Identical missions have no changes", func() {
+			So(DiffMissions(a, a), ShouldBeEmpty)
+		})
+		Convey("Changes are written as text and JSON", func() {
+			var buf bytes.Buffer
+			So(WriteChanges(&buf, changes), ShouldBeNil)
+			So(strings.Count(buf.String(), "\n"), ShouldEqual, len(changes))
+			buf.Reset()
+			So(WriteChangesJSON(&buf, changes), ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, `"kind": "removed"`)
+			buf.Reset()
+			So(WriteChangesJSON(&buf, nil), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "[]\n")
+		})
+	})
+	Convey("Given renamed and replaced entities", t, func() {
+		a := []matchInfo{{keys: []string{"a"}, class: "x"}, {keys: []string{"b"}, class: "x", position: Vec3{100, 0, 0}}, {class: "y"}}
+		b := []matchInfo{{keys: []string{"c"}, class: "x", position: Vec3{5, 0, 0}}, {keys: []string{"b"}, class: "x"}, {class: "y", position: Vec3{60, 0, 0}}}
+		pairs, removed, added := match(a, b)
+
+		Convey("Keys win over positions, then the nearest entity is taken", func() {
+			So(pairs, ShouldResemble, [][2]int{{0, 0}, {1, 1}})
+			So(removed, ShouldResemble, []int{2})
+			So(added, ShouldResemble, []int{2})
+		})
+	})
+}
+
+// scatteredVehicles returns n unnamed empty vehicles 10 metres apart
+func scatteredVehicles(n int, offset float64) []*Vehicle {
+	vehicles := make([]*Vehicle, n)
+	for i := range vehicles {
+		vehicles[i] = &Vehicle{Classname: "HMMWV", Position: Vec3{float64(i%100)*10 + offset, float64(i/100) * 10, 0}}
+	}
+	return vehicles
+}
+
+func TestDiffMissionsScale(t *testing.T) {
+	Convey("Given thousands of unnamed vehicles moved by one metre", t, func() {
+		a := &MissionFile{Mission: &Mission{Vehicles: scatteredVehicles(10000, 0)}}
+		b := &MissionFile{Mission: &Mission{Vehicles: scatteredVehicles(10000, 1)}}
+
+		Convey("Every vehicle is matched to its moved version", func() {
+			changed := 0
+			for _, c := range DiffMissions(a, b) {
+				if c.Kind == ChangeChanged {
+					changed++
+				}
+			}
+			So(changed, ShouldEqual, 10000)
+		})
+		Convey("An unchanged mission has no changes", func() {
+			So(DiffMissions(a, a), ShouldBeEmpty)
+		})
+	})
+}
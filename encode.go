@@ -5,35 +5,44 @@ import (
 	"io"
 	"strconv"
 	"sync"
-	"sync/atomic"
 )
 
-type Encoder struct {
-	wg *sync.WaitGroup
-	w  io.Writer
+// EncoderOptions controls the output of the encoder.
+type EncoderOptions struct {
+	// OmitEmptyStages drops the intro and outro stages which are not set
+	// or contain no entities. The Mission stage is always written.
+	OmitEmptyStages bool
 }
 
-type counter int32
-
-func (c *counter) inc() int32 {
-	return atomic.AddInt32((*int32)(c), 1)
-}
-func (c *counter) get() int32 {
-	return atomic.LoadInt32((*int32)(c))
+type Encoder struct {
+	wg      *sync.WaitGroup
+	w       io.Writer
+	errors  []error
+	options EncoderOptions
 }
 
-func NewClassEncoder() *Encoder {
+// NewClassEncoder returns an encoder for EncodeToClass, the first options
+// are used if given.
+func NewClassEncoder(options ...EncoderOptions) *Encoder {
 	e := &Encoder{
 		wg: &sync.WaitGroup{},
 	}
+	if len(options) > 0 {
+		e.options = options[0]
+	}
 	return e
 }
 
-func NewEncoder(w io.Writer) *Encoder {
+// NewEncoder returns an encoder writing to w, the first options are used
+// if given.
+func NewEncoder(w io.Writer, options ...EncoderOptions) *Encoder {
 	e := &Encoder{
 		wg: &sync.WaitGroup{},
 		w:  w,
 	}
+	if len(options) > 0 {
+		e.options = options[0]
+	}
 	return e
 }
 
@@ -45,72 +54,67 @@ func (e *Encoder) Encode(missionFile *MissionFile) error {
 
 func (e *Encoder) EncodeToClass(missionFile *MissionFile) *sqm.Class {
 	e.wg = &sync.WaitGroup{}
+	e.errors = nil
 	c := e.encodeMissionFile(missionFile)
 	e.wg.Wait()
 	return c
 }
 
+// Warnings returns the dangling references omitted by the last encoding.
+func (e *Encoder) Warnings() []error {
+	return e.errors
+}
+
+// stageIDs assigns the ids of a stage before it is encoded concurrently
+func (e *Encoder) stageIDs(mission *Mission) *stageIDs {
+	ids, errs := buildStageIDs(mission)
+	e.errors = append(e.errors, errs...)
+	return ids
+}
+
+// encodeMissionFile encodes the stages in file order, stages which are not
+// set are written empty unless they are omitted
 func (e *Encoder) encodeMissionFile(missionFile *MissionFile) *sqm.Class {
 	mainClass := &sqm.Class{
 		Name: "mission",
 	}
 	mainClass.Props = append(mainClass.Props, &sqm.Property{"version", sqm.TNumber, missionFile.Version})
 
-	missionClass := &sqm.Class{
-		Name: "Mission",
-	}
-	e.wg.Add(1)
-	go func() {
-		e.encodeMission(missionFile.Mission, missionClass)
-		e.wg.Done()
-	}()
-	mainClass.Classes = append(mainClass.Classes, missionClass)
-	introClass := &sqm.Class{
-		Name: "Intro",
-	}
-	e.wg.Add(1)
-	go func() {
-		e.encodeMission(missionFile.Intro, introClass)
-		e.wg.Done()
-	}()
-
-	mainClass.Classes = append(mainClass.Classes, introClass)
-
-	outroWinClass := &sqm.Class{
-		Name: "OutroWin",
-	}
-	e.wg.Add(1)
-	go func() {
-		e.encodeMission(missionFile.OutroWin, outroWinClass)
-		e.wg.Done()
-	}()
-	mainClass.Classes = append(mainClass.Classes, outroWinClass)
-
-	outroLooseClass := &sqm.Class{
-		Name: "OutroLoose",
-	}
-	e.wg.Add(1)
-	go func() {
-		e.encodeMission(missionFile.OutroLoose, outroLooseClass)
-		e.wg.Done()
-	}()
-	mainClass.Classes = append(mainClass.Classes, outroLooseClass)
-
+	for _, name := range StageNames {
+		mission := missionFile.Stage(name)
+		if e.options.OmitEmptyStages && name != StageMission && (mission == nil || mission.IsEmpty()) {
+			continue
+		}
+		if mission == nil {
+			mission = &Mission{}
+		}
+		stageClass := &sqm.Class{
+			Name: name,
+		}
+		ids := e.stageIDs(mission)
+		e.wg.Add(1)
+		go func() {
+			e.encodeMission(mission, stageClass, ids)
+			e.wg.Done()
+		}()
+		mainClass.Classes = append(mainClass.Classes, stageClass)
+	}
 	return mainClass
 }
 
-func (e *Encoder) encodeMission(mission *Mission, class *sqm.Class) {
-	var counter counter = -1
+func (e *Encoder) encodeMission(mission *Mission, class *sqm.Class, ids *stageIDs) {
 	encodeMissionProperties(mission, class)
-	intelClass := &sqm.Class{
-		Name: "Intel",
+	if mission.Intel != nil {
+		intelClass := &sqm.Class{
+			Name: "Intel",
+		}
+		e.wg.Add(1)
+		go func() {
+			encodeIntel(mission.Intel, intelClass)
+			e.wg.Done()
+		}()
+		class.Classes = append(class.Classes, intelClass)
 	}
-	e.wg.Add(1)
-	go func() {
-		encodeIntel(mission.Intel, intelClass)
-		e.wg.Done()
-	}()
-	class.Classes = append(class.Classes, intelClass)
 
 	if len(mission.Groups) > 0 {
 		groupsClass := &sqm.Class{
@@ -118,7 +122,7 @@ func (e *Encoder) encodeMission(mission *Mission, class *sqm.Class) {
 		}
 		e.wg.Add(1)
 		go func() {
-			e.encodeGroups(mission.Groups, groupsClass, &counter)
+			e.encodeGroups(mission.Groups, groupsClass, ids)
 			e.wg.Done()
 		}()
 		class.Classes = append(class.Classes, groupsClass)
@@ -142,7 +146,7 @@ func (e *Encoder) encodeMission(mission *Mission, class *sqm.Class) {
 		}
 		e.wg.Add(1)
 		go func() {
-			encodeSensors(mission.Sensors, sensorsClass)
+			encodeSensors(mission.Sensors, sensorsClass, ids)
 			e.wg.Done()
 		}()
 		class.Classes = append(class.Classes, sensorsClass)
@@ -153,7 +157,7 @@ func (e *Encoder) encodeMission(mission *Mission, class *sqm.Class) {
 		}
 		e.wg.Add(1)
 		go func() {
-			e.encodeVehicles(mission.Vehicles, vehsClass, &counter)
+			e.encodeVehicles(mission.Vehicles, vehsClass, ids)
 			e.wg.Done()
 		}()
 		class.Classes = append(class.Classes, vehsClass)
@@ -167,6 +171,8 @@ func encodeMissionProperties(mission *Mission, class *sqm.Class) {
 }
 
 func encodeIntel(i *Intel, class *sqm.Class) {
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"briefingName", sqm.TString, i.BriefingName})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"overviewText", sqm.TString, i.OverviewText})
 	var resistanceWest string
 	if i.ResistanceWest {
 		resistanceWest = "1"
@@ -174,46 +180,58 @@ func encodeIntel(i *Intel, class *sqm.Class) {
 		resistanceWest = "0"
 	}
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"resistanceWest", sqm.TNumber, resistanceWest})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"startWeather", sqm.TNumber, i.StartWeather})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"forecastWeather", sqm.TNumber, i.ForecastWeather})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"year", sqm.TNumber, i.Year})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"month", sqm.TNumber, i.Month})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"day", sqm.TNumber, i.Day})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"hour", sqm.TNumber, i.Hour})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"minute", sqm.TNumber, i.Minute})
+	w := i.Weather
+	class.Props = addNumberOmitZero(class.Props, "timeOfChanges", w.TimeOfChanges)
+	class.Props = addNumberOmitNil(class.Props, "startWeather", w.StartWeather)
+	class.Props = addNumberOmitZero(class.Props, "startWind", w.StartWind)
+	class.Props = addNumberOmitZero(class.Props, "startGust", w.StartGust)
+	class.Props = addNumberOmitZero(class.Props, "startWaves", w.StartWaves)
+	class.Props = addNumberOmitZero(class.Props, "startRain", w.StartRain)
+	class.Props = addNumberOmitZero(class.Props, "startLightnings", w.StartLightnings)
+	class.Props = addNumberOmitZero(class.Props, "startFog", w.StartFog)
+	class.Props = addNumberOmitNil(class.Props, "forecastWeather", w.ForecastWeather)
+	class.Props = addNumberOmitZero(class.Props, "forecastFog", w.ForecastFog)
+	class.Props = addNumberOmitZero(class.Props, "year", float64(i.Year))
+	class.Props = addNumberOmitZero(class.Props, "month", float64(i.Month))
+	class.Props = addNumberOmitZero(class.Props, "day", float64(i.Day))
+	// midnight is a valid time once the date is set
+	if i.Year != 0 || i.Hour != 0 || i.Minute != 0 {
+		class.Props = addProp(class.Props, &sqm.Property{"hour", sqm.TNumber, strconv.Itoa(i.Hour)})
+		class.Props = addProp(class.Props, &sqm.Property{"minute", sqm.TNumber, strconv.Itoa(i.Minute)})
+	}
 }
 
-func (e *Encoder) encodeVehicles(vehs []*Vehicle, class *sqm.Class, counter *counter) {
+func (e *Encoder) encodeVehicles(vehs []*Vehicle, class *sqm.Class, ids *stageIDs) {
 	class.Props = append(class.Props, &sqm.Property{"items", sqm.TNumber, strconv.Itoa(len(vehs))})
 	for i, v := range vehs {
 		vehClass := &sqm.Class{
 			Name: "Item" + strconv.Itoa(i),
 		}
 
-		encodeVehicle(v, vehClass, counter)
+		encodeVehicle(v, vehClass, ids)
 		class.Classes = append(class.Classes, vehClass)
 	}
 }
 
-func encodeSensors(sensors []*Sensor, class *sqm.Class) {
+func encodeSensors(sensors []*Sensor, class *sqm.Class, ids *stageIDs) {
 	class.Props = append(class.Props, &sqm.Property{"items", sqm.TNumber, strconv.Itoa(len(sensors))})
 	for i, s := range sensors {
 		sensorClass := &sqm.Class{
 			Name: "Item" + strconv.Itoa(i),
 		}
-		encodeSensor(s, sensorClass)
+		encodeSensor(s, sensorClass, ids)
 		class.Classes = append(class.Classes, sensorClass)
 	}
 }
 
-func encodeSensor(s *Sensor, class *sqm.Class) {
-	class.Arrprops = addArrProp(class.Arrprops, &sqm.ArrayProperty{"position", sqm.TNumber, s.Position[:]})
-	class.Arrprops = addArrProp(class.Arrprops, &sqm.ArrayProperty{"synchronizations", sqm.TNumber, s.Synchronizations[:]})
+func encodeSensor(s *Sensor, class *sqm.Class, ids *stageIDs) {
+	class.Arrprops = addArrProp(class.Arrprops, &sqm.ArrayProperty{"position", sqm.TNumber, formatVec3(s.Position)})
+	class.Arrprops = addArrPropOmitEmpty(class.Arrprops, &sqm.ArrayProperty{"synchronizations", sqm.TNumber, ids.syncs[s]})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"name", sqm.TString, s.Name})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"a", sqm.TNumber, s.Size[0]})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"b", sqm.TNumber, s.Size[1]})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"angle", sqm.TNumber, s.Angle})
-	class.Props = addProp(class.Props, &sqm.Property{"activationBy", sqm.TString, s.ActivationBy})
+	class.Props = addNumberOmitZero(class.Props, "a", s.Size.X)
+	class.Props = addNumberOmitZero(class.Props, "b", s.Size.Y)
+	class.Props = addNumberOmitZero(class.Props, "angle", s.Angle)
+	class.Props = addProp(class.Props, &sqm.Property{"activationBy", sqm.TString, string(s.ActivationBy)})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"activationType", sqm.TString, s.ActivationType})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"timeoutMin", sqm.TNumber, s.TimeoutMin})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"timeoutMid", sqm.TNumber, s.TimeoutMid})
@@ -233,7 +251,7 @@ func encodeSensor(s *Sensor, class *sqm.Class) {
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"expCond", sqm.TString, s.Condition})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"expActiv", sqm.TString, s.OnActivation})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"expDesactiv", sqm.TString, s.OnDeactivation})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"idVehicle", sqm.TNumber, s.VehicleID})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"idVehicle", sqm.TNumber, ids.vehicleID(s.Vehicle)})
 	if s.Effects != nil {
 		effClass := &sqm.Class{
 			Name: "Effects",
@@ -266,12 +284,12 @@ func encodeMarkers(markers []*Marker, class *sqm.Class) {
 }
 
 func encodeMarker(m *Marker, class *sqm.Class) {
-	class.Arrprops = addArrProp(class.Arrprops, &sqm.ArrayProperty{"position", sqm.TNumber, m.Position[:]})
+	class.Arrprops = addArrProp(class.Arrprops, &sqm.ArrayProperty{"position", sqm.TNumber, formatVec3(m.Position)})
 	class.Props = addProp(class.Props, &sqm.Property{"name", sqm.TString, m.Name})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"angle", sqm.TNumber, m.Angle})
+	class.Props = addNumberOmitZero(class.Props, "angle", m.Angle)
 	class.Props = addProp(class.Props, &sqm.Property{"type", sqm.TString, m.Type})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"text", sqm.TString, m.Text})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"markerType", sqm.TString, m.MarkerType})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"markerType", sqm.TString, string(m.MarkerType)})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"colorName", sqm.TString, m.ColorName})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"fillName", sqm.TString, m.FillName})
 	var drawBorder string
@@ -279,10 +297,10 @@ func encodeMarker(m *Marker, class *sqm.Class) {
 		drawBorder = "1"
 	}
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"drawBorder", sqm.TNumber, drawBorder})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"a", sqm.TNumber, m.Size[0]})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"b", sqm.TNumber, m.Size[1]})
+	class.Props = addNumberOmitZero(class.Props, "a", m.Size.X)
+	class.Props = addNumberOmitZero(class.Props, "b", m.Size.Y)
 }
-func (e *Encoder) encodeGroups(groups []*Group, class *sqm.Class, counter *counter) {
+func (e *Encoder) encodeGroups(groups []*Group, class *sqm.Class, ids *stageIDs) {
 	class.Props = append(class.Props, &sqm.Property{"items", sqm.TNumber, strconv.Itoa(len(groups))})
 	for i, g := range groups {
 		groupClass := &sqm.Class{
@@ -290,7 +308,7 @@ func (e *Encoder) encodeGroups(groups []*Group, class *sqm.Class, counter *count
 		}
 		e.wg.Add(1)
 		go func(g *Group, groupClass *sqm.Class) {
-			e.encodeGroup(g, groupClass, counter)
+			e.encodeGroup(g, groupClass, ids)
 			e.wg.Done()
 		}(g, groupClass)
 
@@ -298,14 +316,17 @@ func (e *Encoder) encodeGroups(groups []*Group, class *sqm.Class, counter *count
 	}
 }
 
-func (e *Encoder) encodeGroup(g *Group, class *sqm.Class, counter *counter) {
-	class.Props = addProp(class.Props, &sqm.Property{"side", sqm.TString, g.Side})
+func (e *Encoder) encodeGroup(g *Group, class *sqm.Class, ids *stageIDs) {
+	class.Props = addProp(class.Props, &sqm.Property{"side", sqm.TString, string(g.Side)})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"id", sqm.TNumber, g.ID})
+	class.Props = append(class.Props, g.ExtraProps...)
+	class.Arrprops = append(class.Arrprops, g.ExtraArrprops...)
 	if len(g.Units) > 0 {
 		groupMemberClass := &sqm.Class{
 			Name: "Vehicles",
 		}
 		groupMemberClass.Props = append(groupMemberClass.Props, &sqm.Property{"items", sqm.TNumber, strconv.Itoa(len(g.Units))})
-		e.encodeGroupMembers(g.Units, groupMemberClass, counter)
+		e.encodeGroupMembers(g.Units, groupMemberClass, ids)
 		class.Classes = append(class.Classes, groupMemberClass)
 	}
 
@@ -314,73 +335,90 @@ func (e *Encoder) encodeGroup(g *Group, class *sqm.Class, counter *counter) {
 			Name: "Waypoints",
 		}
 		waypointsClass.Props = append(waypointsClass.Props, &sqm.Property{"items", sqm.TNumber, strconv.Itoa(len(g.Waypoints))})
-		encodeWaypoints(g.Waypoints, waypointsClass)
+		encodeWaypoints(g.Waypoints, waypointsClass, ids)
 		class.Classes = append(class.Classes, waypointsClass)
 	}
+	class.Classes = append(class.Classes, g.ExtraClasses...)
 }
 
-func (e *Encoder) encodeGroupMembers(units []*Vehicle, class *sqm.Class, counter *counter) {
+func (e *Encoder) encodeGroupMembers(units []*Vehicle, class *sqm.Class, ids *stageIDs) {
 	for i, unit := range units {
 		unitclass := &sqm.Class{
 			Name: "Item" + strconv.Itoa(i),
 		}
 
-		encodeVehicle(unit, unitclass, counter)
+		encodeVehicle(unit, unitclass, ids)
 		class.Classes = append(class.Classes, unitclass)
 	}
 }
 
-func encodeVehicle(v *Vehicle, class *sqm.Class, counter *counter) {
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"id", sqm.TNumber, strconv.Itoa(int(counter.inc()))})
-	class.Arrprops = addArrProp(class.Arrprops, &sqm.ArrayProperty{"position", sqm.TNumber, v.Position[:]})
-	class.Arrprops = addArrPropOmitEmpty(class.Arrprops, &sqm.ArrayProperty{"markers", sqm.TString, v.Markers[:]})
+func encodeVehicle(v *Vehicle, class *sqm.Class, ids *stageIDs) {
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"id", sqm.TNumber, ids.vehicleID(v)})
+	class.Arrprops = addArrProp(class.Arrprops, &sqm.ArrayProperty{"position", sqm.TNumber, formatVec3(v.Position)})
+	class.Arrprops = addArrPropOmitEmpty(class.Arrprops, &sqm.ArrayProperty{"markers", sqm.TString, ids.markerNames(v.Markers)})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"text", sqm.TString, v.Name})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"azimut", sqm.TNumber, v.Angle})
+	class.Props = addNumberOmitZero(class.Props, "azimut", v.Angle)
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"vehicle", sqm.TString, v.Classname})
 	var leader string
 	if v.IsLeader {
 		leader = "1"
 	}
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"leader", sqm.TNumber, leader})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"special", sqm.TString, v.Special})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"skill", sqm.TNumber, v.Skill})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"special", sqm.TString, string(v.Special)})
+	class.Props = addNumberOmitNil(class.Props, "skill", v.Skill)
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"player", sqm.TString, v.Player})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"description", sqm.TString, v.Description})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"presence", sqm.TNumber, v.Presence})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"presenceCondition", sqm.TString, v.PresenceCond})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"placement", sqm.TNumber, v.Placement})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"age", sqm.TString, v.Age})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"lock", sqm.TString, v.Lock})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"rank", sqm.TString, v.Rank})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"health", sqm.TNumber, v.Health})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"fuel", sqm.TNumber, v.Fuel})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"ammo", sqm.TNumber, v.Ammo})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"lock", sqm.TString, string(v.Lock)})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"rank", sqm.TString, string(v.Rank)})
+	class.Props = addNumberOmitNil(class.Props, "health", v.Health)
+	class.Props = addNumberOmitNil(class.Props, "fuel", v.Fuel)
+	class.Props = addNumberOmitNil(class.Props, "ammo", v.Ammo)
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"init", sqm.TString, v.Init})
-	if v.Side == "" {
-		class.Props = addProp(class.Props, &sqm.Property{"side", sqm.TString, "EMPTY"})
-	} else {
-		class.Props = addProp(class.Props, &sqm.Property{"side", sqm.TString, v.Side})
+	side := v.Side
+	if side == "" {
+		side = SideEmpty
 	}
+	class.Props = addProp(class.Props, &sqm.Property{"side", sqm.TString, string(side)})
 
 	if v.ForceHeadlessClient {
 		class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"forceHeadlessClient", sqm.TNumber, "1"})
 	}
 }
 
-func encodeWaypoints(waypoints []*Waypoint, class *sqm.Class) {
+func encodeWaypoints(waypoints []*Waypoint, class *sqm.Class, ids *stageIDs) {
 	for i, waypoint := range waypoints {
 		waypointclass := &sqm.Class{
 			Name: "Item" + strconv.Itoa(i),
 		}
-		encodeWaypoint(waypoint, waypointclass)
+		encodeWaypoint(waypoint, waypointclass, ids)
 		class.Classes = append(class.Classes, waypointclass)
 	}
 }
 
-func encodeWaypoint(w *Waypoint, class *sqm.Class) {
-	class.Arrprops = addArrProp(class.Arrprops, &sqm.ArrayProperty{"position", sqm.TNumber, w.Position[:]})
-	class.Arrprops = addArrProp(class.Arrprops, &sqm.ArrayProperty{"synchronizations", sqm.TNumber, w.Synchronizations[:]})
-	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"type", sqm.TString, w.Type})
+func encodeWaypoint(w *Waypoint, class *sqm.Class, ids *stageIDs) {
+	class.Arrprops = addArrProp(class.Arrprops, &sqm.ArrayProperty{"position", sqm.TNumber, formatVec3(w.Position)})
+	class.Arrprops = addArrPropOmitEmpty(class.Arrprops, &sqm.ArrayProperty{"synchronizations", sqm.TNumber, ids.syncs[w]})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"idStatic", sqm.TNumber, w.StaticID})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"housePos", sqm.TNumber, w.HousePos})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"idObject", sqm.TNumber, ids.vehicleID(w.Vehicle)})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"type", sqm.TString, string(w.Type)})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"combatMode", sqm.TString, string(w.CombatMode)})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"formation", sqm.TString, string(w.Formation)})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"speed", sqm.TString, string(w.Speed)})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"combat", sqm.TString, string(w.Combat)})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"description", sqm.TString, w.Description})
+	class.Props = addNumberOmitZero(class.Props, "placement", w.Placement)
+	class.Props = addNumberOmitZero(class.Props, "completitionRadius", w.CompletionRadius)
+	class.Props = addNumberOmitZero(class.Props, "timeoutMin", w.TimeoutMin)
+	class.Props = addNumberOmitZero(class.Props, "timeoutMid", w.TimeoutMid)
+	class.Props = addNumberOmitZero(class.Props, "timeoutMax", w.TimeoutMax)
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"expCond", sqm.TString, w.Condition})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"expActiv", sqm.TString, w.OnActivation})
+	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"script", sqm.TString, w.Script})
 	class.Props = addPropOmitEmpty(class.Props, &sqm.Property{"showWP", sqm.TString, w.ShowWP})
 	if w.Effects != nil {
 		effClass := &sqm.Class{
@@ -405,6 +443,20 @@ func addArrPropOmitEmpty(props []*sqm.ArrayProperty, prop *sqm.ArrayProperty) []
 func addProp(props []*sqm.Property, prop *sqm.Property) []*sqm.Property {
 	return append(props, prop)
 }
+func addNumberOmitZero(props []*sqm.Property, name string, f float64) []*sqm.Property {
+	if f != 0 {
+		return append(props, &sqm.Property{name, sqm.TNumber, FormatNumber(f)})
+	}
+	return props
+}
+
+func addNumberOmitNil(props []*sqm.Property, name string, f *float64) []*sqm.Property {
+	if f != nil {
+		return append(props, &sqm.Property{name, sqm.TNumber, FormatNumber(*f)})
+	}
+	return props
+}
+
 func addPropOmitEmpty(props []*sqm.Property, prop *sqm.Property) []*sqm.Property {
 	if prop.Value != "" {
 		return append(props, prop)
@@ -1,9 +1,11 @@
 package gosqm
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/blang/gosqm/sqm"
 	. "github.com/smartystreets/goconvey/convey"
+	"strings"
 	"testing"
 )
 
@@ -57,14 +59,19 @@ func ShouldContainClassWithName(actual interface{}, expected ...interface{}) str
 func TestEncodeIntel(t *testing.T) {
 	Convey("Given a fresh intel", t, func() {
 		intel := &Intel{
-			ResistanceWest:  false,
-			StartWeather:    "0.3",
-			ForecastWeather: "0.8",
-			Year:            "2009",
-			Month:           "10",
-			Day:             "28",
-			Hour:            "6",
-			Minute:          "5",
+			BriefingName:   "Dawn",
+			ResistanceWest: false,
+			Weather: Weather{
+				StartWeather:    Number(0.3),
+				ForecastWeather: Number(0.8),
+				StartFog:        0.1,
+				TimeOfChanges:   1800,
+			},
+			Year:   2009,
+			Month:  10,
+			Day:    28,
+			Hour:   6,
+			Minute: 5,
 		}
 		Convey("When encoding intel", func() {
 			class := &sqm.Class{}
@@ -78,6 +85,43 @@ func TestEncodeIntel(t *testing.T) {
 				So(class.Props, ShouldContainProp, &sqm.Property{"day", sqm.TNumber, "28"})
 				So(class.Props, ShouldContainProp, &sqm.Property{"hour", sqm.TNumber, "6"})
 				So(class.Props, ShouldContainProp, &sqm.Property{"minute", sqm.TNumber, "5"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"briefingName", sqm.TString, "Dawn"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"startFog", sqm.TNumber, "0.1"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"timeOfChanges", sqm.TNumber, "1800"})
+			})
+			Convey("Unset values are omitted", func() {
+				for _, prop := range class.Props {
+					So(prop.Name, ShouldNotEqual, "overviewText")
+					So(prop.Name, ShouldNotEqual, "startRain")
+				}
+			})
+		})
+		Convey("When encoding intel without weather", func() {
+			intel.Weather = Weather{}
+			class := &sqm.Class{}
+			encodeIntel(intel, class)
+			Convey("No weather is added", func() {
+				for _, prop := range class.Props {
+					So(prop.Name, ShouldNotEqual, "startWeather")
+					So(prop.Name, ShouldNotEqual, "forecastWeather")
+				}
+			})
+		})
+		Convey("When decoding and encoding intel without weather", func() {
+			mf, err := NewDecoder(strings.NewReader("version=11;\nclass Mission\n{\n\tclass Intel\n\t{\n\t\tyear=2010;\n\t};\n};\n")).Decode()
+			So(err, ShouldBeNil)
+			var buf bytes.Buffer
+			So(NewEncoder(&buf).Encode(mf), ShouldBeNil)
+			So(buf.String(), ShouldNotContainSubstring, "Weather")
+		})
+		Convey("When encoding intel at midnight", func() {
+			intel.Hour = 0
+			intel.Minute = 0
+			class := &sqm.Class{}
+			encodeIntel(intel, class)
+			Convey("Hour and minute are written", func() {
+				So(class.Props, ShouldContainProp, &sqm.Property{"hour", sqm.TNumber, "0"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"minute", sqm.TNumber, "0"})
 			})
 		})
 	})
@@ -106,10 +150,10 @@ func TestEncodeVehicle(t *testing.T) {
 	Convey("Given fresh unit/vehicle", t, func() {
 		veh := &Vehicle{
 			Name:                "name",
-			Position:            [3]string{"1.0", "2.0", "3.0"},
-			Angle:               "0.3",
+			Position:            Vec3{X: 1, Y: 3, Z: 2},
+			Angle:               0.3,
 			Classname:           "classname",
-			Skill:               "0.1",
+			Skill:               Number(0.1),
 			Special:             "FORM",
 			IsLeader:            true,
 			Player:              "PLAYER COMMANDER",
@@ -120,20 +164,23 @@ func TestEncodeVehicle(t *testing.T) {
 			Age:                 "5 MIN",
 			Lock:                "UNLOCKED",
 			Rank:                "CORPORAL",
-			Health:              "0.1",
-			Fuel:                "0.2",
-			Ammo:                "0.3",
+			Health:              Number(0.1),
+			Fuel:                Number(0.2),
+			Ammo:                Number(0.3),
 			Init:                "hint a",
 			Side:                "WEST",
 			ForceHeadlessClient: true,
-			Markers:             []string{"a", "b"},
+			Markers:             []*Marker{&Marker{Name: "a"}, &Marker{Name: "b"}},
 		}
-		var idCount counter
+		ids, _ := buildStageIDs(&Mission{
+			Vehicles: []*Vehicle{&Vehicle{}, veh},
+			Markers:  veh.Markers,
+		})
 		Convey("When encoding vehicle", func() {
 			class := &sqm.Class{}
-			encodeVehicle(veh, class, &idCount)
+			encodeVehicle(veh, class, ids)
 			Convey("Class properties should be set correctly", func() {
-				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"position", sqm.TNumber, []string{"1.0", "2.0", "3.0"}})
+				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"position", sqm.TNumber, []string{"1", "2", "3"}})
 				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"markers", sqm.TString, []string{"a", "b"}})
 				So(class.Props, ShouldContainProp, &sqm.Property{"id", sqm.TNumber, "1"})
 				So(class.Props, ShouldContainProp, &sqm.Property{"text", sqm.TString, "name"})
@@ -161,13 +208,104 @@ func TestEncodeVehicle(t *testing.T) {
 	})
 }
 
+const zeroVehicleMission = `version=11;
+class Mission
+{
+	class Vehicles
+	{
+		items=2;
+		class Item0
+		{
+			position[]={1,2,3};
+			id=0;
+			side="EMPTY";
+			vehicle="HMMWV";
+			skill=0;
+			health=0;
+			fuel=0;
+			ammo=0;
+		};
+		class Item1
+		{
+			position[]={4,5,6};
+			id=1;
+			side="EMPTY";
+			vehicle="HMMWV";
+		};
+	};
+};
+`
+
+func TestEncodeVehicleZeroValues(t *testing.T) {
+	Convey("Given an empty and destroyed vehicle next to an untouched one", t, func() {
+		mf, err := NewDecoder(strings.NewReader(zeroVehicleMission)).Decode()
+		So(err, ShouldBeNil)
+		Convey("Explicit zeros are decoded, missing values are nil", func() {
+			destroyed, untouched := mf.Mission.Vehicles[0], mf.Mission.Vehicles[1]
+			So(*destroyed.Fuel, ShouldEqual, 0)
+			So(*destroyed.Health, ShouldEqual, 0)
+			So(*destroyed.Ammo, ShouldEqual, 0)
+			So(*destroyed.Skill, ShouldEqual, 0)
+			So(untouched.Fuel, ShouldBeNil)
+			So(untouched.Skill, ShouldBeNil)
+		})
+		Convey("Encoding writes the zeros back and nothing else", func() {
+			var buf bytes.Buffer
+			So(NewEncoder(&buf).Encode(mf), ShouldBeNil)
+			out := buf.String()
+			for _, prop := range []string{"skill=0;", "health=0;", "fuel=0;", "ammo=0;"} {
+				So(strings.Count(out, prop), ShouldEqual, 1)
+			}
+		})
+	})
+}
+
+func TestEncodeGroup(t *testing.T) {
+	Convey("Given a group with extra attributes", t, func() {
+		g := &Group{
+			Side:         "WEST",
+			ID:           "3",
+			Units:        []*Vehicle{&Vehicle{Name: "unit"}},
+			ExtraProps:   []*sqm.Property{&sqm.Property{"custom", sqm.TString, "value"}},
+			ExtraClasses: []*sqm.Class{&sqm.Class{Name: "Attributes"}},
+		}
+		ids, _ := buildStageIDs(&Mission{Groups: []*Group{g}})
+		Convey("When encoding group", func() {
+			class := &sqm.Class{}
+			NewClassEncoder().encodeGroup(g, class, ids)
+			Convey("Attributes are written", func() {
+				So(class.Props, ShouldContainProp, &sqm.Property{"side", sqm.TString, "WEST"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"id", sqm.TNumber, "3"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"custom", sqm.TString, "value"})
+				So(class.Classes, ShouldContainClassWithName, "Vehicles")
+				So(class.Classes, ShouldContainClassWithName, "Attributes")
+			})
+		})
+	})
+}
+
 func TestEncodeWaypoint(t *testing.T) {
 	Convey("Given fresh waypoint", t, func() {
 		wp := &Waypoint{
 			Type:             "AND",
-			Position:         [3]string{"1.0", "2.0", "3.0"},
+			Position:         Vec3{X: 1, Y: 3, Z: 2},
+			CombatMode:       CombatModeRed,
+			Formation:        FormationWedge,
+			Speed:            SpeedFull,
+			Combat:           BehaviourCombat,
+			Description:      "Attack",
+			Condition:        "true",
+			OnActivation:     "hint a",
+			Script:           "attack.sqf",
+			TimeoutMin:       1,
+			TimeoutMid:       2,
+			TimeoutMax:       3,
+			Placement:        50,
+			CompletionRadius: 25,
+			StaticID:         "12345",
+			HousePos:         "2",
+			Vehicle:          &Vehicle{},
 			ShowWP:           "NEVER",
-			Synchronizations: []string{"1", "2"},
 			Effects: &Effects{
 				Sound:       "sound",
 				Voice:       "voice",
@@ -179,14 +317,39 @@ func TestEncodeWaypoint(t *testing.T) {
 				TitleEffect: "titleeffect",
 			},
 		}
+		other := &Waypoint{}
+		sensor := &Sensor{}
+		wp.SyncWaypoint(other)
+		wp.SyncSensor(sensor)
+		ids, _ := buildStageIDs(&Mission{
+			Groups:   []*Group{&Group{Waypoints: []*Waypoint{wp, other}}},
+			Vehicles: []*Vehicle{&Vehicle{}, wp.Vehicle},
+			Sensors:  []*Sensor{sensor},
+		})
 		Convey("When encoding waypoint", func() {
 			class := &sqm.Class{}
-			encodeWaypoint(wp, class)
+			encodeWaypoint(wp, class, ids)
 			Convey("Class properties should be set correctly", func() {
-				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"position", sqm.TNumber, []string{"1.0", "2.0", "3.0"}})
-				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"synchronizations", sqm.TNumber, []string{"1", "2"}})
+				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"position", sqm.TNumber, []string{"1", "2", "3"}})
+				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"synchronizations", sqm.TNumber, []string{"0", "1"}})
 				So(class.Props, ShouldContainProp, &sqm.Property{"showWP", sqm.TString, "NEVER"})
 				So(class.Props, ShouldContainProp, &sqm.Property{"type", sqm.TString, "AND"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"combatMode", sqm.TString, "RED"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"formation", sqm.TString, "WEDGE"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"speed", sqm.TString, "FULL"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"combat", sqm.TString, "COMBAT"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"description", sqm.TString, "Attack"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"expCond", sqm.TString, "true"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"expActiv", sqm.TString, "hint a"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"script", sqm.TString, "attack.sqf"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"timeoutMin", sqm.TNumber, "1"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"timeoutMid", sqm.TNumber, "2"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"timeoutMax", sqm.TNumber, "3"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"placement", sqm.TNumber, "50"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"completitionRadius", sqm.TNumber, "25"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"idStatic", sqm.TNumber, "12345"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"housePos", sqm.TNumber, "2"})
+				So(class.Props, ShouldContainProp, &sqm.Property{"idObject", sqm.TNumber, "1"})
 			})
 			Convey("Effects class should be set", func() {
 				So(len(class.Classes), ShouldEqual, 1)
@@ -211,21 +374,21 @@ func TestEncodeMarker(t *testing.T) {
 	Convey("Given fresh marker", t, func() {
 		m := &Marker{
 			Name:       "marker",
-			Angle:      "38.1",
-			Position:   [3]string{"1.0", "2.0", "3.0"},
+			Angle:      38.1,
+			Position:   Vec3{X: 1, Y: 3, Z: 2},
 			Type:       "Empty",
 			MarkerType: "ELLIPSE",
 			Text:       "text",
 			ColorName:  "ColorRed",
 			FillName:   "Border",
 			DrawBorder: true,
-			Size:       [2]string{"100", "200"},
+			Size:       Vec2{100, 200},
 		}
 		Convey("When encoding marker", func() {
 			class := &sqm.Class{}
 			encodeMarker(m, class)
 			Convey("Class properties should be set correctly", func() {
-				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"position", sqm.TNumber, []string{"1.0", "2.0", "3.0"}})
+				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"position", sqm.TNumber, []string{"1", "2", "3"}})
 				So(class.Props, ShouldContainProp, &sqm.Property{"name", sqm.TString, "marker"})
 				So(class.Props, ShouldContainProp, &sqm.Property{"angle", sqm.TNumber, "38.1"})
 				So(class.Props, ShouldContainProp, &sqm.Property{"type", sqm.TString, "Empty"})
@@ -244,25 +407,24 @@ func TestEncodeMarker(t *testing.T) {
 func TestEncodeSensor(t *testing.T) {
 	Convey("Given a fresh sensor", t, func() {
 		s := &Sensor{
-			Name:             "sensor",
-			Position:         [3]string{"1.0", "2.0", "3.0"},
-			Size:             [2]string{"100", "200"},
-			Angle:            "12.3",
-			IsRectangle:      true,
-			ActivationBy:     "ANY",
-			ActivationType:   "GUER D",
-			TimeoutMin:       "1",
-			TimeoutMid:       "2",
-			TimeoutMax:       "3",
-			Type:             "EAST G",
-			IsRepeating:      true,
-			Age:              "UNKNOWN",
-			Condition:        "isServer",
-			OnActivation:     "hint test",
-			OnDeactivation:   "hint test2",
-			Text:             "triggertext",
-			IsInterruptible:  true,
-			Synchronizations: []string{"1", "2"},
+			Name:            "sensor",
+			Position:        Vec3{X: 1, Y: 3, Z: 2},
+			Size:            Vec2{100, 200},
+			Angle:           12.3,
+			IsRectangle:     true,
+			ActivationBy:    "ANY",
+			ActivationType:  "GUER D",
+			TimeoutMin:      "1",
+			TimeoutMid:      "2",
+			TimeoutMax:      "3",
+			Type:            "EAST G",
+			IsRepeating:     true,
+			Age:             "UNKNOWN",
+			Condition:       "isServer",
+			OnActivation:    "hint test",
+			OnDeactivation:  "hint test2",
+			Text:            "triggertext",
+			IsInterruptible: true,
 			Effects: &Effects{
 				Sound:       "sound",
 				Voice:       "voice",
@@ -273,14 +435,23 @@ func TestEncodeSensor(t *testing.T) {
 				Title:       "title",
 				TitleEffect: "titleeffect",
 			},
-			VehicleID: "1",
+			Vehicle: &Vehicle{},
 		}
+		wp1 := &Waypoint{}
+		wp2 := &Waypoint{}
+		s.SyncWaypoint(wp1)
+		s.SyncWaypoint(wp2)
+		ids, _ := buildStageIDs(&Mission{
+			Groups:   []*Group{&Group{Waypoints: []*Waypoint{wp1, wp2}}},
+			Vehicles: []*Vehicle{&Vehicle{}, s.Vehicle},
+			Sensors:  []*Sensor{s},
+		})
 		Convey("When encoding sensor", func() {
 			class := &sqm.Class{}
-			encodeSensor(s, class)
+			encodeSensor(s, class, ids)
 			Convey("Class properties should be set correctly", func() {
-				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"position", sqm.TNumber, []string{"1.0", "2.0", "3.0"}})
-				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"synchronizations", sqm.TNumber, []string{"1", "2"}})
+				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"position", sqm.TNumber, []string{"1", "2", "3"}})
+				So(class.Arrprops, ShouldContainProp, &sqm.ArrayProperty{"synchronizations", sqm.TNumber, []string{"0", "1"}})
 				So(class.Props, ShouldContainProp, &sqm.Property{"name", sqm.TString, "sensor"})
 				So(class.Props, ShouldContainProp, &sqm.Property{"a", sqm.TNumber, "100"})
 				So(class.Props, ShouldContainProp, &sqm.Property{"b", sqm.TNumber, "200"})
@@ -326,14 +497,13 @@ func TestEncodeMission(t *testing.T) {
 			Addons:     []string{"add1", "add2"},
 			AddonsAuto: []string{"add3", "add4"},
 			Intel: &Intel{
-				ResistanceWest:  false,
-				StartWeather:    "0.2",
-				ForecastWeather: "0.3",
-				Year:            "2009",
-				Month:           "10",
-				Day:             "5",
-				Hour:            "10",
-				Minute:          "3",
+				ResistanceWest: false,
+				Weather:        Weather{StartWeather: Number(0.2), ForecastWeather: Number(0.3)},
+				Year:           2009,
+				Month:          10,
+				Day:            5,
+				Hour:           10,
+				Minute:         3,
 			},
 			Groups: []*Group{
 				&Group{
@@ -341,12 +511,12 @@ func TestEncodeMission(t *testing.T) {
 					Units: []*Vehicle{
 						&Vehicle{
 							Name:     "unit",
-							Position: [3]string{"1.0", "2.0", "3.0"},
+							Position: Vec3{X: 1, Y: 3, Z: 2},
 						},
 					},
 					Waypoints: []*Waypoint{
 						&Waypoint{
-							Position: [3]string{"1.0", "2.0", "3.0"},
+							Position: Vec3{X: 1, Y: 3, Z: 2},
 							Type:     "AND",
 						},
 					},
@@ -355,29 +525,29 @@ func TestEncodeMission(t *testing.T) {
 			Vehicles: []*Vehicle{
 				&Vehicle{
 					Name:      "veh",
-					Position:  [3]string{"1.0", "2.0", "3.0"},
+					Position:  Vec3{X: 1, Y: 3, Z: 2},
 					Classname: "classname",
 				},
 			},
 			Markers: []*Marker{
 				&Marker{
 					Name:     "marker",
-					Position: [3]string{"1.0", "2.0", "3.0"},
+					Position: Vec3{X: 1, Y: 3, Z: 2},
 					Type:     "empty",
 				},
 			},
 			Sensors: []*Sensor{
 				&Sensor{
 					Name:     "sensor",
-					Position: [3]string{"1.0", "2.0", "3.0"},
-					Size:     [2]string{"100", "200"},
+					Position: Vec3{X: 1, Y: 3, Z: 2},
+					Size:     Vec2{100, 200},
 				},
 			},
 		}
 		Convey("When encoding mission", func() {
 			class := &sqm.Class{}
 			e := NewClassEncoder()
-			e.encodeMission(m, class)
+			e.encodeMission(m, class, e.stageIDs(m))
 			Convey("Intel was set", func() {
 				So(class.Classes, ShouldContainClassWithName, "Intel")
 			})
@@ -0,0 +1,467 @@
+package gosqm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CombatMode is the combat mode set by a waypoint.
+type CombatMode string
+
+const (
+	CombatModeBlue   CombatMode = "BLUE"   // Never fire
+	CombatModeGreen  CombatMode = "GREEN"  // Hold fire, keep formation
+	CombatModeWhite  CombatMode = "WHITE"  // Hold fire, engage at will
+	CombatModeYellow CombatMode = "YELLOW" // Fire at will, keep formation
+	CombatModeRed    CombatMode = "RED"    // Fire at will, engage at will
+)
+
+func (c CombatMode) Valid() bool {
+	switch c {
+	case CombatModeBlue, CombatModeGreen, CombatModeWhite, CombatModeYellow, CombatModeRed:
+		return true
+	}
+	return false
+}
+
+func (c CombatMode) String() string {
+	return string(c)
+}
+
+// ParseCombatMode returns the CombatMode of a value, the value is kept if it is unknown.
+func ParseCombatMode(s string) (CombatMode, error) {
+	v := CombatMode(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// Formation is the formation set by a waypoint.
+type Formation string
+
+const (
+	FormationNoChange   Formation = "NO CHANGE"
+	FormationColumn     Formation = "COLUMN"
+	FormationStagColumn Formation = "STAG COLUMN"
+	FormationWedge      Formation = "WEDGE"
+	FormationEchLeft    Formation = "ECH LEFT"
+	FormationEchRight   Formation = "ECH RIGHT"
+	FormationVee        Formation = "VEE"
+	FormationLine       Formation = "LINE"
+	FormationFile       Formation = "FILE"
+	FormationDiamond    Formation = "DIAMOND"
+)
+
+func (f Formation) Valid() bool {
+	switch f {
+	case FormationNoChange, FormationColumn, FormationStagColumn, FormationWedge, FormationEchLeft,
+		FormationEchRight, FormationVee, FormationLine, FormationFile, FormationDiamond:
+		return true
+	}
+	return false
+}
+
+func (f Formation) String() string {
+	return string(f)
+}
+
+// ParseFormation returns the Formation of a value, the value is kept if it is unknown.
+func ParseFormation(s string) (Formation, error) {
+	v := Formation(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// Speed is the speed mode set by a waypoint.
+type Speed string
+
+const (
+	SpeedUnchanged Speed = "UNCHANGED"
+	SpeedLimited   Speed = "LIMITED"
+	SpeedNormal    Speed = "NORMAL"
+	SpeedFull      Speed = "FULL"
+)
+
+func (s Speed) Valid() bool {
+	switch s {
+	case SpeedUnchanged, SpeedLimited, SpeedNormal, SpeedFull:
+		return true
+	}
+	return false
+}
+
+func (s Speed) String() string {
+	return string(s)
+}
+
+// ParseSpeed returns the Speed of a value, the value is kept if it is unknown.
+func ParseSpeed(s string) (Speed, error) {
+	v := Speed(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// Behaviour is the behaviour set by a waypoint, stored as combat.
+type Behaviour string
+
+const (
+	BehaviourUnchanged Behaviour = "UNCHANGED"
+	BehaviourCareless  Behaviour = "CARELESS"
+	BehaviourSafe      Behaviour = "SAFE"
+	BehaviourAware     Behaviour = "AWARE"
+	BehaviourCombat    Behaviour = "COMBAT"
+	BehaviourStealth   Behaviour = "STEALTH"
+)
+
+func (b Behaviour) Valid() bool {
+	switch b {
+	case BehaviourUnchanged, BehaviourCareless, BehaviourSafe, BehaviourAware, BehaviourCombat, BehaviourStealth:
+		return true
+	}
+	return false
+}
+
+func (b Behaviour) String() string {
+	return string(b)
+}
+
+// ParseBehaviour returns the Behaviour of a value, the value is kept if it is unknown.
+func ParseBehaviour(s string) (Behaviour, error) {
+	v := Behaviour(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// Side is the side of a unit, group or vehicle.
+type Side string
+
+const (
+	SideWest        Side = "WEST"
+	SideEast        Side = "EAST"
+	SideGuer        Side = "GUER"
+	SideCiv         Side = "CIV"
+	SideLogic       Side = "LOGIC"
+	SideEmpty       Side = "EMPTY"
+	SideAmbientLife Side = "AMBIENT LIFE"
+)
+
+func (s Side) Valid() bool {
+	switch s {
+	case SideWest, SideEast, SideGuer, SideCiv, SideLogic, SideEmpty, SideAmbientLife:
+		return true
+	}
+	return false
+}
+
+func (s Side) String() string {
+	return string(s)
+}
+
+// ParseSide returns the Side of a value, the value is kept if it is unknown.
+func ParseSide(s string) (Side, error) {
+	v := Side(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// Rank is the rank of a unit.
+type Rank string
+
+const (
+	RankPrivate    Rank = "PRIVATE"
+	RankCorporal   Rank = "CORPORAL"
+	RankSergeant   Rank = "SERGEANT"
+	RankLieutenant Rank = "LIEUTENANT"
+	RankCaptain    Rank = "CAPTAIN"
+	RankMajor      Rank = "MAJOR"
+	RankColonel    Rank = "COLONEL"
+)
+
+func (r Rank) Valid() bool {
+	switch r {
+	case RankPrivate, RankCorporal, RankSergeant, RankLieutenant, RankCaptain, RankMajor, RankColonel:
+		return true
+	}
+	return false
+}
+
+func (r Rank) String() string {
+	return string(r)
+}
+
+// ParseRank returns the Rank of a value, the value is kept if it is unknown.
+func ParseRank(s string) (Rank, error) {
+	v := Rank(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// Special is the placement of a vehicle.
+type Special string
+
+const (
+	SpecialNone       Special = "NONE"
+	SpecialForm       Special = "FORM"
+	SpecialCargo      Special = "CARGO"
+	SpecialFly        Special = "FLY"
+	SpecialCanCollide Special = "CAN_COLLIDE"
+)
+
+func (s Special) Valid() bool {
+	switch s {
+	case SpecialNone, SpecialForm, SpecialCargo, SpecialFly, SpecialCanCollide:
+		return true
+	}
+	return false
+}
+
+func (s Special) String() string {
+	return string(s)
+}
+
+// ParseSpecial returns the Special of a value, the value is kept if it is unknown.
+func ParseSpecial(s string) (Special, error) {
+	v := Special(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// Lock is the lock state of a vehicle.
+type Lock string
+
+const (
+	LockDefault      Lock = "DEFAULT"
+	LockUnlocked     Lock = "UNLOCKED"
+	LockLocked       Lock = "LOCKED"
+	LockLockedPlayer Lock = "LOCKEDPLAYER"
+)
+
+func (l Lock) Valid() bool {
+	switch l {
+	case LockDefault, LockUnlocked, LockLocked, LockLockedPlayer:
+		return true
+	}
+	return false
+}
+
+func (l Lock) String() string {
+	return string(l)
+}
+
+// ParseLock returns the Lock of a value, the value is kept if it is unknown.
+func ParseLock(s string) (Lock, error) {
+	v := Lock(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// WaypointType is the type of a waypoint.
+type WaypointType string
+
+const (
+	WaypointMove            WaypointType = "MOVE"
+	WaypointDestroy         WaypointType = "DESTROY"
+	WaypointGetIn           WaypointType = "GETIN"
+	WaypointSeekAndDestroy  WaypointType = "SAD"
+	WaypointJoin            WaypointType = "JOIN"
+	WaypointLeader          WaypointType = "LEADER"
+	WaypointGetOut          WaypointType = "GETOUT"
+	WaypointCycle           WaypointType = "CYCLE"
+	WaypointLoad            WaypointType = "LOAD"
+	WaypointUnload          WaypointType = "UNLOAD"
+	WaypointTransportUnload WaypointType = "TR UNLOAD"
+	WaypointHold            WaypointType = "HOLD"
+	WaypointSentry          WaypointType = "SENTRY"
+	WaypointGuard           WaypointType = "GUARD"
+	WaypointTalk            WaypointType = "TALK"
+	WaypointScripted        WaypointType = "SCRIPTED"
+	WaypointSupport         WaypointType = "SUPPORT"
+	WaypointGetInNearest    WaypointType = "GETIN NEAREST"
+	WaypointDismiss         WaypointType = "DISMISS"
+	WaypointAnd             WaypointType = "AND"
+	WaypointOr              WaypointType = "OR"
+)
+
+func (w WaypointType) Valid() bool {
+	switch w {
+	case WaypointMove, WaypointDestroy, WaypointGetIn, WaypointSeekAndDestroy, WaypointJoin,
+		WaypointLeader, WaypointGetOut, WaypointCycle, WaypointLoad, WaypointUnload,
+		WaypointTransportUnload, WaypointHold, WaypointSentry, WaypointGuard, WaypointTalk,
+		WaypointScripted, WaypointSupport, WaypointGetInNearest, WaypointDismiss, WaypointAnd,
+		WaypointOr:
+		return true
+	}
+	return false
+}
+
+func (w WaypointType) String() string {
+	return string(w)
+}
+
+// ParseWaypointType returns the WaypointType of a value, the value is kept if it is unknown.
+func ParseWaypointType(s string) (WaypointType, error) {
+	v := WaypointType(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// ActivationBy is the entity activating a sensor.
+type ActivationBy string
+
+const (
+	ActivationNone       ActivationBy = "NONE"
+	ActivationEast       ActivationBy = "EAST"
+	ActivationWest       ActivationBy = "WEST"
+	ActivationGuer       ActivationBy = "GUER"
+	ActivationCiv        ActivationBy = "CIV"
+	ActivationLogic      ActivationBy = "LOGIC"
+	ActivationAny        ActivationBy = "ANY"
+	ActivationAlpha      ActivationBy = "ALPHA"
+	ActivationBravo      ActivationBy = "BRAVO"
+	ActivationCharlie    ActivationBy = "CHARLIE"
+	ActivationDelta      ActivationBy = "DELTA"
+	ActivationEcho       ActivationBy = "ECHO"
+	ActivationFoxtrot    ActivationBy = "FOXTROT"
+	ActivationGolf       ActivationBy = "GOLF"
+	ActivationHotel      ActivationBy = "HOTEL"
+	ActivationIndia      ActivationBy = "INDIA"
+	ActivationJuliet     ActivationBy = "JULIET"
+	ActivationStatic     ActivationBy = "STATIC"
+	ActivationVehicle    ActivationBy = "VEHICLE"
+	ActivationGroup      ActivationBy = "GROUP"
+	ActivationLeader     ActivationBy = "LEADER"
+	ActivationMember     ActivationBy = "MEMBER"
+	ActivationWestSeized ActivationBy = "WEST SEIZED"
+	ActivationEastSeized ActivationBy = "EAST SEIZED"
+	ActivationGuerSeized ActivationBy = "GUER SEIZED"
+)
+
+func (a ActivationBy) Valid() bool {
+	switch a {
+	case ActivationNone, ActivationEast, ActivationWest, ActivationGuer, ActivationCiv,
+		ActivationLogic, ActivationAny, ActivationAlpha, ActivationBravo, ActivationCharlie,
+		ActivationDelta, ActivationEcho, ActivationFoxtrot, ActivationGolf, ActivationHotel,
+		ActivationIndia, ActivationJuliet, ActivationStatic, ActivationVehicle, ActivationGroup,
+		ActivationLeader, ActivationMember, ActivationWestSeized, ActivationEastSeized,
+		ActivationGuerSeized:
+		return true
+	}
+	return false
+}
+
+func (a ActivationBy) String() string {
+	return string(a)
+}
+
+// ParseActivationBy returns the ActivationBy of a value, the value is kept if it is unknown.
+func ParseActivationBy(s string) (ActivationBy, error) {
+	v := ActivationBy(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// MarkerShape is the shape of a marker, stored as markerType.
+type MarkerShape string
+
+const (
+	MarkerShapeIcon      MarkerShape = "ICON"
+	MarkerShapeRectangle MarkerShape = "RECTANGLE"
+	MarkerShapeEllipse   MarkerShape = "ELLIPSE"
+)
+
+func (m MarkerShape) Valid() bool {
+	switch m {
+	case MarkerShapeIcon, MarkerShapeRectangle, MarkerShapeEllipse:
+		return true
+	}
+	return false
+}
+
+func (m MarkerShape) String() string {
+	return string(m)
+}
+
+// ParseMarkerShape returns the MarkerShape of a value, the value is kept if it is unknown.
+func ParseMarkerShape(s string) (MarkerShape, error) {
+	v := MarkerShape(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// MarkerIcon is the marker class from CfgMarkers, stored as type. Addons
+// define more classes, so Valid only reports the classes of vanilla Arma 2
+// and Operation Arrowhead and unknown icons are not an error.
+type MarkerIcon string
+
+// markerIcons are the lowercase CfgMarkers classes of vanilla Arma 2 and
+// Operation Arrowhead
+var markerIcons = make(map[MarkerIcon]bool)
+
+func init() {
+	for _, icon := range []string{"Empty", "Flag", "Flag1", "Dot", "Destroy", "Start", "End",
+		"Warning", "Join", "Pickup", "Unknown", "Marker", "Arrow", "Select"} {
+		markerIcons[MarkerIcon(strings.ToLower(icon))] = true
+	}
+	for _, icon := range []string{"ambush", "arrow", "arrow2", "box", "circle", "destroy", "dot",
+		"end", "flag", "join", "marker", "objective", "pickup", "start", "triangle", "unknown",
+		"warning"} {
+		markerIcons[MarkerIcon("mil_"+icon)] = true
+		markerIcons[MarkerIcon("hd_"+icon)] = true
+	}
+	for _, side := range []string{"b_", "o_", "n_"} {
+		for _, icon := range []string{"air", "armor", "art", "hq", "inf", "maint", "mech_inf",
+			"motor_inf", "naval", "recon", "service", "support", "uav", "unknown"} {
+			markerIcons[MarkerIcon(side+icon)] = true
+		}
+	}
+}
+
+// Valid returns true for the marker classes of vanilla Arma 2 and Operation
+// Arrowhead, case insensitive.
+func (m MarkerIcon) Valid() bool {
+	return markerIcons[MarkerIcon(strings.ToLower(string(m)))]
+}
+
+func (m MarkerIcon) String() string {
+	return string(m)
+}
+
+// ParseMarkerIcon returns the MarkerIcon of a value, the value is kept if it is unknown.
+func ParseMarkerIcon(s string) (MarkerIcon, error) {
+	v := MarkerIcon(s)
+	if !v.Valid() {
+		return v, unknownValue(s)
+	}
+	return v, nil
+}
+
+// Icon returns the marker class of the marker.
+func (m *Marker) Icon() MarkerIcon {
+	return MarkerIcon(m.Type)
+}
+
+// unknownValue is the error of an InvalidValueError for unknown enum values
+func unknownValue(value string) error {
+	return fmt.Errorf("Unknown value %q", value)
+}
@@ -0,0 +1,68 @@
+package gosqm
+
+import (
+	"github.com/blang/gosqm/sqm"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseEnums(t *testing.T) {
+	Convey("Known values are parsed", t, func() {
+		side, err := ParseSide("WEST")
+		So(err, ShouldBeNil)
+		So(side, ShouldEqual, SideWest)
+		rank, err := ParseRank("MAJOR")
+		So(err, ShouldBeNil)
+		So(rank, ShouldEqual, RankMajor)
+		wpType, err := ParseWaypointType("TR UNLOAD")
+		So(err, ShouldBeNil)
+		So(wpType, ShouldEqual, WaypointTransportUnload)
+		icon, err := ParseMarkerIcon("mil_Dot")
+		So(err, ShouldBeNil)
+		So(icon, ShouldEqual, MarkerIcon("mil_Dot"))
+		So((&Marker{Type: "b_service"}).Icon().Valid(), ShouldBeTrue)
+	})
+	Convey("Unknown values are kept but reported", t, func() {
+		side, err := ParseSide("WESt")
+		So(err, ShouldNotBeNil)
+		So(side.String(), ShouldEqual, "WESt")
+		So(side.Valid(), ShouldBeFalse)
+		icon, err := ParseMarkerIcon("my_marker")
+		So(err, ShouldNotBeNil)
+		So(icon.String(), ShouldEqual, "my_marker")
+	})
+	Convey("Given the test mission", t, func() {
+		b, err := ioutil.ReadFile("testdata/mission.sqm")
+		So(err, ShouldBeNil)
+		class, err := sqm.MakeParser(string(b)).Run()
+		So(err, ShouldBeNil)
+		p := NewParser()
+		_, err = p.Parse(class)
+		So(err, ShouldBeNil)
+		Convey("All values are known", func() {
+			for _, w := range p.Warnings() {
+				So(w, ShouldNotHaveSameTypeAs, &InvalidValueError{})
+			}
+		})
+	})
+	Convey("Given a vehicle with a misspelled side", t, func() {
+		p := NewParser()
+		veh := &Vehicle{}
+		p.parseVehicle(&sqm.Class{
+			Name: "Item0",
+			Props: []*sqm.Property{
+				&sqm.Property{"side", sqm.TString, "WESt"},
+				&sqm.Property{"vehicle", sqm.TString, "USMC_Soldier"},
+			},
+			Arrprops: []*sqm.ArrayProperty{
+				&sqm.ArrayProperty{"position", sqm.TNumber, []string{"1", "2", "3"}},
+			},
+		}, veh)
+		Convey("The value is kept and reported", func() {
+			So(veh.Side, ShouldEqual, Side("WESt"))
+			So(p.Warnings(), ShouldHaveLength, 1)
+			So(p.Warnings()[0], ShouldHaveSameTypeAs, &InvalidValueError{})
+		})
+	})
+}
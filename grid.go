@@ -0,0 +1,105 @@
+package gosqm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Grid converts positions to the map grid references of a terrain.
+// Origin is the position of grid reference 000000. Arma 2 terrains number
+// the grid from the north west corner with northings increasing to the
+// south, set Down for them. Positions outside of WorldSize are rejected if
+// it is set.
+type Grid struct {
+	Origin    Vec2
+	Down      bool
+	WorldSize float64
+}
+
+// Grids are the grids of common terrains by lowercase world name.
+var Grids = map[string]Grid{
+	"altis":     Grid{WorldSize: 30720},
+	"stratis":   Grid{WorldSize: 8192},
+	"tanoa":     Grid{WorldSize: 15360},
+	"malden":    Grid{WorldSize: 12800},
+	"chernarus": Grid{Origin: Vec2{0, 15360}, Down: true, WorldSize: 15360},
+	"takistan":  Grid{Origin: Vec2{0, 12800}, Down: true, WorldSize: 12800},
+	"utes":      Grid{Origin: Vec2{0, 5120}, Down: true, WorldSize: 5120},
+}
+
+// GridError is an invalid grid reference or a position outside of the grid.
+type GridError struct {
+	Msg string
+}
+
+func (e *GridError) Error() string {
+	return e.Msg
+}
+
+// gridPrecision returns the size of a grid square in metres for the number
+// of digits of a reference
+func gridPrecision(digits int) (float64, error) {
+	switch digits {
+	case 6:
+		return 100, nil
+	case 8:
+		return 10, nil
+	case 10:
+		return 1, nil
+	}
+	return 0, &GridError{fmt.Sprintf("Grid references have 6, 8 or 10 digits, not %d", digits)}
+}
+
+// offset returns the easting and northing of a position in metres
+func (g Grid) offset(p Vec3) Vec2 {
+	north := p.Y - g.Origin.Y
+	if g.Down {
+		north = -north
+	}
+	return Vec2{p.X - g.Origin.X, north}
+}
+
+// Reference returns the grid reference of the square containing p with 6,
+// 8 or 10 digits, e.g. "045123" for easting 045 and northing 123.
+func (g Grid) Reference(p Vec3, digits int) (string, error) {
+	precision, err := gridPrecision(digits)
+	if err != nil {
+		return "", err
+	}
+	if g.WorldSize > 0 && (p.X < 0 || p.Y < 0 || p.X > g.WorldSize || p.Y > g.WorldSize) {
+		return "", &GridError{fmt.Sprintf("Position %s is outside of the world", p)}
+	}
+	o := g.offset(p)
+	e := int(math.Floor(o.X / precision))
+	n := int(math.Floor(o.Y / precision))
+	max := int(math.Pow10(digits / 2))
+	if e < 0 || n < 0 || e >= max || n >= max {
+		return "", &GridError{fmt.Sprintf("Position %s is outside of the grid", p)}
+	}
+	return fmt.Sprintf("%0*d%0*d", digits/2, e, digits/2, n), nil
+}
+
+// Position returns the center of the square of a grid reference. Spaces in
+// the reference are ignored, "045 123" and "045123" are the same.
+func (g Grid) Position(ref string) (Vec3, error) {
+	digits := strings.Replace(ref, " ", "", -1)
+	precision, err := gridPrecision(len(digits))
+	if err != nil {
+		return Vec3{}, &GridError{fmt.Sprintf("Invalid grid reference %q", ref)}
+	}
+	var values [2]float64
+	for i, s := range []string{digits[:len(digits)/2], digits[len(digits)/2:]} {
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return Vec3{}, &GridError{fmt.Sprintf("Invalid grid reference %q", ref)}
+		}
+		values[i] = (float64(n) + 0.5) * precision
+	}
+	north := values[1]
+	if g.Down {
+		north = -north
+	}
+	return Vec3{X: g.Origin.X + values[0], Y: g.Origin.Y + north}, nil
+}
@@ -0,0 +1,53 @@
+package gosqm
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestGrid(t *testing.T) {
+	Convey("Given a grid numbered from the south west", t, func() {
+		g := Grids["altis"]
+		p := Vec3{4567.8, 12345.6, 10}
+
+		Convey("References have 6, 8 or 10 digits", func() {
+			ref, err := g.Reference(p, 6)
+			So(err, ShouldBeNil)
+			So(ref, ShouldEqual, "045123")
+			ref, _ = g.Reference(p, 8)
+			So(ref, ShouldEqual, "04561234")
+			ref, _ = g.Reference(p, 10)
+			So(ref, ShouldEqual, "0456712345")
+			_, err = g.Reference(p, 4)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Positions outside the world are rejected", func() {
+			_, err := g.Reference(Vec3{-1, 0, 0}, 6)
+			So(err, ShouldNotBeNil)
+			_, err = g.Reference(Vec3{40000, 0, 0}, 6)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("References are converted to the center of the square", func() {
+			pos, err := g.Position("045 123")
+			So(err, ShouldBeNil)
+			So(pos, ShouldResemble, Vec3{4550, 12350, 0})
+			pos, _ = g.Position("0456712345")
+			So(pos, ShouldResemble, Vec3{4567.5, 12345.5, 0})
+			_, err = g.Position("045 12")
+			So(err, ShouldNotBeNil)
+			_, err = g.Position("04a123")
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("Given a grid numbered from the north west", t, func() {
+		g := Grids["chernarus"]
+
+		Convey("Northings increase to the south", func() {
+			ref, err := g.Reference(Vec3{4567, 15360 - 12345.6, 0}, 8)
+			So(err, ShouldBeNil)
+			So(ref, ShouldEqual, "04561234")
+			pos, _ := g.Position(ref)
+			So(pos, ShouldResemble, Vec3{4565, 15360 - 12345, 0})
+		})
+	})
+}
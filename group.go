@@ -0,0 +1,143 @@
+package gosqm
+
+// Leader returns the leader of the group, the first unit if no unit is
+// marked as leader or nil if the group is empty.
+func (g *Group) Leader() *Vehicle {
+	for _, v := range g.Units {
+		if v.IsLeader {
+			return v
+		}
+	}
+	if len(g.Units) > 0 {
+		return g.Units[0]
+	}
+	return nil
+}
+
+// SetLeader makes v the only leader of the group. v is added to the group
+// if it is not a member yet.
+func (g *Group) SetLeader(v *Vehicle) {
+	if !g.HasUnit(v) {
+		g.AddUnit(v)
+	}
+	for _, u := range g.Units {
+		u.IsLeader = u == v
+	}
+}
+
+// HasUnit reports whether v is a member of the group.
+func (g *Group) HasUnit(v *Vehicle) bool {
+	for _, u := range g.Units {
+		if u == v {
+			return true
+		}
+	}
+	return false
+}
+
+// AddUnit adds v to the group and sets its side to the side of the group.
+// v becomes the leader if the group was empty.
+func (g *Group) AddUnit(v *Vehicle) {
+	if g.HasUnit(v) {
+		return
+	}
+	if g.Side != "" {
+		v.Side = g.Side
+	}
+	v.IsLeader = len(g.Units) == 0
+	g.Units = append(g.Units, v)
+}
+
+// RemoveUnit removes v from the group. If v was the leader the next unit
+// takes over. It returns false if v was not a member.
+func (g *Group) RemoveUnit(v *Vehicle) bool {
+	for i, u := range g.Units {
+		if u != v {
+			continue
+		}
+		g.Units = append(g.Units[:i:i], g.Units[i+1:]...)
+		if v.IsLeader {
+			v.IsLeader = false
+			if len(g.Units) > 0 {
+				g.SetLeader(g.Units[0])
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// MoveUnitTo moves v from the group to other.
+// It returns false if v is not a member of the group.
+func (g *Group) MoveUnitTo(v *Vehicle, other *Group) bool {
+	if !g.RemoveUnit(v) {
+		return false
+	}
+	other.AddUnit(v)
+	return true
+}
+
+// Split moves units into a new group of the same side and returns it.
+// The new group follows copies of the waypoints, including their effects
+// and synchronizations. The first moved unit leads the new group unless the
+// leader of the group is among them. Units which are not members are ignored.
+func (g *Group) Split(units ...*Vehicle) *Group {
+	n := &Group{Side: g.Side}
+	oldLeader := g.Leader()
+	var leader *Vehicle
+	for _, v := range units {
+		if g.MoveUnitTo(v, n) && v == oldLeader {
+			leader = v
+		}
+	}
+	if leader != nil {
+		n.SetLeader(leader)
+	}
+	for _, w := range g.Waypoints {
+		n.Waypoints = append(n.Waypoints, copyWaypoint(w))
+	}
+	return n
+}
+
+// Merge moves all units of other into the group, they follow its leader
+// and waypoints. The waypoints of other are removed together with their
+// synchronizations, the empty group should be removed from the mission.
+func (g *Group) Merge(other *Group) {
+	if other == g {
+		return
+	}
+	for _, v := range other.Units {
+		if g.Side != "" {
+			v.Side = g.Side
+		}
+		v.IsLeader = false
+		g.Units = append(g.Units, v)
+	}
+	if leader := g.Leader(); leader != nil {
+		g.SetLeader(leader)
+	}
+	other.Units = nil
+	for _, w := range other.Waypoints {
+		w.Unsync()
+	}
+	other.Waypoints = nil
+}
+
+// copyWaypoint copies a waypoint, the copy is synchronized with the same
+// waypoints and sensors
+func copyWaypoint(w *Waypoint) *Waypoint {
+	c := *w
+	if w.Effects != nil {
+		effects := *w.Effects
+		c.Effects = &effects
+	}
+	c.SyncWaypoints = nil
+	c.SyncSensors = nil
+	for _, o := range w.SyncWaypoints {
+		c.SyncWaypoint(o)
+	}
+	for _, s := range w.SyncSensors {
+		c.SyncSensor(s)
+	}
+	return &c
+}
@@ -0,0 +1,129 @@
+package gosqm
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestGroupLeader(t *testing.T) {
+	Convey("Given a group with three units", t, func() {
+		a := &Vehicle{Name: "a", IsLeader: true}
+		b := &Vehicle{Name: "b"}
+		c := &Vehicle{Name: "c"}
+		g := &Group{Side: "WEST", Units: []*Vehicle{a, b, c}}
+
+		Convey("Leader is the flagged unit", func() {
+			So(g.Leader(), ShouldEqual, a)
+		})
+		Convey("When setting a new leader", func() {
+			g.SetLeader(c)
+			Convey("Only the new leader is flagged", func() {
+				So(g.Leader(), ShouldEqual, c)
+				So(a.IsLeader, ShouldBeFalse)
+				So(b.IsLeader, ShouldBeFalse)
+				So(c.IsLeader, ShouldBeTrue)
+			})
+		})
+		Convey("When removing the leader", func() {
+			So(g.RemoveUnit(a), ShouldBeTrue)
+			Convey("The next unit takes over", func() {
+				So(g.Units, ShouldResemble, []*Vehicle{b, c})
+				So(g.Leader(), ShouldEqual, b)
+				So(b.IsLeader, ShouldBeTrue)
+				So(a.IsLeader, ShouldBeFalse)
+			})
+			Convey("Removing it again fails", func() {
+				So(g.RemoveUnit(a), ShouldBeFalse)
+			})
+		})
+		Convey("When adding a unit", func() {
+			d := &Vehicle{Name: "d", Side: "EAST", IsLeader: true}
+			g.AddUnit(d)
+			Convey("It joins the side and is no leader", func() {
+				So(g.Units, ShouldHaveLength, 4)
+				So(d.Side, ShouldEqual, SideWest)
+				So(d.IsLeader, ShouldBeFalse)
+				So(g.Leader(), ShouldEqual, a)
+			})
+		})
+		Convey("When moving a unit to another group", func() {
+			other := &Group{Side: "EAST"}
+			So(g.MoveUnitTo(b, other), ShouldBeTrue)
+			Convey("It leads the empty group", func() {
+				So(g.Units, ShouldResemble, []*Vehicle{a, c})
+				So(other.Leader(), ShouldEqual, b)
+				So(b.IsLeader, ShouldBeTrue)
+				So(b.Side, ShouldEqual, SideEast)
+			})
+		})
+	})
+}
+
+func TestGroupSplitMerge(t *testing.T) {
+	Convey("Given a group with a synchronized waypoint", t, func() {
+		a := &Vehicle{Name: "a", IsLeader: true}
+		b := &Vehicle{Name: "b"}
+		c := &Vehicle{Name: "c"}
+		wp := &Waypoint{Type: "MOVE", Effects: &Effects{Sound: "sound"}}
+		s := &Sensor{}
+		wp.SyncSensor(s)
+		g := &Group{Side: "WEST", Units: []*Vehicle{a, b, c}, Waypoints: []*Waypoint{wp}}
+
+		Convey("When splitting units off", func() {
+			n := g.Split(b, c)
+			Convey("Units are moved", func() {
+				So(g.Units, ShouldResemble, []*Vehicle{a})
+				So(n.Units, ShouldResemble, []*Vehicle{b, c})
+				So(n.Side, ShouldEqual, SideWest)
+				So(n.Leader(), ShouldEqual, b)
+				So(b.IsLeader, ShouldBeTrue)
+				So(a.IsLeader, ShouldBeTrue)
+			})
+			Convey("Waypoints are copied with their synchronizations", func() {
+				So(n.Waypoints, ShouldHaveLength, 1)
+				copied := n.Waypoints[0]
+				So(copied == wp, ShouldBeFalse)
+				So(copied.Type, ShouldEqual, WaypointMove)
+				So(copied.Effects == wp.Effects, ShouldBeFalse)
+				So(copied.Effects.Sound, ShouldEqual, "sound")
+				So(copied.SyncSensors, ShouldResemble, []*Sensor{s})
+				So(s.SyncWaypoints, ShouldResemble, []*Waypoint{wp, copied})
+			})
+			Convey("When merging back", func() {
+				g.Merge(n)
+				Convey("All units follow the old leader", func() {
+					So(g.Units, ShouldResemble, []*Vehicle{a, b, c})
+					So(g.Leader(), ShouldEqual, a)
+					So(b.IsLeader, ShouldBeFalse)
+					So(n.Units, ShouldBeEmpty)
+				})
+				Convey("The copied waypoints are unsynchronized", func() {
+					So(n.Waypoints, ShouldBeEmpty)
+					So(s.SyncWaypoints, ShouldResemble, []*Waypoint{wp})
+				})
+			})
+		})
+
+		Convey("When splitting the leader off", func() {
+			n := g.Split(a, b)
+			Convey("The leader keeps leading the new group", func() {
+				So(n.Units, ShouldResemble, []*Vehicle{a, b})
+				So(n.Leader(), ShouldEqual, a)
+				So(a.IsLeader, ShouldBeTrue)
+				So(b.IsLeader, ShouldBeFalse)
+			})
+			Convey("The remaining unit leads the old group", func() {
+				So(g.Units, ShouldResemble, []*Vehicle{c})
+				So(g.Leader(), ShouldEqual, c)
+				So(c.IsLeader, ShouldBeTrue)
+			})
+		})
+
+		Convey("When splitting the leader off after another unit", func() {
+			n := g.Split(c, a)
+			So(n.Leader(), ShouldEqual, a)
+			So(c.IsLeader, ShouldBeFalse)
+			So(g.Leader(), ShouldEqual, b)
+		})
+	})
+}
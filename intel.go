@@ -0,0 +1,102 @@
+package gosqm
+
+import (
+	"math"
+	"time"
+)
+
+// RangeError is a value outside of its valid range.
+type RangeError struct {
+	Name     string
+	Value    float64
+	Min, Max float64
+}
+
+func (e *RangeError) Error() string {
+	return "Value " + FormatNumber(e.Value) + " of " + e.Name + " out of range [" + FormatNumber(e.Min) + ", " + FormatNumber(e.Max) + "]"
+}
+
+func checkRange(name string, v, min, max float64) error {
+	if v < min || v > max {
+		return &RangeError{name, v, min, max}
+	}
+	return nil
+}
+
+// Date returns the mission date. Month and Day default to 1 if they are
+// not set.
+func (i *Intel) Date() time.Time {
+	month, day := i.Month, i.Day
+	if month == 0 {
+		month = 1
+	}
+	if day == 0 {
+		day = 1
+	}
+	return time.Date(i.Year, time.Month(month), day, i.Hour, i.Minute, 0, 0, time.UTC)
+}
+
+// SetDate sets the mission date, seconds are dropped.
+func (i *Intel) SetDate(t time.Time) {
+	i.Year = t.Year()
+	i.Month = int(t.Month())
+	i.Day = t.Day()
+	i.Hour = t.Hour()
+	i.Minute = t.Minute()
+}
+
+// Validate returns a RangeError for every date or weather value outside
+// of its valid range, named like the property. Unset date values are not
+// checked.
+func (i *Intel) Validate() []error {
+	var errs []error
+	for _, check := range i.checks() {
+		if !check.set {
+			continue
+		}
+		if err := checkRange(check.name, check.value, check.min, check.max); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+type intelCheck struct {
+	name            string
+	value, min, max float64
+	set             bool
+}
+
+// checks lists the range of every intel value
+func (i *Intel) checks() []intelCheck {
+	days := 31
+	if i.Month >= 1 && i.Month <= 12 {
+		days = time.Date(i.Year, time.Month(i.Month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	}
+	w := i.Weather
+	optional := func(f *float64) (float64, bool) {
+		if f == nil {
+			return 0, false
+		}
+		return *f, true
+	}
+	startWeather, startSet := optional(w.StartWeather)
+	forecastWeather, forecastSet := optional(w.ForecastWeather)
+	return []intelCheck{
+		{"year", float64(i.Year), 1, 9999, i.Year != 0},
+		{"month", float64(i.Month), 1, 12, i.Month != 0},
+		{"day", float64(i.Day), 1, float64(days), i.Day != 0},
+		{"hour", float64(i.Hour), 0, 23, true},
+		{"minute", float64(i.Minute), 0, 59, true},
+		{"startWeather", startWeather, 0, 1, startSet},
+		{"forecastWeather", forecastWeather, 0, 1, forecastSet},
+		{"startFog", w.StartFog, 0, 1, true},
+		{"forecastFog", w.ForecastFog, 0, 1, true},
+		{"startRain", w.StartRain, 0, 1, true},
+		{"startWind", w.StartWind, 0, 1, true},
+		{"startGust", w.StartGust, 0, 1, true},
+		{"startWaves", w.StartWaves, 0, 1, true},
+		{"startLightnings", w.StartLightnings, 0, 1, true},
+		{"timeOfChanges", w.TimeOfChanges, 0, math.MaxFloat64, true},
+	}
+}
@@ -0,0 +1,48 @@
+package gosqm
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestIntelDate(t *testing.T) {
+	Convey("Given an intel", t, func() {
+		i := &Intel{Year: 2009, Day: 28, Hour: 6}
+		Convey("Unset month defaults to January", func() {
+			So(i.Date(), ShouldResemble, time.Date(2009, time.January, 28, 6, 0, 0, 0, time.UTC))
+		})
+		Convey("When setting a date", func() {
+			i.SetDate(time.Date(2035, time.July, 14, 4, 45, 30, 0, time.UTC))
+			Convey("All fields are set", func() {
+				So(i.Year, ShouldEqual, 2035)
+				So(i.Month, ShouldEqual, 7)
+				So(i.Day, ShouldEqual, 14)
+				So(i.Hour, ShouldEqual, 4)
+				So(i.Minute, ShouldEqual, 45)
+				So(i.Date(), ShouldResemble, time.Date(2035, time.July, 14, 4, 45, 0, 0, time.UTC))
+			})
+		})
+	})
+}
+
+func TestIntelValidate(t *testing.T) {
+	Convey("Given a valid intel", t, func() {
+		i := &Intel{Year: 2035, Month: 2, Day: 28, Hour: 23, Minute: 59, Weather: Weather{StartWeather: Number(1), TimeOfChanges: 3600}}
+		So(i.Validate(), ShouldBeEmpty)
+		Convey("Values out of range are reported", func() {
+			i.Day = 29
+			i.Hour = 24
+			i.Weather.StartFog = -0.1
+			errs := i.Validate()
+			So(errs, ShouldHaveLength, 3)
+			So(errs[0].(*RangeError).Name, ShouldEqual, "day")
+			So(errs[1].(*RangeError).Name, ShouldEqual, "hour")
+			So(errs[2].(*RangeError).Name, ShouldEqual, "startFog")
+			So(errs[0].Error(), ShouldEqual, "Value 29 of day out of range [1, 28]")
+		})
+		Convey("Unset dates are valid", func() {
+			So((&Intel{}).Validate(), ShouldBeEmpty)
+		})
+	})
+}
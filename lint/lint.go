@@ -0,0 +1,183 @@
+// Package lint checks decoded mission files with a set of rules.
+//
+// Rules implement the Rule interface, NewRule turns a function into one:
+//
+//	noInit := lint.NewRule("no-init", func(s *lint.Stage, r *lint.Report) {
+//		for _, u := range s.Units() {
+//			if u.Vehicle.Init != "" {
+//				r.Add(lint.Warning, u.Path, "init line of %s", u.Vehicle.Name)
+//			}
+//		}
+//	})
+//	diags := lint.New(append(lint.DefaultRules(), noInit)...).Lint(missionFile)
+package lint
+
+import (
+	"fmt"
+	"github.com/blang/gosqm"
+	"github.com/blang/gosqm/sqm"
+	"sort"
+)
+
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return "unknown"
+}
+
+// ParseSeverity parses the name of a severity.
+func ParseSeverity(s string) (Severity, error) {
+	for _, sev := range []Severity{Info, Warning, Error} {
+		if sev.String() == s {
+			return sev, nil
+		}
+	}
+	return Info, fmt.Errorf("Unknown severity %q", s)
+}
+
+// Diagnostic is a problem found by a rule. Path is the slash separated
+// location of the entity like it is written to the mission file, e.g.
+// Mission/Groups/Item0/Vehicles/Item1.
+type Diagnostic struct {
+	Rule     string
+	Severity Severity
+	Path     string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return d.Path + ": " + d.Severity.String() + ": " + d.Message + " [" + d.Rule + "]"
+}
+
+// Rule checks a single stage of a mission file.
+type Rule interface {
+	Name() string
+	Check(s *Stage, r *Report)
+}
+
+type funcRule struct {
+	name string
+	fn   func(s *Stage, r *Report)
+}
+
+func (f *funcRule) Name() string {
+	return f.name
+}
+
+func (f *funcRule) Check(s *Stage, r *Report) {
+	f.fn(s, r)
+}
+
+// NewRule returns a rule named name which runs fn.
+func NewRule(name string, fn func(s *Stage, r *Report)) Rule {
+	return &funcRule{name, fn}
+}
+
+// Report collects the diagnostics of a rule.
+type Report struct {
+	rule  string
+	diags []Diagnostic
+}
+
+// Add reports a problem at path.
+func (r *Report) Add(sev Severity, path string, format string, args ...interface{}) {
+	r.diags = append(r.diags, Diagnostic{
+		Rule:     r.rule,
+		Severity: sev,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Linter runs rules over mission files.
+type Linter struct {
+	Rules []Rule
+}
+
+// New returns a linter running rules.
+func New(rules ...Rule) *Linter {
+	return &Linter{Rules: rules}
+}
+
+// Lint runs all rules over every stage of mf and returns the diagnostics
+// sorted by path.
+func (l *Linter) Lint(mf *gosqm.MissionFile) []Diagnostic {
+	var diags []Diagnostic
+	for _, s := range Stages(mf) {
+		for _, rule := range l.Rules {
+			r := &Report{rule: rule.Name()}
+			rule.Check(s, r)
+			diags = append(diags, r.diags...)
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Path < diags[j].Path
+	})
+	return diags
+}
+
+// Max returns the highest severity of diags, -1 if there are none.
+func Max(diags []Diagnostic) Severity {
+	max := Severity(-1)
+	for _, d := range diags {
+		if d.Severity > max {
+			max = d.Severity
+		}
+	}
+	return max
+}
+
+// FromWarnings converts the warnings of a gosqm.Parser into diagnostics.
+// Invalid values, missing entries and dangling references are errors,
+// unknown properties and classes are reported as info.
+func FromWarnings(warnings []error) []Diagnostic {
+	var diags []Diagnostic
+	for _, w := range warnings {
+		d := Diagnostic{Rule: "decode", Severity: Info, Message: w.Error()}
+		switch e := w.(type) {
+		case *gosqm.InvalidValueError:
+			d.Rule = "invalid-value"
+			d.Severity = Error
+			d.Path = classPath(e.ParentClass)
+		case *gosqm.DanglingReferenceError:
+			d.Rule = "dangling-reference"
+			d.Severity = Error
+			d.Path = classPath(e.ParentClass)
+		case *gosqm.MissingClassError:
+			d.Rule = "missing"
+			d.Severity = Error
+			d.Path = classPath(e.ParentClass)
+		case *gosqm.MissingPropertyError:
+			d.Rule = "missing"
+			d.Severity = Error
+			d.Path = classPath(e.ParentClass)
+		case *gosqm.UnkownPropertyError:
+			d.Path = classPath(e.ParentClass)
+		case *gosqm.UnkownClassError:
+			d.Path = classPath(e.ParentClass)
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+func classPath(c *sqm.Class) string {
+	if c == nil {
+		return ""
+	}
+	return c.Path()
+}
@@ -0,0 +1,106 @@
+package lint
+
+import (
+	"github.com/blang/gosqm"
+	. "github.com/smartystreets/goconvey/convey"
+	"os"
+	"testing"
+)
+
+func rules(diags []Diagnostic) []string {
+	var names []string
+	for _, d := range diags {
+		names = append(names, d.Rule)
+	}
+	return names
+}
+
+func TestLint(t *testing.T) {
+	Convey("Given a mission with problems", t, func() {
+		leader := &gosqm.Vehicle{Name: "alpha", Classname: "USMC_Soldier", IsLeader: true, Player: "PLAY CDG", Description: "Squad Leader"}
+		second := &gosqm.Vehicle{Name: "alpha", Classname: "USMC_Soldier", IsLeader: true, Player: "PLAY CDG"}
+		deleted := &gosqm.Waypoint{}
+		w := &gosqm.Waypoint{Type: gosqm.WaypointMove}
+		w.SyncWaypoint(deleted)
+		m := &gosqm.Mission{
+			Addons: []string{"ca_modules"},
+			Groups: []*gosqm.Group{
+				&gosqm.Group{Side: gosqm.SideWest, Units: []*gosqm.Vehicle{leader, second}, Waypoints: []*gosqm.Waypoint{w}},
+				&gosqm.Group{Side: "WESt", Units: []*gosqm.Vehicle{&gosqm.Vehicle{Classname: "USMC_Soldier"}}},
+			},
+			Markers: []*gosqm.Marker{
+				&gosqm.Marker{Name: "area", MarkerType: gosqm.MarkerShapeRectangle, Size: gosqm.Vec2{-10, 10}},
+				&gosqm.Marker{Name: "obj", Type: "mil_Objective"},
+				&gosqm.Marker{Name: "custom", Type: "my_marker"},
+			},
+			Sensors: []*gosqm.Sensor{
+				&gosqm.Sensor{Name: "trigger", Vehicle: &gosqm.Vehicle{Name: "gone"}},
+			},
+		}
+		mf := &gosqm.MissionFile{Mission: m}
+
+		Convey("The default rules find them", func() {
+			diags := New(DefaultRules()...).Lint(mf)
+			So(diags, ShouldResemble, []Diagnostic{
+				{"group-leader", Error, "Mission/Groups/Item0", "group has 2 leaders"},
+				{"duplicate-name", Error, "Mission/Groups/Item0/Vehicles/Item1", `name "alpha" is already used by Mission/Groups/Item0/Vehicles/Item0`},
+				{"playable-description", Warning, "Mission/Groups/Item0/Vehicles/Item1", "playable unit USMC_Soldier has no description"},
+				{"dangling-reference", Error, "Mission/Groups/Item0/Waypoints/Item0", "synchronized waypoint is not part of the stage"},
+				{"group-leader", Error, "Mission/Groups/Item1", "group has no leader"},
+				{"invalid-value", Error, "Mission/Groups/Item1", `invalid side "WESt"`},
+				{"marker-size", Error, "Mission/Markers/Item0", `marker "area" has invalid size -10,10`},
+				{"marker-icon", Info, "Mission/Markers/Item2", `marker "custom" has unknown type "my_marker"`},
+				{"dangling-reference", Error, "Mission/Sensors/Item0", `grouped vehicle "gone" is not part of the stage`},
+			})
+			So(Max(diags), ShouldEqual, Error)
+		})
+		Convey("Missing addons are found with a classname mapping", func() {
+			diags := New(MissingAddons(gosqm.AddonDB{"usmc_soldier": "CA_Characters2"})).Lint(mf)
+			So(diags, ShouldHaveLength, 3)
+			So(diags[0].Message, ShouldEqual, "addon CA_Characters2 of USMC_Soldier is missing from addons")
+			m.Addons = append(m.Addons, "ca_characters2")
+			So(New(MissingAddons(gosqm.AddonDB{"usmc_soldier": "CA_Characters2"})).Lint(mf), ShouldBeEmpty)
+		})
+		Convey("Custom rules run on every stage", func() {
+			mf.Intro = &gosqm.Mission{}
+			var stages []string
+			custom := NewRule("custom", func(s *Stage, r *Report) {
+				stages = append(stages, s.Name)
+				r.Add(Info, s.Name, "checked")
+			})
+			diags := New(custom).Lint(mf)
+			So(stages, ShouldResemble, []string{"Mission", "Intro"})
+			So(rules(diags), ShouldResemble, []string{"custom", "custom"})
+			So(diags[0].String(), ShouldEqual, "Intro: info: checked [custom]")
+		})
+	})
+}
+
+func TestLintTestdata(t *testing.T) {
+	Convey("Given the test mission", t, func() {
+		f, err := os.Open("../testdata/mission.sqm")
+		So(err, ShouldBeNil)
+		defer f.Close()
+		mf, err := gosqm.NewDecoder(f).Decode()
+		So(err, ShouldBeNil)
+
+		Convey("The default rules report no errors", func() {
+			So(Max(New(DefaultRules()...).Lint(mf)), ShouldBeLessThan, Error)
+		})
+	})
+}
+
+func TestFromWarnings(t *testing.T) {
+	Convey("Given parser warnings", t, func() {
+		warnings := []error{
+			&gosqm.DanglingReferenceError{Context: gosqm.ContextSensor, Target: gosqm.ContextVehicle, Ref: "7"},
+			&gosqm.UnkownClassError{Context: gosqm.ContextMission},
+		}
+		Convey("They are converted to diagnostics", func() {
+			diags := FromWarnings(warnings)
+			So(rules(diags), ShouldResemble, []string{"dangling-reference", "decode"})
+			So(diags[0].Severity, ShouldEqual, Error)
+			So(diags[1].Severity, ShouldEqual, Info)
+		})
+	})
+}
@@ -0,0 +1,220 @@
+package lint
+
+import (
+	"github.com/blang/gosqm"
+	"math"
+	"strings"
+)
+
+// DefaultRules returns the rules which need no configuration.
+func DefaultRules() []Rule {
+	return []Rule{
+		GroupLeader,
+		DuplicateName,
+		PlayableDescription,
+		DanglingReference,
+		MarkerSize,
+		MarkerIcon,
+		InvalidValue,
+	}
+}
+
+// GroupLeader reports groups without a leader or with more than one.
+var GroupLeader = NewRule("group-leader", func(s *Stage, r *Report) {
+	for i, g := range s.Mission.Groups {
+		if len(g.Units) == 0 {
+			continue
+		}
+		leaders := 0
+		for _, v := range g.Units {
+			if v.IsLeader {
+				leaders++
+			}
+		}
+		switch {
+		case leaders == 0:
+			r.Add(Error, s.GroupPath(i), "group has no leader")
+		case leaders > 1:
+			r.Add(Error, s.GroupPath(i), "group has %d leaders", leaders)
+		}
+	}
+})
+
+// DuplicateName reports vehicles sharing a name with another vehicle of the
+// stage.
+var DuplicateName = NewRule("duplicate-name", func(s *Stage, r *Report) {
+	seen := make(map[string]string)
+	for _, u := range s.Units() {
+		name := u.Vehicle.Name
+		if name == "" {
+			continue
+		}
+		if path, found := seen[name]; found {
+			r.Add(Error, u.Path, "name %q is already used by %s", name, path)
+			continue
+		}
+		seen[name] = u.Path
+	}
+})
+
+// PlayableDescription reports playable units without a description, which
+// appear without a name in the slot list.
+var PlayableDescription = NewRule("playable-description", func(s *Stage, r *Report) {
+	for _, u := range s.Units() {
+		if u.Vehicle.Player != "" && u.Vehicle.Description == "" {
+			r.Add(Warning, u.Path, "playable unit %s has no description", u.Vehicle.Classname)
+		}
+	}
+})
+
+// DanglingReference reports synchronizations, markers and vehicles
+// referenced by an entity of the stage which are not part of it.
+// The Encoder drops these references.
+var DanglingReference = NewRule("dangling-reference", func(s *Stage, r *Report) {
+	m := s.Mission
+	vehicles := make(map[*gosqm.Vehicle]bool)
+	for _, u := range s.Units() {
+		vehicles[u.Vehicle] = true
+	}
+	markers := make(map[*gosqm.Marker]bool)
+	for _, marker := range m.Markers {
+		markers[marker] = true
+	}
+	waypoints := make(map[*gosqm.Waypoint]bool)
+	for _, g := range m.Groups {
+		for _, w := range g.Waypoints {
+			waypoints[w] = true
+		}
+	}
+	sensors := make(map[*gosqm.Sensor]bool)
+	for _, sensor := range m.Sensors {
+		sensors[sensor] = true
+	}
+
+	for _, u := range s.Units() {
+		for _, marker := range u.Vehicle.Markers {
+			if !markers[marker] {
+				r.Add(Error, u.Path, "marker %q is not part of the stage", marker.Name)
+			}
+		}
+	}
+	for i, g := range m.Groups {
+		for j, w := range g.Waypoints {
+			path := s.WaypointPath(i, j)
+			if w.Vehicle != nil && !vehicles[w.Vehicle] {
+				r.Add(Error, path, "attached vehicle %q is not part of the stage", w.Vehicle.Name)
+			}
+			for _, o := range w.SyncWaypoints {
+				if !waypoints[o] {
+					r.Add(Error, path, "synchronized waypoint is not part of the stage")
+				}
+			}
+			for _, o := range w.SyncSensors {
+				if !sensors[o] {
+					r.Add(Error, path, "synchronized sensor %q is not part of the stage", o.Name)
+				}
+			}
+		}
+	}
+	for i, sensor := range m.Sensors {
+		path := s.SensorPath(i)
+		if sensor.Vehicle != nil && !vehicles[sensor.Vehicle] {
+			r.Add(Error, path, "grouped vehicle %q is not part of the stage", sensor.Vehicle.Name)
+		}
+		for _, o := range sensor.SyncWaypoints {
+			if !waypoints[o] {
+				r.Add(Error, path, "synchronized waypoint is not part of the stage")
+			}
+		}
+		for _, o := range sensor.SyncSensors {
+			if !sensors[o] {
+				r.Add(Error, path, "synchronized sensor %q is not part of the stage", o.Name)
+			}
+		}
+	}
+})
+
+// MarkerSize reports markers with a negative or non-finite size.
+// A zero size is not written and the game default is used.
+var MarkerSize = NewRule("marker-size", func(s *Stage, r *Report) {
+	for i, m := range s.Mission.Markers {
+		if !validSize(m.Size.X) || !validSize(m.Size.Y) {
+			r.Add(Error, s.MarkerPath(i), "marker %q has invalid size %s", m.Name, gosqm.FormatNumber(m.Size.X)+","+gosqm.FormatNumber(m.Size.Y))
+		}
+	}
+})
+
+// MarkerIcon reports marker classes which are not part of vanilla Arma 2
+// and Operation Arrowhead. Addons add marker classes, so this is only an
+// info.
+var MarkerIcon = NewRule("marker-icon", func(s *Stage, r *Report) {
+	for i, m := range s.Mission.Markers {
+		if m.Type != "" && !m.Icon().Valid() {
+			r.Add(Info, s.MarkerPath(i), "marker %q has unknown type %q", m.Name, m.Type)
+		}
+	}
+})
+
+func validSize(f float64) bool {
+	return f >= 0 && !math.IsInf(f, 0)
+}
+
+// InvalidValue reports unknown enum values and intel values out of range.
+// Empty values are not written and therefore valid.
+var InvalidValue = NewRule("invalid-value", func(s *Stage, r *Report) {
+	type value interface {
+		Valid() bool
+		String() string
+	}
+	check := func(path, name string, v value) {
+		if v.String() != "" && !v.Valid() {
+			r.Add(Error, path, "invalid %s %q", name, v.String())
+		}
+	}
+	m := s.Mission
+	for i, g := range m.Groups {
+		check(s.GroupPath(i), "side", g.Side)
+		for j, w := range g.Waypoints {
+			path := s.WaypointPath(i, j)
+			check(path, "type", w.Type)
+			check(path, "combat mode", w.CombatMode)
+			check(path, "formation", w.Formation)
+			check(path, "speed", w.Speed)
+			check(path, "behaviour", w.Combat)
+		}
+	}
+	for _, u := range s.Units() {
+		check(u.Path, "side", u.Vehicle.Side)
+		check(u.Path, "rank", u.Vehicle.Rank)
+		check(u.Path, "special", u.Vehicle.Special)
+		check(u.Path, "lock", u.Vehicle.Lock)
+	}
+	for i, sensor := range m.Sensors {
+		check(s.SensorPath(i), "activation", sensor.ActivationBy)
+	}
+	for i, marker := range m.Markers {
+		check(s.MarkerPath(i), "marker type", marker.MarkerType)
+	}
+	if m.Intel != nil {
+		for _, err := range m.Intel.Validate() {
+			r.Add(Error, s.Name+"/Intel", "%s", err)
+		}
+	}
+})
+
+// MissingAddons returns a rule reporting vehicles whose addon is not listed
+// in the addons of the stage. Classnames unknown to db are ignored.
+func MissingAddons(db gosqm.AddonDB) Rule {
+	return NewRule("missing-addon", func(s *Stage, r *Report) {
+		listed := make(map[string]bool)
+		for _, addon := range s.Mission.Addons {
+			listed[strings.ToLower(addon)] = true
+		}
+		for _, u := range s.Units() {
+			patch, found := db.Lookup(u.Vehicle.Classname)
+			if found && !listed[strings.ToLower(patch)] {
+				r.Add(Error, u.Path, "addon %s of %s is missing from addons", patch, u.Vehicle.Classname)
+			}
+		}
+	})
+}
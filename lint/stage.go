@@ -0,0 +1,75 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/blang/gosqm"
+)
+
+// Stage is a stage of a mission file passed to the rules.
+type Stage struct {
+	Name    string
+	Mission *gosqm.Mission
+	File    *gosqm.MissionFile
+}
+
+// Stages returns the stages of mf which are set.
+func Stages(mf *gosqm.MissionFile) []*Stage {
+	var stages []*Stage
+	for _, s := range mf.Stages() {
+		stages = append(stages, &Stage{s.Name, s.Mission, mf})
+	}
+	return stages
+}
+
+// Unit is a vehicle of a stage and its location. Group is nil for empty
+// vehicles.
+type Unit struct {
+	Path    string
+	Vehicle *gosqm.Vehicle
+	Group   *gosqm.Group
+}
+
+// Units returns the group members of the stage followed by the empty
+// vehicles.
+func (s *Stage) Units() []Unit {
+	var units []Unit
+	for i, g := range s.Mission.Groups {
+		for j, v := range g.Units {
+			units = append(units, Unit{s.UnitPath(i, j), v, g})
+		}
+	}
+	for i, v := range s.Mission.Vehicles {
+		units = append(units, Unit{s.VehiclePath(i), v, nil})
+	}
+	return units
+}
+
+// GroupPath returns the location of the i-th group.
+func (s *Stage) GroupPath(i int) string {
+	return fmt.Sprintf("%s/Groups/Item%d", s.Name, i)
+}
+
+// UnitPath returns the location of the j-th unit of the i-th group.
+func (s *Stage) UnitPath(i, j int) string {
+	return fmt.Sprintf("%s/Vehicles/Item%d", s.GroupPath(i), j)
+}
+
+// WaypointPath returns the location of the j-th waypoint of the i-th group.
+func (s *Stage) WaypointPath(i, j int) string {
+	return fmt.Sprintf("%s/Waypoints/Item%d", s.GroupPath(i), j)
+}
+
+// VehiclePath returns the location of the i-th empty vehicle.
+func (s *Stage) VehiclePath(i int) string {
+	return fmt.Sprintf("%s/Vehicles/Item%d", s.Name, i)
+}
+
+// MarkerPath returns the location of the i-th marker.
+func (s *Stage) MarkerPath(i int) string {
+	return fmt.Sprintf("%s/Markers/Item%d", s.Name, i)
+}
+
+// SensorPath returns the location of the i-th sensor.
+func (s *Stage) SensorPath(i int) string {
+	return fmt.Sprintf("%s/Sensors/Item%d", s.Name, i)
+}
@@ -0,0 +1,305 @@
+package gosqm
+
+import (
+	"github.com/blang/gosqm/sqm"
+	"strconv"
+	"strings"
+)
+
+// MergeClasses merges two versions of a mission class with their common
+// ancestor using sqm.Merge and renumbers the vehicle ids afterwards.
+// Before merging, the vehicle ids of each version and the references to
+// them are replaced by keys which are equal for matching vehicles, so
+// vehicles added on both sides with the same id stay apart. Conflicting
+// vehicle ids are not reported since they are renumbered.
+// The given classes are not modified.
+func MergeClasses(base, ours, theirs *sqm.Class) (*sqm.Class, []sqm.Conflict) {
+	if base == nil {
+		base = &sqm.Class{}
+	}
+	base, ours, theirs = base.Copy(), ours.Copy(), theirs.Copy()
+	keyVehicleIDs(base, ours, theirs)
+	class, conflicts := sqm.Merge(base, ours, theirs)
+	RenumberIDs(class)
+	var real []sqm.Conflict
+	for _, c := range conflicts {
+		if !isVehicleIDPath(c.Path) {
+			real = append(real, c)
+		}
+	}
+	return class, real
+}
+
+// isVehicleIDPath reports whether path is the id of a vehicle of a stage
+// or of a group, which RenumberIDs overwrites
+func isVehicleIDPath(path string) bool {
+	parts := strings.Split(path, "/")
+	n := len(parts)
+	if n < 4 || parts[n-1] != "id" || !sqm.IsItemName(parts[n-2]) || parts[n-3] != "Vehicles" {
+		return false
+	}
+	return n == 4 || n == 6 && parts[n-5] == "Groups" && sqm.IsItemName(parts[n-4])
+}
+
+// keyVehicleIDs matches the vehicles of the three versions like sqm.Merge
+// does and gives matching vehicles the same new id. Vehicles only in ours
+// or theirs get unique ids, unless the same vehicle was added on both
+// sides. References are updated with the ids of their own version.
+func keyVehicleIDs(base, ours, theirs *sqm.Class) {
+	k := &vehicleKeys{keys: make(map[*sqm.Class]string)}
+	var names []string
+	seen := make(map[string]bool)
+	for _, c := range []*sqm.Class{ours, theirs, base} {
+		for _, stage := range c.Classes {
+			if !seen[stage.Name] {
+				seen[stage.Name] = true
+				names = append(names, stage.Name)
+			}
+		}
+	}
+	for _, name := range names {
+		b, o, t := findClass(base, name), findClass(ours, name), findClass(theirs, name)
+		k.collection(classes(findClass(b, "Vehicles")), classes(findClass(o, "Vehicles")), classes(findClass(t, "Vehicles")))
+		k.groups(classes(findClass(b, "Groups")), classes(findClass(o, "Groups")), classes(findClass(t, "Groups")))
+	}
+	for _, c := range []*sqm.Class{base, ours, theirs} {
+		for _, stage := range c.Classes {
+			k.apply(stage)
+		}
+	}
+}
+
+// vehicleKeys are the new ids of the vehicle classes of all versions
+type vehicleKeys struct {
+	keys map[*sqm.Class]string
+	next int
+}
+
+func (k *vehicleKeys) newKey() string {
+	k.next++
+	return strconv.Itoa(k.next - 1)
+}
+
+// groups matches the groups of the versions and keys their vehicles
+func (k *vehicleKeys) groups(base, ours, theirs []*sqm.Class) {
+	vehicles := func(group *sqm.Class) []*sqm.Class {
+		return classes(findClass(group, "Vehicles"))
+	}
+	m := matchVersions(base, ours, theirs)
+	for _, b := range base {
+		k.collection(vehicles(b), vehicles(m.ours[b]), vehicles(m.theirs[b]))
+	}
+	for _, group := range m.addedOurs {
+		k.collection(nil, vehicles(group), vehicles(m.addedBoth[group]))
+	}
+	for _, group := range m.addedTheirs {
+		k.collection(nil, nil, vehicles(group))
+	}
+}
+
+// collection keys the vehicles of a collection in each version
+func (k *vehicleKeys) collection(base, ours, theirs []*sqm.Class) {
+	m := matchVersions(base, ours, theirs)
+	for _, b := range base {
+		key := k.newKey()
+		k.keys[b] = key
+		if o := m.ours[b]; o != nil {
+			k.keys[o] = key
+		}
+		if t := m.theirs[b]; t != nil {
+			k.keys[t] = key
+		}
+	}
+	for _, veh := range m.addedOurs {
+		key := k.newKey()
+		k.keys[veh] = key
+		if same := m.addedBoth[veh]; same != nil {
+			k.keys[same] = key
+		}
+	}
+	for _, veh := range m.addedTheirs {
+		k.keys[veh] = k.newKey()
+	}
+}
+
+// versionMatch is the matching of the classes of ours and theirs with
+// base. Classes added on both sides are mapped from ours to theirs in
+// addedBoth and only listed in addedOurs.
+type versionMatch struct {
+	ours, theirs           map[*sqm.Class]*sqm.Class
+	addedOurs, addedTheirs []*sqm.Class
+	addedBoth              map[*sqm.Class]*sqm.Class
+}
+
+// matchVersions matches ours and theirs with base like sqm.Merge, classes
+// added on both sides are paired if they are equal apart from ids
+func matchVersions(base, ours, theirs []*sqm.Class) *versionMatch {
+	m := &versionMatch{
+		ours:      make(map[*sqm.Class]*sqm.Class),
+		theirs:    make(map[*sqm.Class]*sqm.Class),
+		addedBoth: make(map[*sqm.Class]*sqm.Class),
+	}
+	pairs, _, addedOurs := sqm.MatchClasses(base, ours)
+	for _, pair := range pairs {
+		m.ours[pair[0]] = pair[1]
+	}
+	m.addedOurs = addedOurs
+	pairs, _, addedTheirs := sqm.MatchClasses(base, theirs)
+	for _, pair := range pairs {
+		m.theirs[pair[0]] = pair[1]
+	}
+	used := make(map[*sqm.Class]bool)
+	for _, o := range addedOurs {
+		for _, t := range addedTheirs {
+			if !used[t] && equalWithoutIDs(o, t) {
+				used[t] = true
+				m.addedBoth[o] = t
+				break
+			}
+		}
+	}
+	for _, t := range addedTheirs {
+		if !used[t] {
+			m.addedTheirs = append(m.addedTheirs, t)
+		}
+	}
+	return m
+}
+
+// equalWithoutIDs reports whether two classes are equal apart from ids
+// and id references, which differ between the versions of a merge
+func equalWithoutIDs(a, b *sqm.Class) bool {
+	return len(sqm.Diff(withoutIDs(a.Copy()), withoutIDs(b.Copy()))) == 0
+}
+
+func withoutIDs(c *sqm.Class) *sqm.Class {
+	var props []*sqm.Property
+	for _, prop := range c.Props {
+		if prop.Name != "id" && prop.Name != "idVehicle" && prop.Name != "idObject" {
+			props = append(props, prop)
+		}
+	}
+	c.Props = props
+	for _, sub := range c.Classes {
+		withoutIDs(sub)
+	}
+	return c
+}
+
+// apply replaces the vehicle ids of a stage by their keys and updates the
+// references with the ids of this stage
+func (k *vehicleKeys) apply(stage *sqm.Class) {
+	ids := make(map[string]string)
+	for _, veh := range stageVehicles(stage) {
+		for _, prop := range veh.Props {
+			if prop.Name == "id" {
+				if _, found := ids[prop.Value]; !found {
+					ids[prop.Value] = k.keys[veh]
+				}
+				prop.Value = k.keys[veh]
+			}
+		}
+	}
+	updateVehicleRefs(stage, ids)
+}
+
+func findClass(c *sqm.Class, name string) *sqm.Class {
+	if c == nil {
+		return nil
+	}
+	for _, sub := range c.Classes {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func classes(c *sqm.Class) []*sqm.Class {
+	if c == nil {
+		return nil
+	}
+	return c.Classes
+}
+
+// RenumberIDs assigns sequential ids to all vehicles of every stage of a
+// mission class, the same way the Encoder does, and updates the idVehicle
+// references of sensors and the idObject references of waypoints.
+func RenumberIDs(class *sqm.Class) {
+	for _, stage := range class.Classes {
+		renumberStageIDs(stage)
+	}
+}
+
+// stageVehicles returns the vehicle classes of a stage in encoding order,
+// group members first
+func stageVehicles(stage *sqm.Class) []*sqm.Class {
+	var vehicles []*sqm.Class
+	for _, c := range stage.Classes {
+		if c.Name != "Groups" {
+			continue
+		}
+		for _, group := range c.Classes {
+			for _, members := range group.Classes {
+				if members.Name == "Vehicles" {
+					vehicles = append(vehicles, members.Classes...)
+				}
+			}
+		}
+	}
+	for _, c := range stage.Classes {
+		if c.Name == "Vehicles" {
+			vehicles = append(vehicles, c.Classes...)
+		}
+	}
+	return vehicles
+}
+
+func renumberStageIDs(stage *sqm.Class) {
+	ids := make(map[string]string)
+	for i, veh := range stageVehicles(stage) {
+		id := strconv.Itoa(i)
+		for _, prop := range veh.Props {
+			if prop.Name == "id" {
+				if _, found := ids[prop.Value]; !found {
+					ids[prop.Value] = id
+				}
+				prop.Value = id
+			}
+		}
+	}
+	updateVehicleRefs(stage, ids)
+}
+
+// updateVehicleRefs maps the idVehicle of sensors and the idObject of
+// waypoints with ids, unknown references are kept
+func updateVehicleRefs(stage *sqm.Class, ids map[string]string) {
+	update := func(c *sqm.Class, name string) {
+		for _, prop := range c.Props {
+			if prop.Name == name {
+				if id, found := ids[prop.Value]; found {
+					prop.Value = id
+				}
+			}
+		}
+	}
+	for _, c := range stage.Classes {
+		switch c.Name {
+		case "Sensors":
+			for _, sensor := range c.Classes {
+				update(sensor, "idVehicle")
+			}
+		case "Groups":
+			for _, group := range c.Classes {
+				for _, waypoints := range group.Classes {
+					if waypoints.Name != "Waypoints" {
+						continue
+					}
+					for _, wp := range waypoints.Classes {
+						update(wp, "idObject")
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package gosqm
+
+import (
+	"github.com/blang/gosqm/sqm"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func parseClass(input string) *sqm.Class {
+	c, err := sqm.MakeParser(input).Run()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestMergeClasses(t *testing.T) {
+	Convey("Given units added on both sides", t, func() {
+		base := parseClass(`class Mission
+{
+	class Vehicles
+	{
+		items=1;
+		class Item0 { position[]={1,2,3}; id=0; vehicle="HMMWV"; };
+	};
+	class Sensors
+	{
+		items=1;
+		class Item0 { position[]={1,2,3}; idVehicle=0; };
+	};
+};
+`)
+		ours := parseClass(`class Mission
+{
+	class Vehicles
+	{
+		items=2;
+		class Item0 { position[]={1,2,3}; id=0; vehicle="HMMWV"; };
+		class Item1 { position[]={4,5,6}; id=1; vehicle="M1A1"; };
+	};
+	class Sensors
+	{
+		items=1;
+		class Item0 { position[]={1,2,3}; idVehicle=0; };
+	};
+};
+`)
+		theirs := parseClass(`class Mission
+{
+	class Vehicles
+	{
+		items=2;
+		class Item0 { position[]={7,8,9}; id=0; vehicle="UH1Y"; };
+		class Item1 { position[]={1,2,3}; id=1; vehicle="HMMWV"; };
+	};
+	class Sensors
+	{
+		items=1;
+		class Item0 { position[]={1,2,3}; idVehicle=1; };
+	};
+};
+`)
+		merged, conflicts := MergeClasses(base, ours, theirs)
+		Convey("No conflicts are reported for shifted ids", func() {
+			So(len(conflicts), ShouldEqual, 0)
+		})
+		Convey("Ids are renumbered", func() {
+			vehs := merged.Classes[0].Classes[0]
+			So(len(vehs.Classes), ShouldEqual, 3)
+			So(vehs.Classes[0].Props, ShouldContainProp, &sqm.Property{"id", sqm.TNumber, "0"})
+			So(vehs.Classes[1].Props, ShouldContainProp, &sqm.Property{"id", sqm.TNumber, "1"})
+			So(vehs.Classes[2].Props, ShouldContainProp, &sqm.Property{"id", sqm.TNumber, "2"})
+		})
+	})
+}
+
+func TestMergeClassesCollidingIDs(t *testing.T) {
+	Convey("Given vehicles with the same id added on both sides", t, func() {
+		base := parseClass(`class Mission
+{
+	class Groups
+	{
+		items=1;
+		class Item0 { side="WEST"; id=1; class Vehicles { items=1; class Item0 { position[]={0,0,0}; id=0; vehicle="USMC_Soldier"; }; }; };
+	};
+};
+`)
+		ours := parseClass(`class Mission
+{
+	class Groups
+	{
+		items=1;
+		class Item0 { side="WEST"; id=2; class Vehicles { items=1; class Item0 { position[]={0,0,0}; id=0; vehicle="USMC_Soldier"; }; }; };
+	};
+	class Vehicles
+	{
+		items=1;
+		class Item0 { position[]={1,2,3}; id=1; vehicle="HMMWV"; };
+	};
+	class Sensors
+	{
+		items=1;
+		class Item0 { position[]={1,2,3}; idVehicle=1; text="ours"; };
+	};
+};
+`)
+		theirs := parseClass(`class Mission
+{
+	class Groups
+	{
+		items=1;
+		class Item0 { side="WEST"; id=3; class Vehicles { items=1; class Item0 { position[]={0,0,0}; id=0; vehicle="USMC_Soldier"; }; }; };
+	};
+	class Vehicles
+	{
+		items=1;
+		class Item0 { position[]={7,8,9}; id=1; vehicle="UH1Y"; };
+	};
+	class Sensors
+	{
+		items=1;
+		class Item0 { position[]={7,8,9}; idVehicle=1; text="theirs"; };
+	};
+};
+`)
+		merged, conflicts := MergeClasses(base, ours, theirs)
+		mission := merged.Classes[0]
+		vehicleID := func(classname string) string {
+			for _, veh := range stageVehicles(mission) {
+				if findProp(veh, "vehicle") == classname {
+					return findProp(veh, "id")
+				}
+			}
+			return ""
+		}
+		sensorRef := func(text string) string {
+			for _, sensor := range findClass(mission, "Sensors").Classes {
+				if findProp(sensor, "text") == text {
+					return findProp(sensor, "idVehicle")
+				}
+			}
+			return ""
+		}
+
+		Convey("Both vehicles get their own id", func() {
+			So(vehicleID("USMC_Soldier"), ShouldEqual, "0")
+			So(vehicleID("HMMWV"), ShouldNotEqual, vehicleID("UH1Y"))
+		})
+		Convey("Each trigger keeps pointing at the vehicle of its side", func() {
+			So(sensorRef("ours"), ShouldEqual, vehicleID("HMMWV"))
+			So(sensorRef("theirs"), ShouldEqual, vehicleID("UH1Y"))
+		})
+		Convey("Ids of other classes still conflict", func() {
+			So(conflicts, ShouldHaveLength, 1)
+			So(conflicts[0].Path, ShouldEqual, "Mission/Groups/Item0/id")
+		})
+		Convey("The inputs are not modified", func() {
+			So(findProp(theirs.Classes[0].Classes[2].Classes[0], "idVehicle"), ShouldEqual, "1")
+		})
+	})
+}
+
+// findProp returns the value of a property of c
+func findProp(c *sqm.Class, name string) string {
+	for _, prop := range c.Props {
+		if prop.Name == name {
+			return prop.Value
+		}
+	}
+	return ""
+}
+
+func TestRenumberIDs(t *testing.T) {
+	Convey("Given a mission with unordered ids", t, func() {
+		class := parseClass(`class Mission
+{
+	class Groups
+	{
+		items=1;
+		class Item0
+		{
+			side="WEST";
+			class Vehicles
+			{
+				items=1;
+				class Item0 { id=7; };
+			};
+		};
+	};
+	class Sensors
+	{
+		items=1;
+		class Item0 { idVehicle=12; };
+	};
+	class Vehicles
+	{
+		items=1;
+		class Item0 { id=12; };
+	};
+};
+`)
+		RenumberIDs(class)
+		Convey("Ids are sequential and references are updated", func() {
+			mission := class.Classes[0]
+			So(mission.Classes[0].Classes[0].Classes[0].Classes[0].Props[0].Value, ShouldEqual, "0")
+			So(mission.Classes[2].Classes[0].Props[0].Value, ShouldEqual, "1")
+			So(mission.Classes[1].Classes[0].Props[0].Value, ShouldEqual, "1")
+		})
+	})
+}
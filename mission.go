@@ -1,5 +1,9 @@
 package gosqm
 
+import (
+	"github.com/blang/gosqm/sqm"
+)
+
 type MissionFile struct {
 	Version    string
 	Mission    *Mission
@@ -8,27 +12,63 @@ type MissionFile struct {
 	OutroLoose *Mission
 }
 
+// Group is a group of units. ID is the group id written by newer editors.
+// Properties and classes unknown to gosqm are reported as warnings like
+// elsewhere, but kept in the Extra fields and written back unchanged.
 type Group struct {
-	Side      string
-	Waypoints []*Waypoint
-	Units     []*Vehicle
+	Side          Side
+	ID            string
+	Waypoints     []*Waypoint
+	Units         []*Vehicle
+	ExtraProps    []*sqm.Property
+	ExtraArrprops []*sqm.ArrayProperty
+	ExtraClasses  []*sqm.Class
 }
 
+// Waypoint is a waypoint of a group.
+// Synchronizations are kept on both sides, use SyncWaypoint and SyncSensor
+// to add them. A waypoint attached to a vehicle of the mission references
+// it in Vehicle, one attached to a terrain object stores the object id in
+// StaticID and the building position in HousePos. Timeouts are in
+// seconds, Placement and CompletionRadius in meters, zero values are the
+// game defaults and not written.
 type Waypoint struct {
-	Position         [3]string
-	Type             string
+	Position         Vec3
+	Type             WaypointType
+	CombatMode       CombatMode
+	Formation        Formation
+	Speed            Speed
+	Combat           Behaviour
+	Description      string
+	Condition        string
+	OnActivation     string
+	Script           string
+	TimeoutMin       float64
+	TimeoutMid       float64
+	TimeoutMax       float64
+	Placement        float64
+	CompletionRadius float64
 	ShowWP           string
+	StaticID         string
+	HousePos         string
+	Vehicle          *Vehicle
 	Effects          *Effects
-	Synchronizations []string
+	SyncWaypoints    []*Waypoint
+	SyncSensors      []*Sensor
 }
 
+// Vehicle is a unit or an empty vehicle.
+// A zero Angle is not written. Skill, Health, Fuel and Ammo are nil if the
+// file does not set them, the game then uses its defaults, which are not
+// zero, so a parsed zero is kept and written back. A zero Side is written
+// as SideEmpty.
 type Vehicle struct {
 	Name                string
-	Position            [3]string
-	Angle               string
+	Position            Vec3
+	Angle               float64
 	Classname           string
-	Skill               string
-	Special             string
+	Skill               *float64
+	Special             Special
 	IsLeader            bool
 	Player              string
 	Description         string
@@ -36,52 +76,58 @@ type Vehicle struct {
 	PresenceCond        string
 	Placement           string
 	Age                 string
-	Lock                string
-	Rank                string
-	Health              string
-	Fuel                string
-	Ammo                string
+	Lock                Lock
+	Rank                Rank
+	Health              *float64
+	Fuel                *float64
+	Ammo                *float64
 	Init                string
-	Side                string
-	Markers             []string
+	Side                Side
+	Markers             []*Marker
 	ForceHeadlessClient bool
 }
 
+// Marker is a map marker. Type is the marker class from CfgMarkers, which
+// addons extend, so it is not validated while parsing. Icon returns it as
+// MarkerIcon to check it against the vanilla classes.
 type Marker struct {
 	Name       string
-	Position   [3]string
-	Angle      string
+	Position   Vec3
+	Angle      float64
 	Type       string
-	MarkerType string
+	MarkerType MarkerShape
 	Text       string
 	ColorName  string
 	FillName   string
 	DrawBorder bool
-	Size       [2]string
+	Size       Vec2
 }
 
+// Sensor is a trigger. Vehicle is the vehicle the trigger is grouped with,
+// synchronizations are kept on both sides like for waypoints.
 type Sensor struct {
-	Name             string
-	Position         [3]string
-	Size             [2]string
-	Angle            string
-	IsRectangle      bool
-	ActivationBy     string
-	ActivationType   string
-	TimeoutMin       string
-	TimeoutMid       string
-	TimeoutMax       string
-	Type             string
-	IsRepeating      bool
-	Age              string
-	Condition        string
-	OnActivation     string
-	OnDeactivation   string
-	IsInterruptible  bool
-	Text             string
-	Synchronizations []string
-	VehicleID        string
-	Effects          *Effects
+	Name            string
+	Position        Vec3
+	Size            Vec2
+	Angle           float64
+	IsRectangle     bool
+	ActivationBy    ActivationBy
+	ActivationType  string
+	TimeoutMin      string
+	TimeoutMid      string
+	TimeoutMax      string
+	Type            string
+	IsRepeating     bool
+	Age             string
+	Condition       string
+	OnActivation    string
+	OnDeactivation  string
+	IsInterruptible bool
+	Text            string
+	SyncWaypoints   []*Waypoint
+	SyncSensors     []*Sensor
+	Vehicle         *Vehicle
+	Effects         *Effects
 }
 
 type Effects struct {
@@ -106,13 +152,33 @@ type Mission struct {
 	Sensors    []*Sensor
 }
 
+// Intel holds the briefing, date and weather of a mission.
+// A zero Year means the date is not set and the game default is used.
 type Intel struct {
-	ResistanceWest  bool
-	StartWeather    string
-	ForecastWeather string
-	Year            string
-	Month           string
-	Day             string
-	Hour            string
-	Minute          string
+	BriefingName   string
+	OverviewText   string
+	ResistanceWest bool
+	Weather        Weather
+	Year           int
+	Month          int
+	Day            int
+	Hour           int
+	Minute         int
+}
+
+// Weather is the weather at mission start and the forecast reached after
+// TimeOfChanges seconds. All other values range from 0 to 1.
+// StartWeather and ForecastWeather are nil if the file does not set them,
+// zero values of the others are not written.
+type Weather struct {
+	StartWeather    *float64
+	ForecastWeather *float64
+	StartFog        float64
+	ForecastFog     float64
+	StartRain       float64
+	StartWind       float64
+	StartGust       float64
+	StartWaves      float64
+	StartLightnings float64
+	TimeOfChanges   float64
 }
@@ -0,0 +1,185 @@
+// Package orbat builds the slot list of the playable units of a mission,
+// grouped by side and group in editor order.
+package orbat
+
+import (
+	"fmt"
+	"github.com/blang/gosqm"
+	"strconv"
+	"strings"
+)
+
+// ORBAT is the order of battle of the playable units of a mission.
+type ORBAT struct {
+	Sides []*Side `json:"sides"`
+}
+
+// Side is a side with playable groups.
+type Side struct {
+	Side   gosqm.Side `json:"side"`
+	Groups []*Group   `json:"groups"`
+}
+
+// Group is a group with playable units.
+type Group struct {
+	Callsign string  `json:"callsign"`
+	Slots    []*Slot `json:"slots"`
+}
+
+// Slot is a playable unit. Number counts the slots of all sides starting at
+// 1. Player is true for the unit the host or singleplayer user controls.
+// Presence is the probability of presence if it is less than 1, the
+// unit is only present if PresenceCond is true.
+type Slot struct {
+	Number       int        `json:"number"`
+	Description  string     `json:"description"`
+	Rank         gosqm.Rank `json:"rank"`
+	Classname    string     `json:"classname"`
+	Player       bool       `json:"player"`
+	Presence     string     `json:"presence,omitempty"`
+	PresenceCond string     `json:"presenceCondition,omitempty"`
+}
+
+// Callsigns returns the callsign of the i-th group of a side, counting all
+// groups of the side in editor order starting at 0.
+type Callsigns func(side gosqm.Side, i int, g *gosqm.Group) string
+
+// Options configure the creation of an ORBAT. A nil Callsigns uses
+// DefaultCallsigns.
+type Options struct {
+	Callsigns Callsigns
+}
+
+var phonetic = []string{"Alpha", "Bravo", "Charlie", "Delta", "Echo", "Foxtrot", "Golf", "Hotel", "India", "Juliet", "Kilo", "Lima", "Mike", "November", "Oscar", "Papa", "Quebec", "Romeo", "Sierra", "Tango", "Uniform", "Victor", "Whiskey", "X-ray", "Yankee", "Zulu"}
+
+// DefaultCallsigns names groups like the game does: Alpha 1-1 to Alpha 1-6,
+// Alpha 2-1 to Alpha 4-6, then Bravo 1-1 and so on.
+func DefaultCallsigns(side gosqm.Side, i int, g *gosqm.Group) string {
+	name := phonetic[(i/24)%len(phonetic)]
+	return fmt.Sprintf("%s %d-%d", name, i/6%4+1, i%6+1)
+}
+
+// ListCallsigns uses the names in order and falls back to DefaultCallsigns
+// once they are used up. The names are counted per side.
+func ListCallsigns(names ...string) Callsigns {
+	return func(side gosqm.Side, i int, g *gosqm.Group) string {
+		if i < len(names) {
+			return names[i]
+		}
+		return DefaultCallsigns(side, i, g)
+	}
+}
+
+// IsPlayable returns true if the unit is playable or the player.
+func IsPlayable(v *gosqm.Vehicle) bool {
+	return strings.HasPrefix(strings.ToUpper(v.Player), "PLAY")
+}
+
+// IsPlayer returns true if the unit is the player, e.g. "PLAYER COMMANDER".
+func IsPlayer(v *gosqm.Vehicle) bool {
+	return strings.HasPrefix(strings.ToUpper(v.Player), "PLAYER")
+}
+
+// isAbsent returns true if the unit is never present
+func isAbsent(v *gosqm.Vehicle) bool {
+	if v.Presence == "" {
+		return false
+	}
+	f, err := strconv.ParseFloat(v.Presence, 64)
+	return err == nil && f <= 0
+}
+
+// New returns the ORBAT of the Mission stage. Units with a presence of 0
+// are left out.
+func New(mf *gosqm.MissionFile, options ...Options) *ORBAT {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	callsigns := opts.Callsigns
+	if callsigns == nil {
+		callsigns = DefaultCallsigns
+	}
+
+	o := &ORBAT{}
+	if mf.Mission == nil {
+		return o
+	}
+	sides := make(map[gosqm.Side]*Side)
+	counts := make(map[gosqm.Side]int)
+	number := 0
+	for _, g := range mf.Mission.Groups {
+		i := counts[g.Side]
+		counts[g.Side]++
+		group := &Group{Callsign: callsigns(g.Side, i, g)}
+		for _, v := range g.Units {
+			if !IsPlayable(v) || isAbsent(v) {
+				continue
+			}
+			number++
+			group.Slots = append(group.Slots, &Slot{
+				Number:       number,
+				Description:  v.Description,
+				Rank:         v.Rank,
+				Classname:    v.Classname,
+				Player:       IsPlayer(v),
+				Presence:     presence(v.Presence),
+				PresenceCond: presenceCond(v.PresenceCond),
+			})
+		}
+		if len(group.Slots) == 0 {
+			continue
+		}
+		side, found := sides[g.Side]
+		if !found {
+			side = &Side{Side: g.Side}
+			sides[g.Side] = side
+			o.Sides = append(o.Sides, side)
+		}
+		side.Groups = append(side.Groups, group)
+	}
+	return o
+}
+
+// presence returns the probability of presence if it is less than 1
+func presence(s string) string {
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f >= 1 {
+		return ""
+	}
+	return s
+}
+
+// presenceCond returns the condition of presence unless it is always true
+func presenceCond(s string) string {
+	if strings.TrimSpace(s) == "true" {
+		return ""
+	}
+	return s
+}
+
+// Slots returns the number of slots.
+func (o *ORBAT) Slots() int {
+	n := 0
+	for _, side := range o.Sides {
+		for _, g := range side.Groups {
+			n += len(g.Slots)
+		}
+	}
+	return n
+}
+
+// Role returns the description of the slot or its classname if it has none.
+func (s *Slot) Role() string {
+	if s.Description != "" {
+		return s.Description
+	}
+	return s.Classname
+}
+
+// Status returns "player" or "playable".
+func (s *Slot) Status() string {
+	if s.Player {
+		return "player"
+	}
+	return "playable"
+}
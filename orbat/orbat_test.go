@@ -0,0 +1,92 @@
+package orbat
+
+import (
+	"bytes"
+	"github.com/blang/gosqm"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+func TestORBAT(t *testing.T) {
+	Convey("Given a mission with playable units", t, func() {
+		m := &gosqm.Mission{
+			Groups: []*gosqm.Group{
+				&gosqm.Group{Side: gosqm.SideEast, Units: []*gosqm.Vehicle{
+					&gosqm.Vehicle{Classname: "RU_Soldier_SL", Player: "PLAY CDG", Description: "Squad Leader | East"},
+				}},
+				&gosqm.Group{Side: gosqm.SideWest, Units: []*gosqm.Vehicle{
+					&gosqm.Vehicle{Classname: "USMC_Soldier"},
+				}},
+				&gosqm.Group{Side: gosqm.SideWest, Units: []*gosqm.Vehicle{
+					&gosqm.Vehicle{Classname: "USMC_Soldier_SL", Player: "PLAYER COMMANDER", Description: "Squad Leader", Rank: gosqm.RankSergeant},
+					&gosqm.Vehicle{Classname: "USMC_Soldier_Medic", Player: "PLAY CDG", Presence: "0.5", PresenceCond: "true"},
+					&gosqm.Vehicle{Classname: "USMC_Soldier", Player: "PLAY CDG", Presence: "0"},
+					&gosqm.Vehicle{Classname: "USMC_Soldier_AR", Player: "PLAY CDG", Presence: "1", PresenceCond: "paramsArray select 0 == 1"},
+				}},
+			},
+		}
+		mf := &gosqm.MissionFile{Mission: m}
+
+		Convey("Slots are listed by side and group in editor order", func() {
+			o := New(mf)
+			So(o.Sides, ShouldHaveLength, 2)
+			So(o.Sides[0].Side, ShouldEqual, gosqm.SideEast)
+			So(o.Sides[1].Groups, ShouldHaveLength, 1)
+			So(o.Slots(), ShouldEqual, 4)
+
+			g := o.Sides[1].Groups[0]
+			So(g.Callsign, ShouldEqual, "Alpha 1-2")
+			So(g.Slots[0].Number, ShouldEqual, 2)
+			So(g.Slots[0].Player, ShouldBeTrue)
+			So(g.Slots[0].Rank, ShouldEqual, gosqm.RankSergeant)
+			So(g.Slots[1].Player, ShouldBeFalse)
+			So(g.Slots[1].Role(), ShouldEqual, "USMC_Soldier_Medic")
+			So(g.Slots[1].Presence, ShouldEqual, "0.5")
+			So(g.Slots[1].PresenceCond, ShouldEqual, "")
+			So(g.Slots[2].Classname, ShouldEqual, "USMC_Soldier_AR")
+			So(g.Slots[2].Presence, ShouldEqual, "")
+			So(g.Slots[2].PresenceCond, ShouldEqual, "paramsArray select 0 == 1")
+		})
+		Convey("Callsigns follow the scheme", func() {
+			So(DefaultCallsigns(gosqm.SideWest, 0, nil), ShouldEqual, "Alpha 1-1")
+			So(DefaultCallsigns(gosqm.SideWest, 6, nil), ShouldEqual, "Alpha 2-1")
+			So(DefaultCallsigns(gosqm.SideWest, 25, nil), ShouldEqual, "Bravo 1-2")
+			o := New(mf, Options{Callsigns: ListCallsigns("Command", "Viking")})
+			So(o.Sides[0].Groups[0].Callsign, ShouldEqual, "Command")
+			So(o.Sides[1].Groups[0].Callsign, ShouldEqual, "Viking")
+		})
+		Convey("All formats can be written", func() {
+			o := New(mf)
+			for _, format := range Formats() {
+				var buf bytes.Buffer
+				So(o.Write(&buf, format), ShouldBeNil)
+				So(buf.String(), ShouldContainSubstring, "USMC_Soldier_Medic")
+			}
+			So(o.Write(&bytes.Buffer{}, "pdf"), ShouldNotBeNil)
+		})
+		Convey("Markdown has a table per group", func() {
+			var buf bytes.Buffer
+			So(New(mf).WriteMarkdown(&buf), ShouldBeNil)
+			out := buf.String()
+			So(out, ShouldStartWith, "## EAST\n\n### Alpha 1-1\n")
+			So(out, ShouldContainSubstring, "| 1 | Squad Leader \\| East |  | RU_Soldier_SL | playable |\n")
+			So(out, ShouldContainSubstring, "| 3 | USMC_Soldier_Medic |  | USMC_Soldier_Medic | playable (conditional) |\n")
+		})
+		Convey("HTML is escaped", func() {
+			mf.Mission.Groups[0].Units[0].Description = "<b>&</b> Lead"
+			var buf bytes.Buffer
+			So(New(mf).WriteHTML(&buf), ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, "<td>&lt;b&gt;&amp;&lt;/b&gt; Lead</td>")
+			So(buf.String(), ShouldNotContainSubstring, "<b>")
+			So(buf.String(), ShouldNotContainSubstring, "paramsArray")
+		})
+		Convey("CSV has a row per slot", func() {
+			var buf bytes.Buffer
+			So(New(mf).WriteCSV(&buf), ShouldBeNil)
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			So(lines, ShouldHaveLength, 5)
+			So(lines[2], ShouldEqual, "2,WEST,Alpha 1-2,Squad Leader,SERGEANT,USMC_Soldier_SL,player,,")
+		})
+	})
+}
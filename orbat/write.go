@@ -0,0 +1,111 @@
+package orbat
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Writers are the output formats by name.
+var Writers = map[string]func(o *ORBAT, w io.Writer) error{
+	"markdown": (*ORBAT).WriteMarkdown,
+	"html":     (*ORBAT).WriteHTML,
+	"csv":      (*ORBAT).WriteCSV,
+	"json":     (*ORBAT).WriteJSON,
+}
+
+// Formats returns the names of the output formats.
+func Formats() []string {
+	var names []string
+	for name := range Writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write writes the ORBAT in the named format.
+func (o *ORBAT) Write(w io.Writer, format string) error {
+	write, found := Writers[strings.ToLower(format)]
+	if !found {
+		return fmt.Errorf("Unknown format %q, expected one of %s", format, strings.Join(Formats(), ", "))
+	}
+	return write(o, w)
+}
+
+// markdownEscaper escapes characters which break a table cell
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\n", " ", "\r", "")
+
+// WriteMarkdown writes a section per side and a table per group.
+func (o *ORBAT) WriteMarkdown(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	for i, side := range o.Sides {
+		if i > 0 {
+			printf("\n")
+		}
+		printf("## %s\n", side.Side)
+		for _, g := range side.Groups {
+			printf("\n### %s\n\n", markdownEscaper.Replace(g.Callsign))
+			printf("| # | Role | Rank | Class | Status |\n")
+			printf("|---|------|------|-------|--------|\n")
+			for _, s := range g.Slots {
+				status := s.Status()
+				if s.Presence != "" || s.PresenceCond != "" {
+					status += " (conditional)"
+				}
+				printf("| %d | %s | %s | %s | %s |\n", s.Number, markdownEscaper.Replace(s.Role()), s.Rank, markdownEscaper.Replace(s.Classname), status)
+			}
+		}
+	}
+	return err
+}
+
+var htmlTemplate = template.Must(template.New("orbat").Parse(`<div class="orbat">
+{{range .Sides}}<h2>{{.Side}}</h2>
+{{range .Groups}}<h3>{{.Callsign}}</h3>
+<table>
+<tr><th>#</th><th>Role</th><th>Rank</th><th>Class</th><th>Status</th></tr>
+{{range .Slots}}<tr><td>{{.Number}}</td><td>{{.Role}}</td><td>{{.Rank}}</td><td>{{.Classname}}</td><td>{{.Status}}{{if or .Presence .PresenceCond}} (conditional){{end}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}</div>
+`))
+
+// WriteHTML writes a html fragment with a table per group.
+func (o *ORBAT) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, o)
+}
+
+// WriteCSV writes a row per slot with a header row.
+func (o *ORBAT) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"number", "side", "group", "description", "rank", "classname", "status", "presence", "presenceCondition"})
+	for _, side := range o.Sides {
+		for _, g := range side.Groups {
+			for _, s := range g.Slots {
+				cw.Write([]string{strconv.Itoa(s.Number), side.Side.String(), g.Callsign, s.Description, s.Rank.String(), s.Classname, s.Status(), s.Presence, s.PresenceCond})
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the ORBAT as indented JSON.
+func (o *ORBAT) WriteJSON(w io.Writer) error {
+	buf, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
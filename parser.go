@@ -23,6 +23,7 @@ const (
 	ContextMarker                  = "Marker"
 	ContextSensor                  = "Sensor"
 	ContextSensorEffects           = "SensorEffects"
+	ContextSynchronization         = "Synchronization"
 )
 
 type UnkownPropertyError struct {
@@ -46,6 +47,28 @@ func (e *UnkownPropertyError) Error() string {
 	}
 }
 
+type InvalidValueError struct {
+	ParentClass   *sqm.Class
+	Property      *sqm.Property
+	ArrayProperty *sqm.ArrayProperty
+	Context       Context
+	Err           error
+}
+
+func (e *InvalidValueError) Error() string {
+	var propName string
+	if e.Property != nil {
+		propName = e.Property.Name
+	} else if e.ArrayProperty != nil {
+		propName = e.ArrayProperty.Name
+	}
+	if propName != "" && e.ParentClass != nil {
+		return "Invalid value of property " + propName + " in class " + e.ParentClass.Name + " in context " + e.Context.String() + ": " + e.Err.Error()
+	} else {
+		return "Invalid value: " + e.Err.Error()
+	}
+}
+
 type UnkownClassError struct {
 	ParentClass *sqm.Class
 	Class       *sqm.Class
@@ -60,28 +83,72 @@ func (e *UnkownClassError) Error() string {
 	}
 }
 
+// MissingClassError is a required class which is missing in ParentClass.
+type MissingClassError struct {
+	ParentClass *sqm.Class
+	Name        string
+	Context     Context
+}
+
+func (e *MissingClassError) Error() string {
+	msg := "Missing class " + e.Name
+	if e.ParentClass != nil {
+		msg += " in class " + e.ParentClass.Name
+	}
+	return msg + " in context " + e.Context.String()
+}
+
+// MissingPropertyError is a required property which is missing in
+// ParentClass.
+type MissingPropertyError struct {
+	ParentClass *sqm.Class
+	Name        string
+	Context     Context
+}
+
+func (e *MissingPropertyError) Error() string {
+	msg := "Missing property " + e.Name
+	if e.ParentClass != nil {
+		msg += " in class " + e.ParentClass.Name
+	}
+	return msg + " in context " + e.Context.String()
+}
+
 type Parser struct {
-	wg     *sync.WaitGroup
-	errors []error
+	wg      *sync.WaitGroup
+	errors  []error
+	err     error
+	stage   *stageRefs
+	options DecoderOptions
 }
 
-func NewParser() *Parser {
-	return &Parser{}
+// NewParser returns a parser, the first options are used if given.
+func NewParser(options ...DecoderOptions) *Parser {
+	p := &Parser{}
+	if len(options) > 0 {
+		p.options = options[0]
+	}
+	return p
 }
 
-func (p *Parser) Parse(class *sqm.Class) (*MissionFile, error) {
+// Parse parses a mission file. Options given replace the options of the
+// parser. In strict mode the first unknown property or class is returned
+// as error.
+func (p *Parser) Parse(class *sqm.Class, options ...DecoderOptions) (*MissionFile, error) {
 	p.errors = nil
+	p.err = nil
+	if len(options) > 0 {
+		p.options = options[0]
+	}
 	if class == nil {
 		return nil, fmt.Errorf("Class was nil")
 	}
+	if err := checkTree(class, make(map[*sqm.Class]bool)); err != nil {
+		return nil, err
+	}
 
 	mf := &MissionFile{}
 
-	mf.Intro = &Mission{}
-	mf.Mission = &Mission{}
-	mf.OutroLoose = &Mission{}
-	mf.OutroWin = &Mission{}
-
 	p.wg = &sync.WaitGroup{}
 
 	//set version
@@ -90,38 +157,152 @@ func (p *Parser) Parse(class *sqm.Class) (*MissionFile, error) {
 			mf.Version = val.Value
 		}
 	}
+	// only stages present in the file are set
 	for _, stage := range class.Classes {
-		switch stage.Name {
-		case "Intro":
-			p.parseMission(stage, mf.Intro)
-		case "Mission":
-			p.parseMission(stage, mf.Mission)
-		case "OutroWin":
-			p.parseMission(stage, mf.OutroWin)
-		case "OutroLoose":
-			p.parseMission(stage, mf.OutroLoose)
-
-		default:
+		if mf.stage(stage.Name) == nil {
 			p.saveError(&UnkownClassError{
 				ParentClass: class,
 				Class:       stage,
 				Context:     ContextMissionFile,
 			})
+			continue
 		}
+		mission := mf.Stage(stage.Name)
+		if mission == nil {
+			mission = &Mission{}
+			mf.SetStage(stage.Name, mission)
+		}
+		p.parseMission(stage, mission)
 	}
 	// p.wg.Wait()
+	if p.err != nil {
+		return nil, p.err
+	}
 	return mf, nil
 }
 
+// checkTree returns an error if a class contains nil entries or itself
+func checkTree(class *sqm.Class, parents map[*sqm.Class]bool) error {
+	for _, prop := range class.Props {
+		if prop == nil {
+			return fmt.Errorf("Class %s contains a nil property", class.Name)
+		}
+	}
+	for _, arrprop := range class.Arrprops {
+		if arrprop == nil {
+			return fmt.Errorf("Class %s contains a nil array property", class.Name)
+		}
+	}
+	parents[class] = true
+	defer delete(parents, class)
+	for _, subclass := range class.Classes {
+		if subclass == nil {
+			return fmt.Errorf("Class %s contains a nil class", class.Name)
+		}
+		if parents[subclass] {
+			return fmt.Errorf("Class %s contains its parent class %s", class.Name, subclass.Name)
+		}
+		if err := checkTree(subclass, parents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *Parser) Warnings() []error {
 	return p.errors
 }
 
+// saveError saves a warning according to the options, once the parser
+// failed all further warnings are dropped
 func (p *Parser) saveError(e error) {
-	p.errors = append(p.errors, e)
+	if p.err != nil || p.options.suppressed(warningContext(e)) {
+		return
+	}
+	if p.options.Strict {
+		switch e.(type) {
+		case *UnkownPropertyError, *UnkownClassError:
+			p.err = e
+			return
+		}
+	}
+	if p.options.MaxWarnings <= 0 || len(p.errors) < p.options.MaxWarnings {
+		p.errors = append(p.errors, e)
+	}
+	if p.options.OnWarning != nil {
+		p.options.OnWarning(e)
+	}
+}
+
+// parseNumber parses a number property and saves a warning if it is invalid
+func (p *Parser) parseNumber(class *sqm.Class, prop *sqm.Property, context Context) float64 {
+	f, err := parseNumber(prop.Value)
+	if err != nil {
+		p.saveError(&InvalidValueError{
+			ParentClass: class,
+			Property:    prop,
+			Context:     context,
+			Err:         err,
+		})
+	}
+	return f
+}
+
+// checkValue saves a warning if the value of an enum property is unknown
+func (p *Parser) checkValue(class *sqm.Class, prop *sqm.Property, context Context, valid bool) {
+	if !valid {
+		p.saveError(&InvalidValueError{
+			ParentClass: class,
+			Property:    prop,
+			Context:     context,
+			Err:         unknownValue(prop.Value),
+		})
+	}
+}
+
+// require saves a warning for every property missing in class
+func (p *Parser) require(class *sqm.Class, context Context, names ...string) {
+	for _, name := range names {
+		if !hasProperty(class, name) {
+			p.saveError(&MissingPropertyError{
+				ParentClass: class,
+				Name:        name,
+				Context:     context,
+			})
+		}
+	}
+}
+
+func hasProperty(class *sqm.Class, name string) bool {
+	for _, prop := range class.Props {
+		if prop.Name == name {
+			return true
+		}
+	}
+	for _, arrprop := range class.Arrprops {
+		if arrprop.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePosition parses a position array and saves a warning if it is invalid
+func (p *Parser) parsePosition(class *sqm.Class, arrprop *sqm.ArrayProperty, context Context) Vec3 {
+	v, err := parseVec3(arrprop.Values)
+	if err != nil {
+		p.saveError(&InvalidValueError{
+			ParentClass:   class,
+			ArrayProperty: arrprop,
+			Context:       context,
+			Err:           err,
+		})
+	}
+	return v
 }
 
 func (p *Parser) parseMission(class *sqm.Class, mission *Mission) {
+	p.stage = newStageRefs()
 	p.parseMissionProps(class, mission)
 	for _, baseClass := range class.Classes {
 		switch baseClass.Name {
@@ -144,6 +325,7 @@ func (p *Parser) parseMission(class *sqm.Class, mission *Mission) {
 		}
 
 	}
+	p.resolveRefs()
 }
 
 func (p *Parser) parseMissionProps(class *sqm.Class, mission *Mission) {
@@ -177,24 +359,45 @@ func (p *Parser) parseMissionProps(class *sqm.Class, mission *Mission) {
 
 func (p *Parser) parseIntel(class *sqm.Class, mission *Mission) {
 	intel := &Intel{}
+	w := &intel.Weather
 	for _, prop := range class.Props {
 		switch prop.Name {
+		case "briefingName":
+			intel.BriefingName = prop.Value
+		case "overviewText":
+			intel.OverviewText = prop.Value
 		case "resistanceWest":
 			intel.ResistanceWest = prop.Value == "1"
 		case "startWeather":
-			intel.StartWeather = prop.Value
+			w.StartWeather = Number(p.parseNumber(class, prop, ContextIntel))
 		case "forecastWeather":
-			intel.ForecastWeather = prop.Value
+			w.ForecastWeather = Number(p.parseNumber(class, prop, ContextIntel))
+		case "startFog":
+			w.StartFog = p.parseNumber(class, prop, ContextIntel)
+		case "forecastFog":
+			w.ForecastFog = p.parseNumber(class, prop, ContextIntel)
+		case "startRain":
+			w.StartRain = p.parseNumber(class, prop, ContextIntel)
+		case "startWind":
+			w.StartWind = p.parseNumber(class, prop, ContextIntel)
+		case "startGust":
+			w.StartGust = p.parseNumber(class, prop, ContextIntel)
+		case "startWaves":
+			w.StartWaves = p.parseNumber(class, prop, ContextIntel)
+		case "startLightnings":
+			w.StartLightnings = p.parseNumber(class, prop, ContextIntel)
+		case "timeOfChanges":
+			w.TimeOfChanges = p.parseNumber(class, prop, ContextIntel)
 		case "year":
-			intel.Year = prop.Value
+			intel.Year = int(p.parseNumber(class, prop, ContextIntel))
 		case "month":
-			intel.Month = prop.Value
+			intel.Month = int(p.parseNumber(class, prop, ContextIntel))
 		case "day":
-			intel.Day = prop.Value
+			intel.Day = int(p.parseNumber(class, prop, ContextIntel))
 		case "hour":
-			intel.Hour = prop.Value
+			intel.Hour = int(p.parseNumber(class, prop, ContextIntel))
 		case "minute":
-			intel.Minute = prop.Value
+			intel.Minute = int(p.parseNumber(class, prop, ContextIntel))
 		default:
 			p.saveError(&UnkownPropertyError{
 				ParentClass: class,
@@ -203,6 +406,20 @@ func (p *Parser) parseIntel(class *sqm.Class, mission *Mission) {
 			})
 		}
 	}
+	for _, err := range intel.Validate() {
+		var prop *sqm.Property
+		for _, pr := range class.Props {
+			if pr.Name == err.(*RangeError).Name {
+				prop = pr
+			}
+		}
+		p.saveError(&InvalidValueError{
+			ParentClass: class,
+			Property:    prop,
+			Context:     ContextIntel,
+			Err:         err,
+		})
+	}
 	mission.Intel = intel
 }
 
@@ -221,8 +438,13 @@ func (p *Parser) parseGroup(class *sqm.Class, group *Group) {
 	for _, prop := range class.Props {
 		switch prop.Name {
 		case "side":
-			group.Side = prop.Value
+			group.Side = Side(prop.Value)
+			p.checkValue(class, prop, ContextGroup, group.Side.Valid())
+		case "id":
+			group.ID = prop.Value
 		default:
+			// kept for round-tripping but still reported
+			group.ExtraProps = append(group.ExtraProps, prop)
 			p.saveError(&UnkownPropertyError{
 				ParentClass: class,
 				Property:    prop,
@@ -231,13 +453,25 @@ func (p *Parser) parseGroup(class *sqm.Class, group *Group) {
 		}
 
 	}
+	p.require(class, ContextGroup, "side")
+	for _, arrprop := range class.Arrprops {
+		group.ExtraArrprops = append(group.ExtraArrprops, arrprop)
+		p.saveError(&UnkownPropertyError{
+			ParentClass:   class,
+			ArrayProperty: arrprop,
+			Context:       ContextGroup,
+		})
+	}
+	hasVehicles := false
 	for _, subclass := range class.Classes {
 		switch subclass.Name {
 		case "Vehicles":
+			hasVehicles = true
 			p.parseGroupMembers(subclass, group)
 		case "Waypoints":
 			p.parseGroupWaypoints(subclass, group)
 		default:
+			group.ExtraClasses = append(group.ExtraClasses, subclass)
 			p.saveError(&UnkownClassError{
 				ParentClass: class,
 				Class:       subclass,
@@ -245,6 +479,13 @@ func (p *Parser) parseGroup(class *sqm.Class, group *Group) {
 			})
 		}
 	}
+	if !hasVehicles {
+		p.saveError(&MissingClassError{
+			ParentClass: class,
+			Name:        "Vehicles",
+			Context:     ContextGroup,
+		})
+	}
 }
 
 func (p *Parser) parseGroupWaypoints(class *sqm.Class, group *Group) {
@@ -256,12 +497,50 @@ func (p *Parser) parseGroupWaypoints(class *sqm.Class, group *Group) {
 }
 
 func (p *Parser) parseGroupWaypoint(class *sqm.Class, wp *Waypoint) {
+	p.require(class, ContextWaypoint, "position")
 	for _, prop := range class.Props {
 		switch prop.Name {
 		case "type":
-			wp.Type = prop.Value
+			wp.Type = WaypointType(prop.Value)
+			p.checkValue(class, prop, ContextWaypoint, wp.Type.Valid())
+		case "combatMode":
+			wp.CombatMode = CombatMode(prop.Value)
+			p.checkValue(class, prop, ContextWaypoint, wp.CombatMode.Valid())
+		case "formation":
+			wp.Formation = Formation(prop.Value)
+			p.checkValue(class, prop, ContextWaypoint, wp.Formation.Valid())
+		case "speed":
+			wp.Speed = Speed(prop.Value)
+			p.checkValue(class, prop, ContextWaypoint, wp.Speed.Valid())
+		case "combat":
+			wp.Combat = Behaviour(prop.Value)
+			p.checkValue(class, prop, ContextWaypoint, wp.Combat.Valid())
+		case "description":
+			wp.Description = prop.Value
+		case "expCond":
+			wp.Condition = prop.Value
+		case "expActiv":
+			wp.OnActivation = prop.Value
+		case "script":
+			wp.Script = prop.Value
+		case "timeoutMin":
+			wp.TimeoutMin = p.parseNumber(class, prop, ContextWaypoint)
+		case "timeoutMid":
+			wp.TimeoutMid = p.parseNumber(class, prop, ContextWaypoint)
+		case "timeoutMax":
+			wp.TimeoutMax = p.parseNumber(class, prop, ContextWaypoint)
+		case "placement":
+			wp.Placement = p.parseNumber(class, prop, ContextWaypoint)
+		case "completitionRadius":
+			wp.CompletionRadius = p.parseNumber(class, prop, ContextWaypoint)
 		case "showWP":
 			wp.ShowWP = prop.Value
+		case "idStatic":
+			wp.StaticID = prop.Value
+		case "housePos":
+			wp.HousePos = prop.Value
+		case "idObject":
+			p.refs().waypointVehicles = append(p.refs().waypointVehicles, waypointVehicleRef{wp, class, prop.Value})
 		default:
 			p.saveError(&UnkownPropertyError{
 				ParentClass: class,
@@ -273,9 +552,9 @@ func (p *Parser) parseGroupWaypoint(class *sqm.Class, wp *Waypoint) {
 	for _, arrprop := range class.Arrprops {
 		switch arrprop.Name {
 		case "position":
-			wp.Position = [3]string{arrprop.Values[0], arrprop.Values[1], arrprop.Values[2]}
+			wp.Position = p.parsePosition(class, arrprop, ContextWaypoint)
 		case "synchronizations":
-			wp.Synchronizations = arrprop.Values
+			p.refs().addSyncs(wp, class, arrprop.Values)
 		default:
 			p.saveError(&UnkownPropertyError{
 				ParentClass:   class,
@@ -311,20 +590,25 @@ func (p *Parser) parseGroupMembers(class *sqm.Class, group *Group) {
 }
 
 func (p *Parser) parseVehicle(class *sqm.Class, veh *Vehicle) {
+	p.require(class, ContextVehicle, "position", "vehicle")
 	for _, prop := range class.Props {
 		switch prop.Name {
 		case "id":
-			// autogenerated
+			// regenerated by the encoder
+			if _, found := p.refs().vehicles[prop.Value]; !found {
+				p.refs().vehicles[prop.Value] = veh
+			}
 		case "text":
 			veh.Name = prop.Value
 		case "vehicle":
 			veh.Classname = prop.Value
 		case "skill":
-			veh.Skill = prop.Value
+			veh.Skill = Number(p.parseNumber(class, prop, ContextVehicle))
 		case "azimut":
-			veh.Angle = prop.Value
+			veh.Angle = p.parseNumber(class, prop, ContextVehicle)
 		case "special":
-			veh.Special = prop.Value
+			veh.Special = Special(prop.Value)
+			p.checkValue(class, prop, ContextVehicle, veh.Special.Valid())
 		case "leader":
 			veh.IsLeader = prop.Value == "1"
 		case "player":
@@ -340,19 +624,22 @@ func (p *Parser) parseVehicle(class *sqm.Class, veh *Vehicle) {
 		case "age":
 			veh.Age = prop.Value
 		case "lock":
-			veh.Lock = prop.Value
+			veh.Lock = Lock(prop.Value)
+			p.checkValue(class, prop, ContextVehicle, veh.Lock.Valid())
 		case "rank":
-			veh.Rank = prop.Value
+			veh.Rank = Rank(prop.Value)
+			p.checkValue(class, prop, ContextVehicle, veh.Rank.Valid())
 		case "health":
-			veh.Health = prop.Value
+			veh.Health = Number(p.parseNumber(class, prop, ContextVehicle))
 		case "fuel":
-			veh.Fuel = prop.Value
+			veh.Fuel = Number(p.parseNumber(class, prop, ContextVehicle))
 		case "ammo":
-			veh.Ammo = prop.Value
+			veh.Ammo = Number(p.parseNumber(class, prop, ContextVehicle))
 		case "init":
 			veh.Init = prop.Value
 		case "side":
-			veh.Side = prop.Value
+			veh.Side = Side(prop.Value)
+			p.checkValue(class, prop, ContextVehicle, veh.Side.Valid())
 		case "forceHeadlessClient":
 			veh.ForceHeadlessClient = prop.Value == "1"
 		default:
@@ -366,9 +653,11 @@ func (p *Parser) parseVehicle(class *sqm.Class, veh *Vehicle) {
 	for _, arrprop := range class.Arrprops {
 		switch arrprop.Name {
 		case "position":
-			veh.Position = [3]string{arrprop.Values[0], arrprop.Values[1], arrprop.Values[2]}
+			veh.Position = p.parsePosition(class, arrprop, ContextVehicle)
 		case "markers":
-			veh.Markers = arrprop.Values[:]
+			for _, name := range arrprop.Values {
+				p.refs().vehicleMarkers = append(p.refs().vehicleMarkers, markerRef{veh, class, name})
+			}
 		default:
 			p.saveError(&UnkownPropertyError{
 				ParentClass:   class,
@@ -388,26 +677,31 @@ func (p *Parser) parseMarkers(class *sqm.Class, mission *Mission) {
 }
 
 func (p *Parser) parseMarker(c *sqm.Class, marker *Marker) {
+	p.require(c, ContextMarker, "position", "name")
 	for _, prop := range c.Props {
 		switch prop.Name {
 		case "name":
 			marker.Name = prop.Value
+			if _, found := p.refs().markers[prop.Value]; !found {
+				p.refs().markers[prop.Value] = marker
+			}
 		case "angle":
-			marker.Angle = prop.Value
+			marker.Angle = p.parseNumber(c, prop, ContextMarker)
 		case "text":
 			marker.Text = prop.Value
 		case "type":
 			marker.Type = prop.Value
 		case "markerType":
-			marker.MarkerType = prop.Value
+			marker.MarkerType = MarkerShape(prop.Value)
+			p.checkValue(c, prop, ContextMarker, marker.MarkerType.Valid())
 		case "colorName":
 			marker.ColorName = prop.Value
 		case "fillName":
 			marker.FillName = prop.Value
 		case "a":
-			marker.Size[0] = prop.Value
+			marker.Size.X = p.parseNumber(c, prop, ContextMarker)
 		case "b":
-			marker.Size[1] = prop.Value
+			marker.Size.Y = p.parseNumber(c, prop, ContextMarker)
 		case "drawBorder":
 			marker.DrawBorder = prop.Value == "1"
 		default:
@@ -421,7 +715,7 @@ func (p *Parser) parseMarker(c *sqm.Class, marker *Marker) {
 	for _, arrprop := range c.Arrprops {
 		switch arrprop.Name {
 		case "position":
-			marker.Position = [3]string{arrprop.Values[0], arrprop.Values[1], arrprop.Values[2]}
+			marker.Position = p.parsePosition(c, arrprop, ContextMarker)
 		default:
 			p.saveError(&UnkownPropertyError{
 				ParentClass:   c,
@@ -441,20 +735,22 @@ func (p *Parser) parseSensors(class *sqm.Class, mission *Mission) {
 }
 
 func (p *Parser) parseSensor(c *sqm.Class, sensor *Sensor) {
+	p.require(c, ContextSensor, "position")
 	for _, prop := range c.Props {
 		switch prop.Name {
 		case "name":
 			sensor.Name = prop.Value
 		case "a":
-			sensor.Size[0] = prop.Value
+			sensor.Size.X = p.parseNumber(c, prop, ContextSensor)
 		case "b":
-			sensor.Size[1] = prop.Value
+			sensor.Size.Y = p.parseNumber(c, prop, ContextSensor)
 		case "angle":
-			sensor.Angle = prop.Value
+			sensor.Angle = p.parseNumber(c, prop, ContextSensor)
 		case "rectangular":
 			sensor.IsRectangle = prop.Value == "1"
 		case "activationBy":
-			sensor.ActivationBy = prop.Value
+			sensor.ActivationBy = ActivationBy(prop.Value)
+			p.checkValue(c, prop, ContextSensor, sensor.ActivationBy.Valid())
 		case "activationType":
 			sensor.ActivationType = prop.Value
 		case "timeoutMin":
@@ -480,7 +776,7 @@ func (p *Parser) parseSensor(c *sqm.Class, sensor *Sensor) {
 		case "text":
 			sensor.Text = prop.Value
 		case "idVehicle":
-			sensor.VehicleID = prop.Value
+			p.refs().sensorVehicles = append(p.refs().sensorVehicles, vehicleRef{sensor, c, prop.Value})
 		default:
 			p.saveError(&UnkownPropertyError{
 				ParentClass: c,
@@ -492,9 +788,9 @@ func (p *Parser) parseSensor(c *sqm.Class, sensor *Sensor) {
 	for _, arrprop := range c.Arrprops {
 		switch arrprop.Name {
 		case "position":
-			sensor.Position = [3]string{arrprop.Values[0], arrprop.Values[1], arrprop.Values[2]}
+			sensor.Position = p.parsePosition(c, arrprop, ContextSensor)
 		case "synchronizations":
-			sensor.Synchronizations = arrprop.Values
+			p.refs().addSyncs(sensor, c, arrprop.Values)
 		default:
 			p.saveError(&UnkownPropertyError{
 				ParentClass:   c,
@@ -509,7 +805,7 @@ func (p *Parser) parseSensor(c *sqm.Class, sensor *Sensor) {
 			case "Effects":
 				effects := &Effects{}
 				sensor.Effects = effects
-				p.parseEffects(c.Classes[0], effects)
+				p.parseEffects(subclass, effects)
 			default:
 				p.saveError(&UnkownClassError{
 					ParentClass: c,
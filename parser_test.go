@@ -45,6 +45,16 @@ func TestParseIntel(t *testing.T) {
 				&sqm.Property{"day", sqm.TNumber, "28"},
 				&sqm.Property{"hour", sqm.TNumber, "6"},
 				&sqm.Property{"minute", sqm.TNumber, "5"},
+				&sqm.Property{"briefingName", sqm.TString, "Dawn"},
+				&sqm.Property{"overviewText", sqm.TString, "Overview"},
+				&sqm.Property{"startFog", sqm.TNumber, "0.1"},
+				&sqm.Property{"forecastFog", sqm.TNumber, "0.2"},
+				&sqm.Property{"startRain", sqm.TNumber, "0.4"},
+				&sqm.Property{"startWind", sqm.TNumber, "0.5"},
+				&sqm.Property{"startGust", sqm.TNumber, "0.6"},
+				&sqm.Property{"startWaves", sqm.TNumber, "0.7"},
+				&sqm.Property{"startLightnings", sqm.TNumber, "0.9"},
+				&sqm.Property{"timeOfChanges", sqm.TNumber, "1800"},
 			},
 		}
 		Convey("When parse intel", func() {
@@ -53,13 +63,37 @@ func TestParseIntel(t *testing.T) {
 			i := mission.Intel
 			Convey("All properties are correct", func() {
 				So(i.ResistanceWest, ShouldBeTrue)
-				So(i.StartWeather, ShouldEqual, "0.3")
-				So(i.ForecastWeather, ShouldEqual, "0.8")
-				So(i.Year, ShouldEqual, "2009")
-				So(i.Month, ShouldEqual, "10")
-				So(i.Day, ShouldEqual, "28")
-				So(i.Hour, ShouldEqual, "6")
-				So(i.Minute, ShouldEqual, "5")
+				So(i.BriefingName, ShouldEqual, "Dawn")
+				So(i.OverviewText, ShouldEqual, "Overview")
+				So(i.Weather, ShouldResemble, Weather{
+					StartWeather:    Number(0.3),
+					ForecastWeather: Number(0.8),
+					StartFog:        0.1,
+					ForecastFog:     0.2,
+					StartRain:       0.4,
+					StartWind:       0.5,
+					StartGust:       0.6,
+					StartWaves:      0.7,
+					StartLightnings: 0.9,
+					TimeOfChanges:   1800,
+				})
+				So(i.Year, ShouldEqual, 2009)
+				So(i.Month, ShouldEqual, 10)
+				So(i.Day, ShouldEqual, 28)
+				So(i.Hour, ShouldEqual, 6)
+				So(i.Minute, ShouldEqual, 5)
+				So(p.Warnings(), ShouldBeEmpty)
+			})
+		})
+		Convey("When parse intel with values out of range", func() {
+			intelclass.Props[1].Value = "1.5"
+			intelclass.Props[4].Value = "13"
+			mission := &Mission{}
+			p.parseIntel(intelclass, mission)
+			Convey("Warnings were saved", func() {
+				So(p.Warnings(), ShouldHaveLength, 2)
+				So(p.Warnings()[0].(*InvalidValueError).Property, ShouldEqual, intelclass.Props[4])
+				So(p.Warnings()[1].(*InvalidValueError).Property, ShouldEqual, intelclass.Props[1])
 			})
 		})
 	})
@@ -72,6 +106,7 @@ func TestParseGroups(t *testing.T) {
 			Name: "Item0",
 			Props: []*sqm.Property{
 				&sqm.Property{"text", sqm.TNumber, "name"},
+				&sqm.Property{"vehicle", sqm.TString, "USMC_Soldier"},
 			},
 
 			Arrprops: []*sqm.ArrayProperty{
@@ -95,6 +130,22 @@ func TestParseGroups(t *testing.T) {
 			Name: "Item0",
 			Props: []*sqm.Property{
 				&sqm.Property{"type", sqm.TString, "AND"},
+				&sqm.Property{"combatMode", sqm.TString, "YELLOW"},
+				&sqm.Property{"formation", sqm.TString, "STAG COLUMN"},
+				&sqm.Property{"speed", sqm.TString, "LIMITED"},
+				&sqm.Property{"combat", sqm.TString, "SAFE"},
+				&sqm.Property{"description", sqm.TString, "Patrol"},
+				&sqm.Property{"expCond", sqm.TString, "true"},
+				&sqm.Property{"expActiv", sqm.TString, "hint a"},
+				&sqm.Property{"script", sqm.TString, "patrol.sqf"},
+				&sqm.Property{"timeoutMin", sqm.TNumber, "1"},
+				&sqm.Property{"timeoutMid", sqm.TNumber, "2"},
+				&sqm.Property{"timeoutMax", sqm.TNumber, "3"},
+				&sqm.Property{"placement", sqm.TNumber, "50"},
+				&sqm.Property{"completitionRadius", sqm.TNumber, "25"},
+				&sqm.Property{"idStatic", sqm.TNumber, "12345"},
+				&sqm.Property{"housePos", sqm.TNumber, "2"},
+				&sqm.Property{"idObject", sqm.TNumber, "3"},
 				&sqm.Property{"showWP", sqm.TString, "NEVER"},
 			},
 			Arrprops: []*sqm.ArrayProperty{
@@ -122,10 +173,13 @@ func TestParseGroups(t *testing.T) {
 			Name: "Item0",
 			Props: []*sqm.Property{
 				&sqm.Property{"side", sqm.TString, "WEST"},
+				&sqm.Property{"id", sqm.TNumber, "3"},
+				&sqm.Property{"custom", sqm.TString, "value"},
 			},
 			Classes: []*sqm.Class{
 				groupvehiclesclass,
 				groupwaypointsclass,
+				&sqm.Class{Name: "Attributes"},
 			},
 		}
 		groupsclass := &sqm.Class{
@@ -144,7 +198,7 @@ func TestParseGroups(t *testing.T) {
 				So(len(mission.Groups[0].Units), ShouldEqual, 1)
 			})
 			Convey("Group should have right side", func() {
-				So(mission.Groups[0].Side, ShouldEqual, "WEST")
+				So(mission.Groups[0].Side, ShouldEqual, SideWest)
 			})
 
 		})
@@ -155,15 +209,59 @@ func TestParseGroups(t *testing.T) {
 			Convey("Group has one waypoint", func() {
 				So(len(group.Waypoints), ShouldEqual, 1)
 			})
+			Convey("Group attributes are kept", func() {
+				So(group.ID, ShouldEqual, "3")
+				So(group.ExtraProps, ShouldResemble, []*sqm.Property{&sqm.Property{"custom", sqm.TString, "value"}})
+				So(group.ExtraClasses, ShouldHaveLength, 1)
+				So(group.ExtraClasses[0].Name, ShouldEqual, "Attributes")
+			})
+			Convey("Unknown group attributes are still reported", func() {
+				So(p.Warnings(), ShouldHaveLength, 2)
+				So(p.Warnings()[0], ShouldHaveSameTypeAs, &UnkownPropertyError{})
+				So(p.Warnings()[1], ShouldHaveSameTypeAs, &UnkownClassError{})
+			})
 		})
 		Convey("When parse waypoint", func() {
 			wp := &Waypoint{}
 			p.parseGroupWaypoint(waypointclass, wp)
 			Convey("All properties are correct", func() {
-				So(wp.Position, ShouldResemble, [3]string{"1.0", "2.0", "3.0"})
-				So(wp.Synchronizations, ShouldResemble, []string{"1", "2"})
-				So(wp.Type, ShouldEqual, "AND")
+				So(wp.Position, ShouldResemble, Vec3{X: 1, Y: 3, Z: 2})
+				So(p.refs().syncOrder, ShouldResemble, []string{"1", "2"})
+				So(wp.Type, ShouldEqual, WaypointAnd)
+				So(wp.CombatMode, ShouldEqual, CombatModeYellow)
+				So(wp.Formation, ShouldEqual, FormationStagColumn)
+				So(wp.Speed, ShouldEqual, SpeedLimited)
+				So(wp.Combat, ShouldEqual, BehaviourSafe)
+				So(wp.Description, ShouldEqual, "Patrol")
+				So(wp.Condition, ShouldEqual, "true")
+				So(wp.OnActivation, ShouldEqual, "hint a")
+				So(wp.Script, ShouldEqual, "patrol.sqf")
+				So(wp.TimeoutMin, ShouldEqual, 1)
+				So(wp.TimeoutMid, ShouldEqual, 2)
+				So(wp.TimeoutMax, ShouldEqual, 3)
+				So(wp.Placement, ShouldEqual, 50)
+				So(wp.CompletionRadius, ShouldEqual, 25)
+				So(wp.StaticID, ShouldEqual, "12345")
+				So(wp.HousePos, ShouldEqual, "2")
+				So(p.refs().waypointVehicles[0].id, ShouldEqual, "3")
 				So(wp.ShowWP, ShouldEqual, "NEVER")
+				So(p.Warnings(), ShouldBeEmpty)
+			})
+			Convey("Unknown enum values are reported", func() {
+				waypointclass.Props[1].Value = "PINK"
+				p.parseGroupWaypoint(waypointclass, &Waypoint{})
+				So(p.Warnings(), ShouldHaveLength, 1)
+				So(p.Warnings()[0], ShouldHaveSameTypeAs, &InvalidValueError{})
+			})
+			Convey("Invalid numbers are reported", func() {
+				for _, prop := range waypointclass.Props {
+					if prop.Name == "timeoutMin" {
+						prop.Value = "soon"
+					}
+				}
+				p.parseGroupWaypoint(waypointclass, &Waypoint{})
+				So(p.Warnings(), ShouldHaveLength, 1)
+				So(p.Warnings()[0].(*InvalidValueError).Property.Name, ShouldEqual, "timeoutMin")
 			})
 			Convey("Effects was set", func() {
 				So(wp.Effects, ShouldNotBeNil)
@@ -222,29 +320,46 @@ func TestParseVehicle(t *testing.T) {
 			Convey("All properties are correct", func() {
 				So(veh.Name, ShouldEqual, "name")
 				So(veh.Classname, ShouldEqual, "classname")
-				So(veh.Angle, ShouldEqual, "12.3")
-				So(veh.Special, ShouldEqual, "FORM")
+				So(veh.Angle, ShouldEqual, 12.3)
+				So(veh.Special, ShouldEqual, SpecialForm)
 				So(veh.IsLeader, ShouldBeTrue)
-				So(veh.Skill, ShouldEqual, "0.60000002")
-				So(veh.Position, ShouldResemble, [3]string{"1.0", "2.0", "3.0"})
-				So(veh.Markers, ShouldResemble, []string{"a", "b"})
+				So(*veh.Skill, ShouldEqual, 0.60000002)
+				So(veh.Position, ShouldResemble, Vec3{X: 1, Y: 3, Z: 2})
+				So(p.refs().vehicleMarkers, ShouldHaveLength, 2)
 				So(veh.Player, ShouldEqual, "PLAYER COMMANDER")
 				So(veh.Description, ShouldEqual, "Description")
 				So(veh.Presence, ShouldEqual, "0.3")
 				So(veh.PresenceCond, ShouldEqual, "true")
 				So(veh.Placement, ShouldEqual, "20")
 				So(veh.Age, ShouldEqual, "5 MIN")
-				So(veh.Lock, ShouldEqual, "UNLOCKED")
-				So(veh.Rank, ShouldEqual, "CORPORAL")
-				So(veh.Health, ShouldEqual, "0.1")
-				So(veh.Fuel, ShouldEqual, "0.2")
-				So(veh.Ammo, ShouldEqual, "0.3")
+				So(veh.Lock, ShouldEqual, LockUnlocked)
+				So(veh.Rank, ShouldEqual, RankCorporal)
+				So(*veh.Health, ShouldEqual, 0.1)
+				So(*veh.Fuel, ShouldEqual, 0.2)
+				So(*veh.Ammo, ShouldEqual, 0.3)
 				So(veh.Init, ShouldEqual, "hint a")
-				So(veh.Side, ShouldEqual, "WEST")
+				So(veh.Side, ShouldEqual, SideWest)
 				So(veh.ForceHeadlessClient, ShouldBeTrue)
 			})
+			Convey("No warnings", func() {
+				So(p.Warnings(), ShouldBeEmpty)
+			})
+		})
+		Convey("When parse vehicle with invalid numbers", func() {
+			vehclass.Props[1] = &sqm.Property{"azimut", sqm.TNumber, "abc"}
+			vehclass.Arrprops[0] = &sqm.ArrayProperty{"position", sqm.TNumber, []string{"1.0", "2.0"}}
+			veh := &Vehicle{}
+			p.parseVehicle(vehclass, veh)
+			Convey("Values are zero", func() {
+				So(veh.Angle, ShouldEqual, 0)
+				So(veh.Position, ShouldResemble, Vec3{})
+			})
+			Convey("Warnings were saved", func() {
+				So(p.Warnings(), ShouldHaveLength, 2)
+				So(p.Warnings()[0], ShouldHaveSameTypeAs, &InvalidValueError{})
+				So(p.Warnings()[1], ShouldHaveSameTypeAs, &InvalidValueError{})
+			})
 		})
-
 	})
 }
 
@@ -290,14 +405,14 @@ func TestParseMarkers(t *testing.T) {
 			p.parseMarker(markerClass, m)
 			Convey("All properties are correct", func() {
 				So(m.Name, ShouldEqual, "m1")
-				So(m.Angle, ShouldEqual, "38.1")
-				So(m.MarkerType, ShouldEqual, "ELLIPSE")
+				So(m.Angle, ShouldEqual, 38.1)
+				So(m.MarkerType, ShouldEqual, MarkerShapeEllipse)
 				So(m.Type, ShouldEqual, "Empty")
 				So(m.ColorName, ShouldEqual, "ColorRed")
 				So(m.FillName, ShouldEqual, "Border")
-				So(m.Size, ShouldResemble, [2]string{"1000", "2000"})
+				So(m.Size, ShouldResemble, Vec2{1000, 2000})
 				So(m.DrawBorder, ShouldBeTrue)
-				So(m.Position, ShouldResemble, [3]string{"1.0", "2.0", "3.0"})
+				So(m.Position, ShouldResemble, Vec3{X: 1, Y: 3, Z: 2})
 			})
 		})
 	})
@@ -362,7 +477,7 @@ func TestParseSensors(t *testing.T) {
 				So(len(mission.Sensors), ShouldEqual, 1)
 			})
 			Convey("Marker has ActivationBy", func() {
-				So(mission.Sensors[0].ActivationBy, ShouldEqual, "ANY")
+				So(mission.Sensors[0].ActivationBy, ShouldEqual, ActivationAny)
 			})
 		})
 		Convey("When parse single sensor", func() {
@@ -370,15 +485,15 @@ func TestParseSensors(t *testing.T) {
 			p.parseSensor(sensorClass, s)
 			Convey("All properties are correct", func() {
 				So(s.Name, ShouldEqual, "s1")
-				So(s.Synchronizations, ShouldResemble, []string{"1", "2"})
-				So(s.Position, ShouldResemble, [3]string{"1.0", "2.0", "3.0"})
-				So(s.Size, ShouldResemble, [2]string{"1000", "2000"})
-				So(s.Angle, ShouldEqual, "38.8545")
+				So(p.refs().syncOrder, ShouldResemble, []string{"1", "2"})
+				So(s.Position, ShouldResemble, Vec3{X: 1, Y: 3, Z: 2})
+				So(s.Size, ShouldResemble, Vec2{1000, 2000})
+				So(s.Angle, ShouldEqual, 38.8545)
 				So(s.IsRectangle, ShouldBeTrue)
 				So(s.IsRepeating, ShouldBeTrue)
 				So(s.IsInterruptible, ShouldBeTrue)
 				So(s.Age, ShouldEqual, "UNKNOWN")
-				So(s.ActivationBy, ShouldEqual, "ANY")
+				So(s.ActivationBy, ShouldEqual, ActivationAny)
 				So(s.ActivationType, ShouldEqual, "GUER D")
 				So(s.TimeoutMin, ShouldEqual, "1")
 				So(s.TimeoutMid, ShouldEqual, "2")
@@ -388,7 +503,7 @@ func TestParseSensors(t *testing.T) {
 				So(s.OnActivation, ShouldEqual, "hint a1")
 				So(s.OnDeactivation, ShouldEqual, "hint a2")
 				So(s.Text, ShouldEqual, "triggertext")
-				So(s.VehicleID, ShouldEqual, "1")
+				So(p.refs().sensorVehicles[0].id, ShouldEqual, "1")
 			})
 			Convey("Effects should be set", func() {
 				So(s.Effects, ShouldNotBeNil)
@@ -1,55 +1,196 @@
+// Command parsetree prints the class tree of a mission file.
+//
+// Without -show the text output lists the vehicle and side of a class
+// without their names, as in "Item0 (3): USMC_Soldier WEST 12". With -show
+// the listed properties are printed as name=value, strings quoted.
+//
+// The exit code is 1 if the mission file cannot be parsed and 2 on invalid
+// flags or unreadable files.
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/blang/gosqm/sqm"
+	"io"
 	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
 )
 
 var (
 	missionFile  = flag.String("mission", "./mission.sqm", "mission.sqm")
 	indentSpaces = flag.Int("indent", 2, "Indent in spaces")
+	maxDepth     = flag.Int("depth", -1, "Maximum depth below the printed classes, -1 for all")
+	selectFlag   = flag.String("select", "", "Print only the classes matching the selector, e.g. Mission/Groups/*[side=WEST]")
+	showFlag     = flag.String("show", "", "Comma separated properties whose values are printed as name=value, vehicle and side by default")
+	formatFlag   = flag.String("format", "text", "Output format: text, json or yaml")
+	statsFlag    = flag.Bool("stats", false, "Print class and attribute counts of each subtree")
+	colorFlag    = flag.Bool("color", false, "Colorize text output")
 )
 
-func init() {
-	flag.Parse()
+// defaultShow are the properties shown without -show
+var defaultShow = []string{"vehicle", "side"}
+
+// ANSI colors of the text output
+const (
+	colorReset = "\x1b[0m"
+	colorName  = "\x1b[1;34m"
+	colorValue = "\x1b[32m"
+	colorCount = "\x1b[2m"
+)
+
+// options control which classes and values are printed and how
+type options struct {
+	indent   int
+	maxDepth int
+	show     []string
+	// legacy prints vehicle and side without names in the text output,
+	// like parsetree did before -show existed
+	legacy bool
+	stats  bool
+	color  bool
+}
+
+// node is a printed class
+type node struct {
+	Name       string     `json:"name"`
+	ID         string     `json:"id,omitempty"`
+	Path       string     `json:"path"`
+	Values     []*value   `json:"values,omitempty"`
+	Attributes int        `json:"attributes"`
+	Stats      *treeStats `json:"stats,omitempty"`
+	Classes    []*node    `json:"classes,omitempty"`
+}
+
+// value is a shown property, strings are quoted like in the mission file
+type value struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	raw   string
+}
+
+// treeStats are the counts of a subtree including its root
+type treeStats struct {
+	Classes    int `json:"classes"`
+	Attributes int `json:"attributes"`
+	Depth      int `json:"depth"`
+}
+
+// writer writes the printed classes in one output format
+type writer func(w io.Writer, nodes []*node, opts options) error
+
+var writers = map[string]writer{
+	"text": writeText,
+	"json": writeJSON,
+	"yaml": writeYAML,
 }
 
 func main() {
+	flag.Parse()
+	opts := options{
+		indent:   *indentSpaces,
+		maxDepth: *maxDepth,
+		show:     defaultShow,
+		legacy:   true,
+		stats:    *statsFlag,
+		color:    *colorFlag,
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "show" {
+			opts.show = parseShow(*showFlag)
+			opts.legacy = false
+		}
+	})
+	write, found := writers[*formatFlag]
+	if !found {
+		fmt.Fprintf(os.Stderr, "Unknown format %q\n", *formatFlag)
+		os.Exit(2)
+	}
+
 	buf, err := ioutil.ReadFile(*missionFile)
 	if err != nil {
-		fmt.Printf("Could not open file: %s", err.Error())
-		return
+		fmt.Fprintf(os.Stderr, "Could not open file: %s\n", err)
+		os.Exit(2)
 	}
 	p := sqm.MakeParser(string(buf))
-	// c, perr := p.run()
 	class, perr := p.Run()
 	if perr != nil {
-		fmt.Printf("Parser returned with error %q", perr)
+		fmt.Fprintf(os.Stderr, "Parser returned with error %q\n", perr)
+		os.Exit(1)
+	}
+
+	nodes, err := buildTree(class, *selectFlag, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid selector %q: %s\n", *selectFlag, err)
+		os.Exit(2)
+	}
+	w := bufio.NewWriter(os.Stdout)
+	err = write(w, nodes, opts)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write output: %s\n", err)
+		os.Exit(2)
 	}
+}
 
-	printTreePart(class, 0)
+// parseShow splits the comma separated -show list
+func parseShow(list string) []string {
+	var show []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			show = append(show, name)
+		}
+	}
+	return show
 }
 
-func printTreePart(class *sqm.Class, level int) {
-	classname, side := parseVehicle(class)
-	id, found := parseId(class)
-	var name string
-	if found {
-		name = class.Name + " (" + id + ")"
-	} else {
-		name = class.Name
+// buildTree returns the printed classes, the classes matching selector or
+// class itself if selector is empty
+func buildTree(class *sqm.Class, selector string, opts options) ([]*node, error) {
+	roots := []*sqm.Class{class}
+	if selector != "" {
+		sel, err := sqm.ParseSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		roots = sel.Select(class)
 	}
-	if classname != "" {
-		fmt.Printf("%s%s: %s %s %d\n", indent(level), name, classname, side, countAttributes(class))
-	} else {
-		fmt.Printf("%s%s: %d\n", indent(level), name, countAttributes(class))
+	nodes := make([]*node, len(roots))
+	for i, root := range roots {
+		nodes[i] = makeNode(root, opts, 0)
 	}
-	for _, subclass := range class.Classes {
-		printTreePart(subclass, level+1)
+	return nodes, nil
+}
+
+// makeNode converts a class and its subclasses up to the maximum depth
+func makeNode(class *sqm.Class, opts options, depth int) *node {
+	n := &node{
+		Name:       class.Name,
+		Path:       class.Path(),
+		Attributes: countAttributes(class),
+	}
+	n.ID, _ = parseId(class)
+	for _, name := range opts.show {
+		if v, raw, found := propertyValue(class, name); found {
+			n.Values = append(n.Values, &value{name, v, raw})
+		}
 	}
+	if opts.stats {
+		n.Stats = subtreeStats(class)
+	}
+	if opts.maxDepth < 0 || depth < opts.maxDepth {
+		for _, subclass := range class.Classes {
+			n.Classes = append(n.Classes, makeNode(subclass, opts, depth+1))
+		}
+	}
+	return n
 }
 
 func countAttributes(class *sqm.Class) int {
@@ -59,17 +200,47 @@ func countAttributes(class *sqm.Class) int {
 	return i
 }
 
-func parseVehicle(class *sqm.Class) (classname string, side string) {
-	for _, prop := range class.Props {
-		switch prop.Name {
-		case "vehicle":
-			classname = prop.Value
+// subtreeStats counts the classes and attributes below and including class
+func subtreeStats(class *sqm.Class) *treeStats {
+	s := &treeStats{Classes: 1, Attributes: countAttributes(class)}
+	for _, subclass := range class.Classes {
+		sub := subtreeStats(subclass)
+		s.Classes += sub.Classes
+		s.Attributes += sub.Attributes
+		if sub.Depth+1 > s.Depth {
+			s.Depth = sub.Depth + 1
+		}
+	}
+	return s
+}
 
-		case "side":
-			side = prop.Value
+// propertyValue returns a property or array property formatted like in the
+// mission file and the unquoted value of a property, names are case
+// insensitive
+func propertyValue(class *sqm.Class, name string) (string, string, bool) {
+	for _, prop := range class.Props {
+		if strings.EqualFold(prop.Name, name) {
+			return formatValue(prop.Typ, prop.Value), prop.Value, true
+		}
+	}
+	for _, prop := range class.Arrprops {
+		if strings.EqualFold(prop.Name, name) {
+			values := make([]string, len(prop.Values))
+			for i, v := range prop.Values {
+				values[i] = formatValue(prop.Typ, v)
+			}
+			list := "{" + strings.Join(values, ",") + "}"
+			return list, list, true
 		}
 	}
-	return
+	return "", "", false
+}
+
+func formatValue(typ sqm.PropType, v string) string {
+	if typ == sqm.TString {
+		return `"` + v + `"`
+	}
+	return v
 }
 
 func parseId(class *sqm.Class) (string, bool) {
@@ -82,10 +253,127 @@ func parseId(class *sqm.Class) (string, bool) {
 	return "", false
 }
 
-func indent(level int) string {
+// writeText writes one line per class with the class name, id, shown values
+// and attribute count, subclasses are indented
+func writeText(w io.Writer, nodes []*node, opts options) error {
+	for _, n := range nodes {
+		if err := writeTextNode(w, n, opts, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTextNode(w io.Writer, n *node, opts options, level int) error {
+	name := n.Name
+	if n.ID != "" {
+		name += " (" + n.ID + ")"
+	}
+	line := indent(level, opts.indent) + opts.colorize(colorName, name) + ":"
+	if opts.legacy {
+		line += legacyValues(n, opts)
+	} else {
+		for _, v := range n.Values {
+			line += " " + v.Name + "=" + opts.colorize(colorValue, v.Value)
+		}
+	}
+	counts := strconv.Itoa(n.Attributes)
+	if n.Stats != nil {
+		counts += fmt.Sprintf(" [classes %d, attributes %d, depth %d]", n.Stats.Classes, n.Stats.Attributes, n.Stats.Depth)
+	}
+	line += " " + opts.colorize(colorCount, counts)
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+	for _, sub := range n.Classes {
+		if err := writeTextNode(w, sub, opts, level+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyValues formats vehicle and side as " USMC_Soldier WEST", or
+// nothing if the class has no vehicle
+func legacyValues(n *node, opts options) string {
+	var vehicle, side string
+	found := false
+	for _, v := range n.Values {
+		switch v.Name {
+		case "vehicle":
+			vehicle, found = v.raw, true
+		case "side":
+			side = v.raw
+		}
+	}
+	if !found {
+		return ""
+	}
+	return " " + opts.colorize(colorValue, vehicle) + " " + opts.colorize(colorValue, side)
+}
+
+// colorize wraps s in an ANSI color if color output is enabled
+func (opts options) colorize(color, s string) string {
+	if !opts.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func writeJSON(w io.Writer, nodes []*node, opts options) error {
+	data, err := json.MarshalIndent(nodes, "", strings.Repeat(" ", opts.indent))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// writeYAML writes the nodes as YAML sequence indented by two spaces,
+// strings are double quoted which YAML reads like JSON strings
+func writeYAML(w io.Writer, nodes []*node, opts options) error {
+	if len(nodes) == 0 {
+		_, err := fmt.Fprintln(w, "[]")
+		return err
+	}
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		writeYAMLNode(&buf, n, 0)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeYAMLNode(buf *bytes.Buffer, n *node, level int) {
+	prefix := strings.Repeat("  ", level)
+	fmt.Fprintf(buf, "%s- name: %s\n", prefix, strconv.Quote(n.Name))
+	prefix += "  "
+	if n.ID != "" {
+		fmt.Fprintf(buf, "%sid: %s\n", prefix, strconv.Quote(n.ID))
+	}
+	fmt.Fprintf(buf, "%spath: %s\n", prefix, strconv.Quote(n.Path))
+	if len(n.Values) > 0 {
+		fmt.Fprintf(buf, "%svalues:\n", prefix)
+		for _, v := range n.Values {
+			fmt.Fprintf(buf, "%s  - name: %s\n%s    value: %s\n", prefix, strconv.Quote(v.Name), prefix, strconv.Quote(v.Value))
+		}
+	}
+	fmt.Fprintf(buf, "%sattributes: %d\n", prefix, n.Attributes)
+	if n.Stats != nil {
+		fmt.Fprintf(buf, "%sstats: {classes: %d, attributes: %d, depth: %d}\n", prefix, n.Stats.Classes, n.Stats.Attributes, n.Stats.Depth)
+	}
+	if len(n.Classes) > 0 {
+		fmt.Fprintf(buf, "%sclasses:\n", prefix)
+		for _, sub := range n.Classes {
+			writeYAMLNode(buf, sub, level+1)
+		}
+	}
+}
+
+func indent(level, spaces int) string {
 	var buffer bytes.Buffer
 
-	for i := 0; i < level*(*indentSpaces); i++ {
+	for i := 0; i < level*spaces; i++ {
 		buffer.WriteString(" ")
 	}
 
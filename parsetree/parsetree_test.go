@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/blang/gosqm/sqm"
+	. "github.com/smartystreets/goconvey/convey"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testMission = `version=11;
+class Mission
+{
+	class Groups
+	{
+		items=2;
+		class Item0
+		{
+			side="WEST";
+			class Vehicles
+			{
+				items=1;
+				class Item0
+				{
+					id=3;
+					vehicle="USMC_Soldier";
+					side="WEST";
+					skill=0.6;
+				};
+			};
+		};
+		class Item1
+		{
+			side="EAST";
+		};
+	};
+};
+`
+
+func parseTestMission() *sqm.Class {
+	class, err := sqm.MakeParser(testMission).Run()
+	if err != nil {
+		panic(err)
+	}
+	return class
+}
+
+func defaultOptions() options {
+	return options{indent: 2, maxDepth: -1, show: defaultShow, legacy: true}
+}
+
+func text(nodes []*node, opts options) string {
+	var buf bytes.Buffer
+	So(writeText(&buf, nodes, opts), ShouldBeNil)
+	return buf.String()
+}
+
+func TestBuildTree(t *testing.T) {
+	Convey("Given a mission", t, func() {
+		class := parseTestMission()
+
+		Convey("The default text output prints vehicle and side without names", func() {
+			nodes, err := buildTree(class, "", defaultOptions())
+			So(err, ShouldBeNil)
+			So(text(nodes, defaultOptions()), ShouldEqual, `mission: 1
+  Mission: 0
+    Groups: 1
+      Item0: 1
+        Vehicles: 1
+          Item0 (3): USMC_Soldier WEST 4
+      Item1: 1
+`)
+		})
+		Convey("Depth limits the printed subclasses", func() {
+			opts := defaultOptions()
+			opts.maxDepth = 1
+			nodes, _ := buildTree(class, "", opts)
+			So(text(nodes, opts), ShouldEqual, "mission: 1\n  Mission: 0\n")
+			opts.maxDepth = 0
+			nodes, _ = buildTree(class, "", opts)
+			So(nodes[0].Classes, ShouldBeEmpty)
+		})
+		Convey("The selector picks the printed classes", func() {
+			opts := defaultOptions()
+			opts.maxDepth = 0
+			nodes, err := buildTree(class, "Mission/Groups/*[side=EAST]", opts)
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 1)
+			So(nodes[0].Path, ShouldEqual, "Mission/Groups/Item1")
+			_, err = buildTree(class, "Mission/[", opts)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Shown properties are printed with their names", func() {
+			opts := defaultOptions()
+			opts.show = parseShow("vehicle, skill,missing")
+			opts.legacy = false
+			nodes, _ := buildTree(class, "Mission/Groups/Item0/Vehicles/Item0", opts)
+			So(text(nodes, opts), ShouldEqual, "Item0 (3): vehicle=\"USMC_Soldier\" skill=0.6 4\n")
+		})
+		Convey("Stats count the subtree", func() {
+			opts := defaultOptions()
+			opts.stats = true
+			nodes, _ := buildTree(class, "Mission/Groups", opts)
+			So(*nodes[0].Stats, ShouldResemble, treeStats{5, 8, 3})
+		})
+	})
+}
+
+func TestWriteYAML(t *testing.T) {
+	Convey("Given the tree of a mission", t, func() {
+		opts := defaultOptions()
+		opts.stats = true
+		opts.show = []string{"vehicle", "side", "skill"}
+		nodes, _ := buildTree(parseTestMission(), "", opts)
+		nodes[0].Values = []*value{&value{"quote", `"a \ b"`, ""}}
+
+		Convey("The YAML output reads back like the JSON output", func() {
+			var yaml, js bytes.Buffer
+			So(writeYAML(&yaml, nodes, opts), ShouldBeNil)
+			So(writeJSON(&js, nodes, opts), ShouldBeNil)
+			var expected interface{}
+			So(json.Unmarshal(js.Bytes(), &expected), ShouldBeNil)
+			So(readYAML(yaml.String()), ShouldResemble, expected)
+		})
+		Convey("No classes are an empty sequence", func() {
+			var yaml bytes.Buffer
+			So(writeYAML(&yaml, nil, opts), ShouldBeNil)
+			So(readYAML(yaml.String()), ShouldResemble, []interface{}{})
+		})
+	})
+}
+
+// readYAML reads the subset of YAML written by writeYAML: block sequences
+// of mappings, double quoted strings, numbers and flow mappings
+func readYAML(s string) interface{} {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if lines[0] == "[]" {
+		return []interface{}{}
+	}
+	seq, _ := readYAMLSequence(lines, 0, 0)
+	return seq
+}
+
+func readYAMLSequence(lines []string, i, indent int) ([]interface{}, int) {
+	seq := []interface{}{}
+	for i < len(lines) && indentOf(lines[i]) == indent && strings.HasPrefix(lines[i][indent:], "- ") {
+		lines[i] = strings.Repeat(" ", indent+2) + lines[i][indent+2:]
+		var m map[string]interface{}
+		m, i = readYAMLMapping(lines, i, indent+2)
+		seq = append(seq, m)
+	}
+	return seq, i
+}
+
+func readYAMLMapping(lines []string, i, indent int) (map[string]interface{}, int) {
+	m := make(map[string]interface{})
+	for i < len(lines) && indentOf(lines[i]) == indent && !strings.HasPrefix(lines[i][indent:], "- ") {
+		parts := strings.SplitN(lines[i][indent:], ":", 2)
+		key, val := parts[0], strings.TrimSpace(parts[1])
+		i++
+		if val == "" {
+			m[key], i = readYAMLSequence(lines, i, indentOf(lines[i]))
+		} else {
+			m[key] = readYAMLScalar(val)
+		}
+	}
+	return m, i
+}
+
+func readYAMLScalar(s string) interface{} {
+	if strings.HasPrefix(s, `"`) {
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			panic(err)
+		}
+		return v
+	}
+	if strings.HasPrefix(s, "{") {
+		m := make(map[string]interface{})
+		for _, pair := range strings.Split(strings.Trim(s, "{}"), ", ") {
+			parts := strings.SplitN(pair, ": ", 2)
+			m[parts[0]] = readYAMLScalar(parts[1])
+		}
+		return m
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -0,0 +1,399 @@
+package gosqm
+
+import (
+	"github.com/blang/gosqm/sqm"
+	"strconv"
+)
+
+// DanglingReferenceError is a reference to an entity which does not exist
+// in the same stage. Ref is the id or name of the missing entity if known.
+// ParentClass is nil if the reference was found while encoding.
+type DanglingReferenceError struct {
+	ParentClass *sqm.Class
+	Context     Context
+	Target      Context
+	Ref         string
+}
+
+func (e *DanglingReferenceError) Error() string {
+	msg := "Dangling reference to " + e.Target.String()
+	if e.Ref != "" {
+		msg += " " + e.Ref
+	}
+	if e.ParentClass != nil {
+		msg += " in class " + e.ParentClass.Name
+	}
+	return msg + " in context " + e.Context.String()
+}
+
+// SyncWaypoint synchronizes the waypoint with another waypoint.
+func (w *Waypoint) SyncWaypoint(o *Waypoint) {
+	if !containsWaypoint(w.SyncWaypoints, o) {
+		w.SyncWaypoints = append(w.SyncWaypoints, o)
+	}
+	if !containsWaypoint(o.SyncWaypoints, w) {
+		o.SyncWaypoints = append(o.SyncWaypoints, w)
+	}
+}
+
+// SyncSensor synchronizes the waypoint with a sensor.
+func (w *Waypoint) SyncSensor(s *Sensor) {
+	if !containsSensor(w.SyncSensors, s) {
+		w.SyncSensors = append(w.SyncSensors, s)
+	}
+	if !containsWaypoint(s.SyncWaypoints, w) {
+		s.SyncWaypoints = append(s.SyncWaypoints, w)
+	}
+}
+
+// SyncSensor synchronizes the sensor with another sensor.
+func (s *Sensor) SyncSensor(o *Sensor) {
+	if !containsSensor(s.SyncSensors, o) {
+		s.SyncSensors = append(s.SyncSensors, o)
+	}
+	if !containsSensor(o.SyncSensors, s) {
+		o.SyncSensors = append(o.SyncSensors, s)
+	}
+}
+
+// SyncWaypoint synchronizes the sensor with a waypoint.
+func (s *Sensor) SyncWaypoint(w *Waypoint) {
+	w.SyncSensor(s)
+}
+
+// Unsync removes all synchronizations of the waypoint on both sides.
+func (w *Waypoint) Unsync() {
+	for _, o := range w.SyncWaypoints {
+		o.SyncWaypoints = removeWaypoint(o.SyncWaypoints, w)
+	}
+	for _, s := range w.SyncSensors {
+		s.SyncWaypoints = removeWaypoint(s.SyncWaypoints, w)
+	}
+	w.SyncWaypoints = nil
+	w.SyncSensors = nil
+}
+
+// Unsync removes all synchronizations of the sensor on both sides.
+func (s *Sensor) Unsync() {
+	for _, w := range s.SyncWaypoints {
+		w.SyncSensors = removeSensor(w.SyncSensors, s)
+	}
+	for _, o := range s.SyncSensors {
+		o.SyncSensors = removeSensor(o.SyncSensors, s)
+	}
+	s.SyncWaypoints = nil
+	s.SyncSensors = nil
+}
+
+func removeWaypoint(list []*Waypoint, w *Waypoint) []*Waypoint {
+	var res []*Waypoint
+	for _, e := range list {
+		if e != w {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func removeSensor(list []*Sensor, s *Sensor) []*Sensor {
+	var res []*Sensor
+	for _, e := range list {
+		if e != s {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func containsWaypoint(list []*Waypoint, w *Waypoint) bool {
+	for _, e := range list {
+		if e == w {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSensor(list []*Sensor, s *Sensor) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// DanglingReferences returns an error for every reference of the mission
+// to a vehicle, marker, waypoint or sensor which is not part of it.
+// The Encoder omits these references.
+func (m *Mission) DanglingReferences() []error {
+	_, errs := buildStageIDs(m)
+	return errs
+}
+
+// allVehicles returns the group members followed by the empty vehicles
+func (m *Mission) allVehicles() []*Vehicle {
+	var vehicles []*Vehicle
+	for _, g := range m.Groups {
+		vehicles = append(vehicles, g.Units...)
+	}
+	return append(vehicles, m.Vehicles...)
+}
+
+// stageIDs holds the ids the encoder assigns to the entities of a stage
+type stageIDs struct {
+	vehicles map[*Vehicle]int
+	markers  map[*Marker]bool
+	syncs    map[interface{}][]string
+}
+
+// buildStageIDs numbers the vehicles of a mission like the editor does,
+// group members first, and assigns a synchronization id to every linked
+// pair of waypoints and sensors
+func buildStageIDs(m *Mission) (*stageIDs, []error) {
+	ids := &stageIDs{
+		vehicles: make(map[*Vehicle]int),
+		markers:  make(map[*Marker]bool),
+		syncs:    make(map[interface{}][]string),
+	}
+	var errs []error
+
+	vehicles := m.allVehicles()
+	var waypoints []*Waypoint
+	for _, g := range m.Groups {
+		waypoints = append(waypoints, g.Waypoints...)
+	}
+	for i, v := range vehicles {
+		if _, found := ids.vehicles[v]; !found {
+			ids.vehicles[v] = i
+		}
+	}
+	for _, marker := range m.Markers {
+		ids.markers[marker] = true
+	}
+	for _, v := range vehicles {
+		for _, marker := range v.Markers {
+			if !ids.markers[marker] {
+				errs = append(errs, &DanglingReferenceError{Context: ContextVehicle, Target: ContextMarker, Ref: markerName(marker)})
+			}
+		}
+	}
+	for _, s := range m.Sensors {
+		if _, found := ids.vehicles[s.Vehicle]; s.Vehicle != nil && !found {
+			errs = append(errs, &DanglingReferenceError{Context: ContextSensor, Target: ContextVehicle, Ref: s.Vehicle.Name})
+		}
+	}
+	for _, w := range waypoints {
+		if _, found := ids.vehicles[w.Vehicle]; w.Vehicle != nil && !found {
+			errs = append(errs, &DanglingReferenceError{Context: ContextWaypoint, Target: ContextVehicle, Ref: w.Vehicle.Name})
+		}
+	}
+
+	// index of every synchronizable entity, waypoints first
+	index := make(map[interface{}]int)
+	var members []interface{}
+	for _, w := range waypoints {
+		index[w] = len(members)
+		members = append(members, w)
+	}
+	for _, s := range m.Sensors {
+		index[s] = len(members)
+		members = append(members, s)
+	}
+	pairs := make(map[[2]int]bool)
+	next := 0
+	link := func(a interface{}, b interface{}, context, target Context) {
+		j, found := index[b]
+		if !found {
+			errs = append(errs, &DanglingReferenceError{Context: context, Target: target})
+			return
+		}
+		i := index[a]
+		if i == j {
+			return
+		}
+		pair := [2]int{i, j}
+		if j < i {
+			pair = [2]int{j, i}
+		}
+		if pairs[pair] {
+			return
+		}
+		pairs[pair] = true
+		id := strconv.Itoa(next)
+		next++
+		ids.syncs[a] = append(ids.syncs[a], id)
+		ids.syncs[b] = append(ids.syncs[b], id)
+	}
+	for _, member := range members {
+		switch e := member.(type) {
+		case *Waypoint:
+			for _, w := range e.SyncWaypoints {
+				link(e, w, ContextWaypoint, ContextWaypoint)
+			}
+			for _, s := range e.SyncSensors {
+				link(e, s, ContextWaypoint, ContextSensor)
+			}
+		case *Sensor:
+			for _, w := range e.SyncWaypoints {
+				link(e, w, ContextSensor, ContextWaypoint)
+			}
+			for _, s := range e.SyncSensors {
+				link(e, s, ContextSensor, ContextSensor)
+			}
+		}
+	}
+	return ids, errs
+}
+
+func markerName(m *Marker) string {
+	if m == nil {
+		return ""
+	}
+	return m.Name
+}
+
+// markerNames returns the names of the markers known to the stage
+func (ids *stageIDs) markerNames(markers []*Marker) []string {
+	var names []string
+	for _, m := range markers {
+		if ids.markers[m] {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
+
+// vehicleID returns the id of a vehicle, or an empty string if it is not
+// part of the stage
+func (ids *stageIDs) vehicleID(v *Vehicle) string {
+	if id, found := ids.vehicles[v]; found && v != nil {
+		return strconv.Itoa(id)
+	}
+	return ""
+}
+
+// stageRefs collects the ids and names of the entities of a stage while
+// parsing and the references to resolve once the stage is complete
+type stageRefs struct {
+	vehicles         map[string]*Vehicle
+	markers          map[string]*Marker
+	syncs            map[string][]syncRef
+	syncOrder        []string
+	vehicleMarkers   []markerRef
+	sensorVehicles   []vehicleRef
+	waypointVehicles []waypointVehicleRef
+}
+
+type syncRef struct {
+	entity interface{}
+	class  *sqm.Class
+}
+
+type markerRef struct {
+	vehicle *Vehicle
+	class   *sqm.Class
+	name    string
+}
+
+type vehicleRef struct {
+	sensor *Sensor
+	class  *sqm.Class
+	id     string
+}
+
+type waypointVehicleRef struct {
+	waypoint *Waypoint
+	class    *sqm.Class
+	id       string
+}
+
+func newStageRefs() *stageRefs {
+	return &stageRefs{
+		vehicles: make(map[string]*Vehicle),
+		markers:  make(map[string]*Marker),
+		syncs:    make(map[string][]syncRef),
+	}
+}
+
+func (r *stageRefs) addSyncs(entity interface{}, class *sqm.Class, ids []string) {
+	for _, id := range ids {
+		if _, found := r.syncs[id]; !found {
+			r.syncOrder = append(r.syncOrder, id)
+		}
+		r.syncs[id] = append(r.syncs[id], syncRef{entity, class})
+	}
+}
+
+// refs returns the references of the stage being parsed
+func (p *Parser) refs() *stageRefs {
+	if p.stage == nil {
+		p.stage = newStageRefs()
+	}
+	return p.stage
+}
+
+// resolveRefs links the references of the stage being parsed and saves a
+// warning for every dangling one
+func (p *Parser) resolveRefs() {
+	r := p.refs()
+	p.stage = nil
+
+	for _, ref := range r.vehicleMarkers {
+		if m, found := r.markers[ref.name]; found {
+			ref.vehicle.Markers = append(ref.vehicle.Markers, m)
+		} else {
+			p.saveError(&DanglingReferenceError{ParentClass: ref.class, Context: ContextVehicle, Target: ContextMarker, Ref: ref.name})
+		}
+	}
+	for _, ref := range r.sensorVehicles {
+		if v, found := r.vehicles[ref.id]; found {
+			ref.sensor.Vehicle = v
+		} else {
+			p.saveError(&DanglingReferenceError{ParentClass: ref.class, Context: ContextSensor, Target: ContextVehicle, Ref: ref.id})
+		}
+	}
+	for _, ref := range r.waypointVehicles {
+		if v, found := r.vehicles[ref.id]; found {
+			ref.waypoint.Vehicle = v
+		} else {
+			p.saveError(&DanglingReferenceError{ParentClass: ref.class, Context: ContextWaypoint, Target: ContextVehicle, Ref: ref.id})
+		}
+	}
+	for _, id := range r.syncOrder {
+		members := r.syncs[id]
+		if len(members) < 2 {
+			ref := members[0]
+			var context Context = ContextWaypoint
+			if _, ok := ref.entity.(*Sensor); ok {
+				context = ContextSensor
+			}
+			p.saveError(&DanglingReferenceError{ParentClass: ref.class, Context: context, Target: ContextSynchronization, Ref: id})
+			continue
+		}
+		for i, a := range members {
+			for _, b := range members[i+1:] {
+				syncEntities(a.entity, b.entity)
+			}
+		}
+	}
+}
+
+func syncEntities(a, b interface{}) {
+	switch e := a.(type) {
+	case *Waypoint:
+		switch o := b.(type) {
+		case *Waypoint:
+			e.SyncWaypoint(o)
+		case *Sensor:
+			e.SyncSensor(o)
+		}
+	case *Sensor:
+		switch o := b.(type) {
+		case *Waypoint:
+			e.SyncWaypoint(o)
+		case *Sensor:
+			e.SyncSensor(o)
+		}
+	}
+}
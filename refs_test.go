@@ -0,0 +1,140 @@
+package gosqm
+
+import (
+	"github.com/blang/gosqm/sqm"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+const refsMission = `version=11;
+class Mission
+{
+	class Intel {};
+	class Groups
+	{
+		items=1;
+		class Item0
+		{
+			side="WEST";
+			class Vehicles
+			{
+				items=1;
+				class Item0 { position[]={1,2,3}; id=4; side="WEST"; vehicle="USMC_Soldier"; markers[]={"m1"}; };
+			};
+			class Waypoints
+			{
+				items=2;
+				class Item0 { position[]={1,2,3}; synchronizations[]={7}; };
+				class Item1 { position[]={1,2,3}; synchronizations[]={8}; };
+			};
+		};
+	};
+	class Vehicles
+	{
+		items=1;
+		class Item0 { position[]={1,2,3}; id=9; side="EMPTY"; vehicle="HMMWV"; markers[]={"missing"}; };
+	};
+	class Markers
+	{
+		items=1;
+		class Item0 { position[]={1,2,3}; name="m1"; type="Empty"; };
+	};
+	class Sensors
+	{
+		items=1;
+		class Item0 { position[]={1,2,3}; activationBy="ANY"; synchronizations[]={7}; idVehicle=9; };
+	};
+};
+class Intro { class Intel {}; };
+class OutroWin { class Intel {}; };
+class OutroLoose { class Intel {}; };
+`
+
+func TestParseReferences(t *testing.T) {
+	Convey("Given a mission with references", t, func() {
+		p := NewParser()
+		mf, err := p.Parse(parseClass(refsMission))
+		So(err, ShouldBeNil)
+		m := mf.Mission
+		unit := m.Groups[0].Units[0]
+		wp0 := m.Groups[0].Waypoints[0]
+		wp1 := m.Groups[0].Waypoints[1]
+		sensor := m.Sensors[0]
+
+		Convey("References are resolved", func() {
+			So(unit.Markers, ShouldHaveLength, 1)
+			So(unit.Markers[0], ShouldEqual, m.Markers[0])
+			So(sensor.Vehicle, ShouldEqual, m.Vehicles[0])
+			So(wp0.SyncSensors, ShouldHaveLength, 1)
+			So(wp0.SyncSensors[0], ShouldEqual, sensor)
+			So(sensor.SyncWaypoints, ShouldHaveLength, 1)
+			So(sensor.SyncWaypoints[0], ShouldEqual, wp0)
+		})
+		Convey("Dangling references are reported", func() {
+			So(m.Vehicles[0].Markers, ShouldBeEmpty)
+			So(wp1.SyncWaypoints, ShouldBeEmpty)
+			So(wp1.SyncSensors, ShouldBeEmpty)
+			var dangling []string
+			for _, w := range p.Warnings() {
+				if d, ok := w.(*DanglingReferenceError); ok {
+					dangling = append(dangling, d.Ref)
+				}
+			}
+			So(dangling, ShouldResemble, []string{"missing", "8"})
+		})
+		Convey("When units are reordered and encoded", func() {
+			m.Vehicles = append(m.Vehicles, &Vehicle{Classname: "M1A1"})
+			m.Vehicles[0], m.Vehicles[1] = m.Vehicles[1], m.Vehicles[0]
+			m.Groups[0].Waypoints[0], m.Groups[0].Waypoints[1] = wp1, wp0
+			enc := NewClassEncoder()
+			class := enc.EncodeToClass(mf)
+			So(enc.Warnings(), ShouldBeEmpty)
+
+			Convey("Ids are regenerated", func() {
+				stage := class.Classes[0]
+				groups := stage.Classes[1]
+				sensors := stage.Classes[3]
+				vehs := stage.Classes[4]
+				So(groups.Classes[0].Classes[0].Classes[0].Props, ShouldContainProp, &sqm.Property{"id", sqm.TNumber, "0"})
+				So(vehs.Classes[1].Props, ShouldContainProp, &sqm.Property{"id", sqm.TNumber, "2"})
+				So(sensors.Classes[0].Props, ShouldContainProp, &sqm.Property{"idVehicle", sqm.TNumber, "2"})
+				So(sensors.Classes[0].Arrprops, ShouldContainProp, &sqm.ArrayProperty{"synchronizations", sqm.TNumber, []string{"0"}})
+				wps := groups.Classes[0].Classes[1]
+				So(wps.Classes[1].Arrprops, ShouldContainProp, &sqm.ArrayProperty{"synchronizations", sqm.TNumber, []string{"0"}})
+			})
+			Convey("Empty synchronizations are omitted", func() {
+				wps := class.Classes[0].Classes[1].Classes[0].Classes[1]
+				for _, a := range wps.Classes[0].Arrprops {
+					So(a.Name, ShouldNotEqual, "synchronizations")
+				}
+			})
+		})
+		Convey("When a referenced vehicle is deleted", func() {
+			m.Vehicles = nil
+			enc := NewClassEncoder()
+			class := enc.EncodeToClass(mf)
+			Convey("The reference is omitted and reported", func() {
+				So(m.DanglingReferences(), ShouldHaveLength, 1)
+				So(enc.Warnings(), ShouldHaveLength, 1)
+				sensors := class.Classes[0].Classes[len(class.Classes[0].Classes)-1]
+				So(sensors.Name, ShouldEqual, "Sensors")
+				for _, prop := range sensors.Classes[0].Props {
+					So(prop.Name, ShouldNotEqual, "idVehicle")
+				}
+			})
+		})
+	})
+}
+
+func TestSync(t *testing.T) {
+	Convey("Given a waypoint and a sensor", t, func() {
+		wp := &Waypoint{}
+		s := &Sensor{}
+		Convey("Synchronization is kept on both sides once", func() {
+			wp.SyncSensor(s)
+			s.SyncWaypoint(wp)
+			So(wp.SyncSensors, ShouldResemble, []*Sensor{s})
+			So(s.SyncWaypoints, ShouldResemble, []*Waypoint{wp})
+		})
+	})
+}
@@ -0,0 +1,277 @@
+package gosqm
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultCellSize is the cell size of a SpatialIndex in metres if none is
+// given.
+const DefaultCellSize = 100
+
+// maxAreaCells is the number of cells a sensor area may cover before it is
+// checked on every query instead of being added to the cells
+const maxAreaCells = 1024
+
+// SpatialEntry is an entity found by a spatial query. Entity is a *Vehicle,
+// *Waypoint, *Marker or *Sensor, Group is set for units and waypoints.
+type SpatialEntry struct {
+	Entity   interface{}
+	Position Vec3
+	Group    *Group
+	Stage    string
+	index    int
+}
+
+type cell [2]int
+
+// SpatialIndex is a grid of the entities of one or more stages.
+// Queries work on the map plane and ignore heights. The index is not
+// updated if entities are moved, build a new one instead.
+type SpatialIndex struct {
+	cellSize float64
+	cells    map[cell][]*SpatialEntry
+	areas    map[cell][]*SpatialEntry
+	large    []*SpatialEntry
+	min, max Vec2
+	count    int
+}
+
+// NewSpatialIndex returns an empty index with cells of cellSize metres, the
+// DefaultCellSize is used if cellSize is not positive.
+func NewSpatialIndex(cellSize float64) *SpatialIndex {
+	if cellSize <= 0 {
+		cellSize = DefaultCellSize
+	}
+	return &SpatialIndex{
+		cellSize: cellSize,
+		cells:    make(map[cell][]*SpatialEntry),
+		areas:    make(map[cell][]*SpatialEntry),
+	}
+}
+
+// IndexMissionFile returns an index of the entities of all stages.
+func IndexMissionFile(mf *MissionFile, cellSize float64) *SpatialIndex {
+	idx := NewSpatialIndex(cellSize)
+	for _, stage := range mf.Stages() {
+		idx.AddMission(stage.Name, stage.Mission)
+	}
+	return idx
+}
+
+// AddMission adds the units, waypoints, vehicles, markers and sensors of a
+// mission to the index.
+func (idx *SpatialIndex) AddMission(stage string, m *Mission) {
+	for _, g := range m.Groups {
+		for _, v := range g.Units {
+			idx.add(&SpatialEntry{v, v.Position, g, stage, 0})
+		}
+		for _, w := range g.Waypoints {
+			idx.add(&SpatialEntry{w, w.Position, g, stage, 0})
+		}
+	}
+	for _, v := range m.Vehicles {
+		idx.add(&SpatialEntry{v, v.Position, nil, stage, 0})
+	}
+	for _, marker := range m.Markers {
+		idx.add(&SpatialEntry{marker, marker.Position, nil, stage, 0})
+	}
+	for _, s := range m.Sensors {
+		e := &SpatialEntry{s, s.Position, nil, stage, 0}
+		idx.add(e)
+		idx.addArea(e, areaRadius(s.Size))
+	}
+}
+
+// Len returns the number of entries.
+func (idx *SpatialIndex) Len() int {
+	return idx.count
+}
+
+func (idx *SpatialIndex) cellOf(p Vec2) cell {
+	return cell{int(math.Floor(p.X / idx.cellSize)), int(math.Floor(p.Y / idx.cellSize))}
+}
+
+func (idx *SpatialIndex) add(e *SpatialEntry) {
+	p := e.Position.XY()
+	if idx.count == 0 {
+		idx.min, idx.max = p, p
+	}
+	idx.min = Vec2{math.Min(idx.min.X, p.X), math.Min(idx.min.Y, p.Y)}
+	idx.max = Vec2{math.Max(idx.max.X, p.X), math.Max(idx.max.Y, p.Y)}
+	e.index = idx.count
+	c := idx.cellOf(p)
+	idx.cells[c] = append(idx.cells[c], e)
+	idx.count++
+}
+
+// addArea adds an area to every cell its bounding square touches
+func (idx *SpatialIndex) addArea(e *SpatialEntry, radius float64) {
+	p := e.Position.XY()
+	lo := idx.cellOf(p.Sub(Vec2{radius, radius}))
+	hi := idx.cellOf(p.Add(Vec2{radius, radius}))
+	if (hi[0]-lo[0]+1)*(hi[1]-lo[1]+1) > maxAreaCells {
+		idx.large = append(idx.large, e)
+		return
+	}
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			c := cell{x, y}
+			idx.areas[c] = append(idx.areas[c], e)
+		}
+	}
+}
+
+// Within returns the entries within radius metres of p, nearest first.
+func (idx *SpatialIndex) Within(p Vec3, radius float64) []*SpatialEntry {
+	var res []*SpatialEntry
+	idx.visit(p.XY(), radius, func(e *SpatialEntry) {
+		if e.Position.Dist2D(p) <= radius {
+			res = append(res, e)
+		}
+	})
+	sortByDistance(res, p)
+	return res
+}
+
+// visit calls fn for the entries of every cell touched by the circle
+func (idx *SpatialIndex) visit(p Vec2, radius float64, fn func(e *SpatialEntry)) {
+	if radius < 0 {
+		return
+	}
+	lo := idx.cellOf(p.Sub(Vec2{radius, radius}))
+	hi := idx.cellOf(p.Add(Vec2{radius, radius}))
+	if float64(hi[0]-lo[0]+1)*float64(hi[1]-lo[1]+1) > float64(len(idx.cells)) {
+		for c, entries := range idx.cells {
+			if c[0] >= lo[0] && c[0] <= hi[0] && c[1] >= lo[1] && c[1] <= hi[1] {
+				for _, e := range entries {
+					fn(e)
+				}
+			}
+		}
+		return
+	}
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for _, e := range idx.cells[cell{x, y}] {
+				fn(e)
+			}
+		}
+	}
+}
+
+// Nearest returns up to k entries nearest to p for which filter returns
+// true, nearest first. A nil filter accepts every entry.
+func (idx *SpatialIndex) Nearest(p Vec3, k int, filter func(e *SpatialEntry) bool) []*SpatialEntry {
+	if k <= 0 || idx.count == 0 {
+		return nil
+	}
+	// the farthest entry is within the distance to the farthest corner
+	q := p.XY()
+	dx := math.Max(math.Abs(q.X-idx.min.X), math.Abs(q.X-idx.max.X))
+	dy := math.Max(math.Abs(q.Y-idx.min.Y), math.Abs(q.Y-idx.max.Y))
+	limit := math.Hypot(dx, dy)
+
+	for radius := idx.cellSize; ; radius *= 2 {
+		var res []*SpatialEntry
+		for _, e := range idx.Within(p, math.Min(radius, limit)) {
+			if filter == nil || filter(e) {
+				res = append(res, e)
+			}
+		}
+		if len(res) >= k || radius >= limit {
+			if len(res) > k {
+				res = res[:k]
+			}
+			return res
+		}
+	}
+}
+
+// InMarker returns the entries inside the area of a rectangle or ellipse
+// marker, nearest to its center first. The marker itself is not returned.
+func (idx *SpatialIndex) InMarker(m *Marker) []*SpatialEntry {
+	return idx.inArea(m, m.Position, m.Size, m.Contains)
+}
+
+// InSensor returns the entries inside the area of a sensor, nearest to its
+// center first. The sensor itself is not returned.
+func (idx *SpatialIndex) InSensor(s *Sensor) []*SpatialEntry {
+	return idx.inArea(s, s.Position, s.Size, s.Contains)
+}
+
+func (idx *SpatialIndex) inArea(entity interface{}, center Vec3, size Vec2, contains func(p Vec3) bool) []*SpatialEntry {
+	var res []*SpatialEntry
+	for _, e := range idx.Within(center, areaRadius(size)) {
+		if e.Entity != entity && contains(e.Position) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// SensorsAt returns the entries of the sensors whose area contains p.
+func (idx *SpatialIndex) SensorsAt(p Vec3) []*SpatialEntry {
+	var res []*SpatialEntry
+	check := func(e *SpatialEntry) {
+		if e.Entity.(*Sensor).Contains(p) {
+			res = append(res, e)
+		}
+	}
+	for _, e := range idx.areas[idx.cellOf(p.XY())] {
+		check(e)
+	}
+	for _, e := range idx.large {
+		check(e)
+	}
+	return res
+}
+
+// sortByDistance sorts entries nearest first, ties in insertion order
+func sortByDistance(entries []*SpatialEntry, p Vec3) {
+	sort.Slice(entries, func(i, j int) bool {
+		di, dj := entries[i].Position.Dist2D(p), entries[j].Position.Dist2D(p)
+		if di != dj {
+			return di < dj
+		}
+		return entries[i].index < entries[j].index
+	})
+}
+
+// Contains returns true if p is inside the area of a rectangle or ellipse
+// marker. Icons have no area.
+func (m *Marker) Contains(p Vec3) bool {
+	switch m.MarkerType {
+	case MarkerShapeRectangle:
+		return areaContains(m.Position, m.Size, m.Angle, true, p)
+	case MarkerShapeEllipse:
+		return areaContains(m.Position, m.Size, m.Angle, false, p)
+	}
+	return false
+}
+
+// Contains returns true if p is inside the area of the sensor.
+func (s *Sensor) Contains(p Vec3) bool {
+	return areaContains(s.Position, s.Size, s.Angle, s.IsRectangle, p)
+}
+
+// areaContains checks if p is inside a rectangle or ellipse with half
+// extents size turned clockwise by angle
+func areaContains(center Vec3, size Vec2, angle float64, rectangle bool, p Vec3) bool {
+	if size.X <= 0 || size.Y <= 0 {
+		return false
+	}
+	sin, cos := sinCos(angle)
+	d := p.XY().Sub(center.XY())
+	x := (d.X*cos - d.Y*sin) / size.X
+	y := (d.X*sin + d.Y*cos) / size.Y
+	if rectangle {
+		return math.Abs(x) <= 1 && math.Abs(y) <= 1
+	}
+	return x*x+y*y <= 1
+}
+
+// areaRadius returns the radius of the circle around an area
+func areaRadius(size Vec2) float64 {
+	return math.Hypot(size.X, size.Y)
+}
@@ -0,0 +1,69 @@
+package gosqm
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestSpatialIndex(t *testing.T) {
+	Convey("Given an indexed mission", t, func() {
+		leader := &Vehicle{Name: "leader", Position: Vec3{100, 100, 0}}
+		medic := &Vehicle{Name: "medic", Position: Vec3{130, 100, 0}}
+		waypoint := &Waypoint{Position: Vec3{500, 500, 0}}
+		group := &Group{Units: []*Vehicle{leader, medic}, Waypoints: []*Waypoint{waypoint}}
+		car := &Vehicle{Name: "car", Position: Vec3{2000, 100, 0}}
+		zone := &Marker{Name: "zone", Position: Vec3{100, 100, 0}, MarkerType: MarkerShapeRectangle, Size: Vec2{40, 5}, Angle: 90}
+		area := &Marker{Name: "area", Position: Vec3{115, 100, 0}, MarkerType: MarkerShapeEllipse, Size: Vec2{20, 5}}
+		trigger := &Sensor{Name: "trigger", Position: Vec3{120, 100, 0}, Size: Vec2{15, 15}}
+		big := &Sensor{Name: "big", Position: Vec3{0, 0, 0}, Size: Vec2{10000, 10000}, IsRectangle: true}
+		m := &Mission{Groups: []*Group{group}, Vehicles: []*Vehicle{car}, Markers: []*Marker{zone, area}, Sensors: []*Sensor{trigger, big}}
+		idx := IndexMissionFile(&MissionFile{Mission: m, Intro: &Mission{}}, 0)
+
+		Convey("All entities are indexed", func() {
+			So(idx.Len(), ShouldEqual, 8)
+		})
+		Convey("Radius queries return the nearest first", func() {
+			res := idx.Within(Vec3{125, 100, 0}, 10)
+			So(res, ShouldHaveLength, 3)
+			So(res[0].Entity == medic, ShouldBeTrue)
+			So(res[0].Group == group, ShouldBeTrue)
+			So(res[0].Stage, ShouldEqual, StageMission)
+			So(res[1].Entity == trigger, ShouldBeTrue)
+			So(res[2].Entity == area, ShouldBeTrue)
+			So(idx.Within(Vec3{}, 1e7), ShouldHaveLength, 8)
+		})
+		Convey("Nearest queries search beyond the first cell", func() {
+			res := idx.Nearest(Vec3{1900, 100, 0}, 2, nil)
+			So(res, ShouldHaveLength, 2)
+			So(res[0].Entity == car, ShouldBeTrue)
+			So(res[1].Entity == waypoint, ShouldBeTrue)
+			isSensor := func(e *SpatialEntry) bool {
+				_, ok := e.Entity.(*Sensor)
+				return ok
+			}
+			res = idx.Nearest(Vec3{1900, 100, 0}, 5, isSensor)
+			So(res, ShouldHaveLength, 2)
+			So(res[0].Entity == trigger, ShouldBeTrue)
+		})
+		Convey("Rectangles respect their angle", func() {
+			res := idx.InMarker(zone)
+			So(res, ShouldHaveLength, 1)
+			So(res[0].Entity == leader, ShouldBeTrue)
+			So(zone.Contains(Vec3{100, 130, 0}), ShouldBeTrue)
+			So(zone.Contains(Vec3{130, 100, 0}), ShouldBeFalse)
+		})
+		Convey("Ellipses respect their shape", func() {
+			So(area.Contains(Vec3{130, 100, 0}), ShouldBeTrue)
+			So(area.Contains(Vec3{130, 104, 0}), ShouldBeFalse)
+			So((&Marker{Size: Vec2{10, 10}}).Contains(Vec3{}), ShouldBeFalse)
+		})
+		Convey("Sensors containing a unit are found", func() {
+			res := idx.SensorsAt(medic.Position)
+			So(res, ShouldHaveLength, 2)
+			So(res[0].Entity == trigger, ShouldBeTrue)
+			So(res[1].Entity == big, ShouldBeTrue)
+			So(idx.SensorsAt(car.Position), ShouldHaveLength, 1)
+			So(idx.InSensor(trigger), ShouldHaveLength, 2)
+		})
+	})
+}
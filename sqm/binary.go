@@ -0,0 +1,338 @@
+package sqm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// binaryMagic starts every binarized (raP) file
+const binaryMagic = "\x00raP"
+
+// entry types of a binarized class body
+const (
+	binClass byte = iota
+	binValue
+	binArray
+)
+
+// value types of binarized properties and array elements
+const (
+	binString byte = iota
+	binFloat
+	binInt
+)
+
+// maxBinaryDepth limits the nesting of classes read from binarized files
+const maxBinaryDepth = 256
+
+// IsBinary reports whether data starts like a binarized (raP) file.
+func IsBinary(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(binaryMagic))
+}
+
+// EncodeBinary writes the class in the binarized raP format the game uses
+// for mission.sqm files inside pbos. Numbers are stored as 32 bit integers
+// if possible and as single precision floats otherwise.
+func EncodeBinary(w io.Writer, class *Class) error {
+	b := &binWriter{}
+	b.buf.WriteString(binaryMagic)
+	b.uint32(0)
+	b.uint32(8)
+	b.uint32(0) // offset of the enums, set at the end
+	if err := b.body(class); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(b.buf.Bytes()[12:], uint32(b.buf.Len()))
+	b.uint32(0) // no enums
+	_, err := w.Write(b.buf.Bytes())
+	return err
+}
+
+type binWriter struct {
+	buf bytes.Buffer
+}
+
+func (b *binWriter) uint32(v uint32) {
+	var tmp [4]byte
+	binary.LittleEndian.PutUint32(tmp[:], v)
+	b.buf.Write(tmp[:])
+}
+
+func (b *binWriter) asciiz(s string) {
+	b.buf.WriteString(s)
+	b.buf.WriteByte(0)
+}
+
+// compressed writes an unsigned integer with 7 bits per byte
+func (b *binWriter) compressed(v int) {
+	for v >= 0x80 {
+		b.buf.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	b.buf.WriteByte(byte(v))
+}
+
+// body writes the entries of a class followed by the bodies of its
+// subclasses, whose offsets are filled in once they are known
+func (b *binWriter) body(c *Class) error {
+	b.asciiz("") // no base class
+	b.compressed(len(c.Arrprops) + len(c.Props) + len(c.Classes))
+	for _, p := range c.Arrprops {
+		b.buf.WriteByte(binArray)
+		b.asciiz(p.Name)
+		b.compressed(len(p.Values))
+		for _, v := range p.Values {
+			typ, payload, err := b.value(p.Typ, v, p.Name)
+			if err != nil {
+				return err
+			}
+			b.buf.WriteByte(typ)
+			b.buf.Write(payload)
+		}
+	}
+	for _, p := range c.Props {
+		typ, payload, err := b.value(p.Typ, p.Value, p.Name)
+		if err != nil {
+			return err
+		}
+		b.buf.WriteByte(binValue)
+		b.buf.WriteByte(typ)
+		b.asciiz(p.Name)
+		b.buf.Write(payload)
+	}
+	offsets := make([]int, len(c.Classes))
+	for i, sub := range c.Classes {
+		b.buf.WriteByte(binClass)
+		b.asciiz(sub.Name)
+		offsets[i] = b.buf.Len()
+		b.uint32(0)
+	}
+	for i, sub := range c.Classes {
+		binary.LittleEndian.PutUint32(b.buf.Bytes()[offsets[i]:], uint32(b.buf.Len()))
+		if err := b.body(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value encodes a string or number as value type and payload
+func (b *binWriter) value(typ PropType, value string, name string) (byte, []byte, error) {
+	if typ == TString {
+		return binString, append([]byte(unescapeString(value)), 0), nil
+	}
+	tmp := make([]byte, 4)
+	if i, err := strconv.ParseInt(value, 10, 32); err == nil {
+		binary.LittleEndian.PutUint32(tmp, uint32(int32(i)))
+		return binInt, tmp, nil
+	}
+	if f, err := strconv.ParseFloat(value, 32); err == nil {
+		binary.LittleEndian.PutUint32(tmp, math.Float32bits(float32(f)))
+		return binFloat, tmp, nil
+	}
+	return 0, nil, fmt.Errorf("Invalid number %q in %s", value, name)
+}
+
+// DecodeBinary reads a class written in the binarized raP format.
+func DecodeBinary(r io.Reader) (*Class, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !IsBinary(data) || len(data) < 16 {
+		return nil, errors.New("Missing raP header")
+	}
+	b := &binReader{data: data, visited: make(map[int]bool)}
+	class := &Class{Name: "mission"}
+	if err := b.body(class, 16, 0); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+type binReader struct {
+	data    []byte
+	pos     int
+	visited map[int]bool
+}
+
+func (b *binReader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Offset %d: %s", b.pos, fmt.Sprintf(format, args...))
+}
+
+func (b *binReader) byte() (byte, error) {
+	if b.pos >= len(b.data) {
+		return 0, b.errorf("Unexpected end of file")
+	}
+	b.pos++
+	return b.data[b.pos-1], nil
+}
+
+func (b *binReader) uint32() (uint32, error) {
+	if b.pos+4 > len(b.data) {
+		return 0, b.errorf("Unexpected end of file")
+	}
+	b.pos += 4
+	return binary.LittleEndian.Uint32(b.data[b.pos-4:]), nil
+}
+
+func (b *binReader) asciiz() (string, error) {
+	end := bytes.IndexByte(b.data[b.pos:], 0)
+	if end < 0 {
+		return "", b.errorf("Unterminated string")
+	}
+	s := string(b.data[b.pos : b.pos+end])
+	b.pos += end + 1
+	return s, nil
+}
+
+func (b *binReader) compressed() (int, error) {
+	v := 0
+	for shift := uint(0); shift < 32; shift += 7 {
+		c, err := b.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= int(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, b.errorf("Invalid compressed integer")
+}
+
+// body reads the class body at offset into c
+func (b *binReader) body(c *Class, offset int, depth int) error {
+	if depth > maxBinaryDepth {
+		return b.errorf("Classes nested too deep")
+	}
+	if offset < 16 || offset >= len(b.data) || b.visited[offset] {
+		return b.errorf("Invalid class offset %d", offset)
+	}
+	b.visited[offset] = true
+	b.pos = offset
+	if _, err := b.asciiz(); err != nil {
+		return err
+	}
+	n, err := b.compressed()
+	if err != nil {
+		return err
+	}
+	var offsets []int
+	for i := 0; i < n; i++ {
+		typ, err := b.byte()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case binClass:
+			name, err := b.asciiz()
+			if err != nil {
+				return err
+			}
+			off, err := b.uint32()
+			if err != nil {
+				return err
+			}
+			c.Classes = append(c.Classes, &Class{Name: name, parent: c})
+			offsets = append(offsets, int(off))
+		case binValue:
+			sub, err := b.byte()
+			if err != nil {
+				return err
+			}
+			name, err := b.asciiz()
+			if err != nil {
+				return err
+			}
+			ptyp, value, err := b.value(sub)
+			if err != nil {
+				return err
+			}
+			c.Props = append(c.Props, &Property{name, ptyp, value})
+		case binArray:
+			name, err := b.asciiz()
+			if err != nil {
+				return err
+			}
+			p, err := b.array(name)
+			if err != nil {
+				return err
+			}
+			c.Arrprops = append(c.Arrprops, p)
+		default:
+			return b.errorf("Unsupported entry type %d", typ)
+		}
+	}
+	for i, sub := range c.Classes {
+		if err := b.body(sub, offsets[i], depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *binReader) array(name string) (*ArrayProperty, error) {
+	n, err := b.compressed()
+	if err != nil {
+		return nil, err
+	}
+	if n > len(b.data)-b.pos {
+		return nil, b.errorf("Array %s too long", name)
+	}
+	p := &ArrayProperty{Name: name, Typ: TNumber}
+	for i := 0; i < n; i++ {
+		sub, err := b.byte()
+		if err != nil {
+			return nil, err
+		}
+		typ, value, err := b.value(sub)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			p.Typ = typ
+		} else if typ != p.Typ {
+			return nil, b.errorf("Array %s mixes strings and numbers", name)
+		}
+		p.Values = append(p.Values, value)
+	}
+	return p, nil
+}
+
+// value reads a string, float or integer value
+func (b *binReader) value(sub byte) (PropType, string, error) {
+	switch sub {
+	case binString:
+		s, err := b.asciiz()
+		return TString, escapeString(s), err
+	case binFloat:
+		v, err := b.uint32()
+		f := float64(math.Float32frombits(v))
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return TNumber, "", b.errorf("Number is not finite")
+		}
+		return TNumber, strconv.FormatFloat(f, 'f', -1, 32), err
+	case binInt:
+		v, err := b.uint32()
+		return TNumber, strconv.Itoa(int(int32(v))), err
+	}
+	return TNumber, "", b.errorf("Unsupported value type %d", sub)
+}
+
+// escapeString doubles the quotes of a raw string
+func escapeString(s string) string {
+	return strings.Replace(s, `"`, `""`, -1)
+}
+
+// unescapeString removes the doubled quotes of a string value
+func unescapeString(s string) string {
+	return strings.Replace(s, `""`, `"`, -1)
+}
@@ -0,0 +1,66 @@
+package sqm
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"testing"
+)
+
+const binarySource = `version=11;
+class Mission
+{
+	addOns[]={"ca_modules","cacharacters"};
+	randomSeed=8765432;
+	class Item0 { position[]={1.5,-2,300000}; text="say ""hi"""; skill=0.60000002; empty[]={}; };
+};
+`
+
+func TestBinary(t *testing.T) {
+	Convey("Given a class encoded as raP", t, func() {
+		class := mustParse(binarySource)
+		var buf bytes.Buffer
+		So(EncodeBinary(&buf, class), ShouldBeNil)
+		So(IsBinary(buf.Bytes()), ShouldBeTrue)
+
+		Convey("It decodes to the same class", func() {
+			decoded, err := DecodeBinary(bytes.NewReader(buf.Bytes()))
+			So(err, ShouldBeNil)
+			So(decoded.Props[0].Value, ShouldEqual, "11")
+			mission := decoded.Classes[0]
+			So(mission.Path(), ShouldEqual, "Mission")
+			So(mission.Arrprops[0].Values, ShouldResemble, []string{"ca_modules", "cacharacters"})
+			So(mission.Arrprops[0].Typ, ShouldEqual, TString)
+			item := mission.Classes[0]
+			So(item.Arrprops[0].Values, ShouldResemble, []string{"1.5", "-2", "300000"})
+			So(item.Arrprops[1].Values, ShouldBeEmpty)
+			So(item.Props[0].Value, ShouldEqual, `say ""hi""`)
+			So(item.Props[1].Value, ShouldEqual, "0.6")
+		})
+		Convey("Truncated files are rejected", func() {
+			data := buf.Bytes()
+			for i := 0; i < len(data)-4; i++ {
+				_, err := DecodeBinary(bytes.NewReader(data[:i]))
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("Invalid numbers are not encoded", func() {
+			bad := &Class{Props: []*Property{&Property{"x", TNumber, "abc"}}}
+			So(EncodeBinary(&bytes.Buffer{}, bad), ShouldNotBeNil)
+		})
+	})
+	Convey("Given the test mission", t, func() {
+		buf, err := ioutil.ReadFile("../testdata/mission.sqm")
+		So(err, ShouldBeNil)
+		class := mustParse(string(buf))
+
+		Convey("Encoding is stable after one round trip", func() {
+			var first, second bytes.Buffer
+			So(EncodeBinary(&first, class), ShouldBeNil)
+			decoded, err := DecodeBinary(bytes.NewReader(first.Bytes()))
+			So(err, ShouldBeNil)
+			So(EncodeBinary(&second, decoded), ShouldBeNil)
+			So(bytes.Equal(first.Bytes(), second.Bytes()), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,433 @@
+package sqm
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type EditType int
+
+const (
+	PropertyAdded       EditType = iota // Property or array property only in b
+	PropertyRemoved                     // Property or array property only in a
+	PropertyChanged                     // Value, type or array length differs
+	ArrayElementChanged                 // Single element of an array of the same length differs
+	ClassAdded                          // Class only in b
+	ClassRemoved                        // Class only in a
+	ItemReordered                       // ItemN class moved relative to its siblings
+)
+
+func (t EditType) String() string {
+	switch t {
+	case PropertyAdded:
+		return "PropertyAdded"
+	case PropertyRemoved:
+		return "PropertyRemoved"
+	case PropertyChanged:
+		return "PropertyChanged"
+	case ArrayElementChanged:
+		return "ArrayElementChanged"
+	case ClassAdded:
+		return "ClassAdded"
+	case ClassRemoved:
+		return "ClassRemoved"
+	case ItemReordered:
+		return "ItemReordered"
+	}
+	return "Unkown"
+}
+
+// Edit is a single difference between two class trees.
+// Path is the slash separated path of the element in b, or in a if the
+// element was removed. OldPath is the path in a, it differs from Path if
+// an ItemN class was renumbered.
+type Edit struct {
+	Type    EditType
+	Path    string
+	OldPath string
+	Index   int    // Element index for ArrayElementChanged
+	Old     string // Old value, or old class name for ItemReordered
+	New     string // New value, or new class name for ItemReordered
+}
+
+func (e Edit) String() string {
+	switch e.Type {
+	case PropertyAdded, ClassAdded:
+		return e.Type.String() + " " + e.Path + " " + e.New
+	case PropertyRemoved, ClassRemoved:
+		return e.Type.String() + " " + e.Path + " " + e.Old
+	case ItemReordered:
+		return e.Type.String() + " " + e.OldPath + " -> " + e.Path
+	}
+	return e.Type.String() + " " + e.Path + ": " + e.Old + " -> " + e.New
+}
+
+// Diff returns the edits needed to turn class a into class b.
+// Sibling classes named ItemN are treated as a collection and matched by
+// content, so inserting or removing one item does not report all
+// following items as changed.
+func Diff(a, b *Class) []Edit {
+	var edits []Edit
+	diffClass(a, b, "", "", &edits)
+	return edits
+}
+
+var itemNameRegexp = regexp.MustCompile(`^Item[0-9]+$`)
+
+// IsItemName reports whether name is the name of a collection member
+// like Item0.
+func IsItemName(name string) bool {
+	return itemNameRegexp.MatchString(name)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "/" + name
+}
+
+func diffClass(a, b *Class, pathA, pathB string, edits *[]Edit) {
+	diffProps(a, b, pathA, pathB, edits)
+	diffArrprops(a, b, pathA, pathB, edits)
+
+	pairs, removed, added := MatchClasses(a.Classes, b.Classes)
+	for _, c := range removed {
+		p := joinPath(pathA, c.Name)
+		*edits = append(*edits, Edit{Type: ClassRemoved, Path: p, OldPath: p, Old: c.Name})
+	}
+	for _, c := range added {
+		*edits = append(*edits, Edit{Type: ClassAdded, Path: joinPath(pathB, c.Name), New: c.Name})
+	}
+	for _, pair := range reordered(pairs, b.Classes) {
+		*edits = append(*edits, Edit{
+			Type:    ItemReordered,
+			Path:    joinPath(pathB, pair[1].Name),
+			OldPath: joinPath(pathA, pair[0].Name),
+			Old:     pair[0].Name,
+			New:     pair[1].Name,
+		})
+	}
+	for _, pair := range pairs {
+		diffClass(pair[0], pair[1], joinPath(pathA, pair[0].Name), joinPath(pathB, pair[1].Name), edits)
+	}
+}
+
+func diffProps(a, b *Class, pathA, pathB string, edits *[]Edit) {
+	for _, pa := range a.Props {
+		pb := findProp(b.Props, pa.Name)
+		if pb == nil {
+			p := joinPath(pathA, pa.Name)
+			*edits = append(*edits, Edit{Type: PropertyRemoved, Path: p, OldPath: p, Old: pa.Value})
+			continue
+		}
+		if pa.Value != pb.Value || pa.Typ != pb.Typ {
+			*edits = append(*edits, Edit{
+				Type:    PropertyChanged,
+				Path:    joinPath(pathB, pa.Name),
+				OldPath: joinPath(pathA, pa.Name),
+				Old:     pa.Value,
+				New:     pb.Value,
+			})
+		}
+	}
+	for _, pb := range b.Props {
+		if findProp(a.Props, pb.Name) == nil {
+			*edits = append(*edits, Edit{Type: PropertyAdded, Path: joinPath(pathB, pb.Name), New: pb.Value})
+		}
+	}
+}
+
+func diffArrprops(a, b *Class, pathA, pathB string, edits *[]Edit) {
+	for _, pa := range a.Arrprops {
+		pb := findArrprop(b.Arrprops, pa.Name)
+		if pb == nil {
+			p := joinPath(pathA, pa.Name)
+			*edits = append(*edits, Edit{Type: PropertyRemoved, Path: p, OldPath: p, Old: formatValues(pa.Values)})
+			continue
+		}
+		if pa.Typ != pb.Typ || len(pa.Values) != len(pb.Values) {
+			*edits = append(*edits, Edit{
+				Type:    PropertyChanged,
+				Path:    joinPath(pathB, pa.Name),
+				OldPath: joinPath(pathA, pa.Name),
+				Old:     formatValues(pa.Values),
+				New:     formatValues(pb.Values),
+			})
+			continue
+		}
+		for i := range pa.Values {
+			if pa.Values[i] != pb.Values[i] {
+				*edits = append(*edits, Edit{
+					Type:    ArrayElementChanged,
+					Path:    joinPath(pathB, pa.Name),
+					OldPath: joinPath(pathA, pa.Name),
+					Index:   i,
+					Old:     pa.Values[i],
+					New:     pb.Values[i],
+				})
+			}
+		}
+	}
+	for _, pb := range b.Arrprops {
+		if findArrprop(a.Arrprops, pb.Name) == nil {
+			*edits = append(*edits, Edit{Type: PropertyAdded, Path: joinPath(pathB, pb.Name), New: formatValues(pb.Values)})
+		}
+	}
+}
+
+func formatValues(values []string) string {
+	return "{" + strings.Join(values, ",") + "}"
+}
+
+func findProp(props []*Property, name string) *Property {
+	for _, p := range props {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func findArrprop(props []*ArrayProperty, name string) *ArrayProperty {
+	for _, p := range props {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// minItemSimilarity is the minimum share of equal attributes for two
+// different ItemN classes to be considered the same item
+const minItemSimilarity = 0.5
+
+// MatchClasses pairs the classes of a with the classes of b.
+// Named classes are paired by name, ItemN classes by content: equal items
+// first, then the most similar ones. Unpaired classes are returned as
+// removed (only in a) and added (only in b).
+func MatchClasses(a, b []*Class) (pairs [][2]*Class, removed, added []*Class) {
+	usedB := make(map[*Class]bool)
+	var itemsA, itemsB []*Class
+	for _, cb := range b {
+		if IsItemName(cb.Name) {
+			itemsB = append(itemsB, cb)
+		}
+	}
+	for _, ca := range a {
+		if IsItemName(ca.Name) {
+			itemsA = append(itemsA, ca)
+			continue
+		}
+		var match *Class
+		for _, cb := range b {
+			if !usedB[cb] && cb.Name == ca.Name {
+				match = cb
+				break
+			}
+		}
+		if match == nil {
+			removed = append(removed, ca)
+			continue
+		}
+		usedB[match] = true
+		pairs = append(pairs, [2]*Class{ca, match})
+	}
+
+	// equal items
+	fps := make(fingerprints)
+	var unmatchedA []*Class
+	for _, ca := range itemsA {
+		fp := fps.of(ca)
+		var match *Class
+		for _, cb := range itemsB {
+			if !usedB[cb] && fps.of(cb) == fp {
+				match = cb
+				break
+			}
+		}
+		if match == nil {
+			unmatchedA = append(unmatchedA, ca)
+			continue
+		}
+		usedB[match] = true
+		pairs = append(pairs, [2]*Class{ca, match})
+	}
+
+	// similar items, best candidates first
+	type candidate struct {
+		a, b  *Class
+		score float64
+	}
+	var candidates []candidate
+	for _, ca := range unmatchedA {
+		for _, cb := range itemsB {
+			if usedB[cb] {
+				continue
+			}
+			if s := similarity(ca, cb, fps); s >= minItemSimilarity {
+				candidates = append(candidates, candidate{ca, cb, s})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	usedA := make(map[*Class]bool)
+	for _, c := range candidates {
+		if usedA[c.a] || usedB[c.b] {
+			continue
+		}
+		usedA[c.a] = true
+		usedB[c.b] = true
+		pairs = append(pairs, [2]*Class{c.a, c.b})
+	}
+	for _, ca := range unmatchedA {
+		if !usedA[ca] {
+			removed = append(removed, ca)
+		}
+	}
+	for _, cb := range b {
+		if !usedB[cb] {
+			added = append(added, cb)
+		}
+	}
+
+	// keep pairs in order of a
+	index := make(map[*Class]int)
+	for i, c := range a {
+		index[c] = i
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return index[pairs[i][0]] < index[pairs[j][0]]
+	})
+	return
+}
+
+// reordered returns the ItemN pairs which changed their relative order.
+// The longest run of pairs which kept their order is considered unmoved.
+func reordered(pairs [][2]*Class, b []*Class) [][2]*Class {
+	indexB := make(map[*Class]int)
+	for i, c := range b {
+		indexB[c] = i
+	}
+	var items [][2]*Class
+	for _, pair := range pairs {
+		if IsItemName(pair[0].Name) {
+			items = append(items, pair)
+		}
+	}
+	if len(items) < 2 {
+		return nil
+	}
+
+	// longest increasing subsequence of the positions in b
+	n := len(items)
+	length := make([]int, n)
+	prev := make([]int, n)
+	best := 0
+	for i := 0; i < n; i++ {
+		length[i] = 1
+		prev[i] = -1
+		for j := 0; j < i; j++ {
+			if indexB[items[j][1]] < indexB[items[i][1]] && length[j]+1 > length[i] {
+				length[i] = length[j] + 1
+				prev[i] = j
+			}
+		}
+		if length[i] > length[best] {
+			best = i
+		}
+	}
+	kept := make(map[int]bool)
+	for i := best; i >= 0; i = prev[i] {
+		kept[i] = true
+	}
+	var moved [][2]*Class
+	for i, pair := range items {
+		if !kept[i] {
+			moved = append(moved, pair)
+		}
+	}
+	return moved
+}
+
+// fingerprint serializes the content of a class without its own name,
+// strings are quoted so they differ from numbers
+func fingerprint(c *Class) string {
+	return make(fingerprints).of(c)
+}
+
+// fingerprints caches the fingerprints of classes and their subclasses
+type fingerprints map[*Class]string
+
+func (f fingerprints) of(c *Class) string {
+	if fp, found := f[c]; found {
+		return fp
+	}
+	var s []string
+	for _, p := range c.Arrprops {
+		values := make([]string, len(p.Values))
+		for i, v := range p.Values {
+			values[i] = typedValue(p.Typ, v)
+		}
+		s = append(s, p.Name+"[]="+formatValues(values))
+	}
+	for _, p := range c.Props {
+		s = append(s, p.Name+"="+typedValue(p.Typ, p.Value))
+	}
+	for _, sub := range c.Classes {
+		s = append(s, "class "+sub.Name+"{"+f.of(sub)+"}")
+	}
+	fp := strings.Join(s, ";")
+	f[c] = fp
+	return fp
+}
+
+func typedValue(typ PropType, v string) string {
+	if typ == TString {
+		return `"` + v + `"`
+	}
+	return v
+}
+
+// similarity is the share of equal attributes and subclasses of two classes
+func similarity(a, b *Class, fps fingerprints) float64 {
+	total, equal := 0, 0
+	for _, pa := range a.Props {
+		total++
+		if pb := findProp(b.Props, pa.Name); pb != nil && pb.Typ == pa.Typ && pb.Value == pa.Value {
+			equal++
+		}
+	}
+	for _, pb := range b.Props {
+		if findProp(a.Props, pb.Name) == nil {
+			total++
+		}
+	}
+	for _, pa := range a.Arrprops {
+		total++
+		if pb := findArrprop(b.Arrprops, pa.Name); pb != nil && pb.Typ == pa.Typ && formatValues(pb.Values) == formatValues(pa.Values) {
+			equal++
+		}
+	}
+	for _, pb := range b.Arrprops {
+		if findArrprop(a.Arrprops, pb.Name) == nil {
+			total++
+		}
+	}
+	for _, ca := range a.Classes {
+		total++
+		for _, cb := range b.Classes {
+			if cb.Name == ca.Name && fps.of(ca) == fps.of(cb) {
+				equal++
+				break
+			}
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(equal) / float64(total)
+}
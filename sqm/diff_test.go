@@ -0,0 +1,106 @@
+package sqm
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func mustParse(input string) *Class {
+	c, err := MakeParser(input).Run()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+const diffBase = `class Vehicles
+{
+	items=3;
+	class Item0 { position[]={1,2,3}; text="alpha"; vehicle="USMC_Soldier"; };
+	class Item1 { position[]={4,5,6}; text="bravo"; vehicle="USMC_Soldier_AR"; };
+	class Item2 { position[]={7,8,9}; text="charlie"; vehicle="USMC_Soldier_Medic"; };
+};
+`
+
+func TestDiff(t *testing.T) {
+	Convey("Given two equal classes", t, func() {
+		edits := Diff(mustParse(diffBase), mustParse(diffBase))
+		Convey("There should be no edits", func() {
+			So(len(edits), ShouldEqual, 0)
+		})
+	})
+
+	Convey("Given an inserted item", t, func() {
+		b := mustParse(`class Vehicles
+{
+	items=4;
+	class Item0 { position[]={1,2,3}; text="alpha"; vehicle="USMC_Soldier"; };
+	class Item1 { position[]={0,0,0}; text="new"; vehicle="USMC_Soldier_AT"; };
+	class Item2 { position[]={4,5,6}; text="bravo"; vehicle="USMC_Soldier_AR"; };
+	class Item3 { position[]={7,8,9}; text="charlie"; vehicle="USMC_Soldier_Medic"; };
+};
+`)
+		edits := Diff(mustParse(diffBase), b)
+		Convey("Only the count and the new class are reported", func() {
+			So(len(edits), ShouldEqual, 2)
+			So(edits[0], ShouldResemble, Edit{Type: PropertyChanged, Path: "Vehicles/items", OldPath: "Vehicles/items", Old: "3", New: "4"})
+			So(edits[1], ShouldResemble, Edit{Type: ClassAdded, Path: "Vehicles/Item1", New: "Item1"})
+		})
+	})
+
+	Convey("Given a changed property in a renumbered item", t, func() {
+		b := mustParse(`class Vehicles
+{
+	items=2;
+	class Item0 { position[]={4,5,6}; text="bravo"; vehicle="USMC_Soldier_AR"; };
+	class Item1 { position[]={7,8,10}; text="charlie"; vehicle="USMC_Soldier_Medic"; };
+};
+`)
+		edits := Diff(mustParse(diffBase), b)
+		Convey("Removal and element change carry both paths", func() {
+			So(len(edits), ShouldEqual, 3)
+			So(edits[1], ShouldResemble, Edit{Type: ClassRemoved, Path: "Vehicles/Item0", OldPath: "Vehicles/Item0", Old: "Item0"})
+			So(edits[2], ShouldResemble, Edit{Type: ArrayElementChanged, Path: "Vehicles/Item1/position", OldPath: "Vehicles/Item2/position", Index: 2, Old: "9", New: "10"})
+		})
+	})
+
+	Convey("Given swapped items", t, func() {
+		b := mustParse(`class Vehicles
+{
+	items=3;
+	class Item0 { position[]={1,2,3}; text="alpha"; vehicle="USMC_Soldier"; };
+	class Item1 { position[]={7,8,9}; text="charlie"; vehicle="USMC_Soldier_Medic"; };
+	class Item2 { position[]={4,5,6}; text="bravo"; vehicle="USMC_Soldier_AR"; };
+};
+`)
+		edits := Diff(mustParse(diffBase), b)
+		Convey("A single reorder is reported", func() {
+			So(len(edits), ShouldEqual, 1)
+			So(edits[0].Type, ShouldEqual, ItemReordered)
+		})
+	})
+
+	Convey("Given added and removed properties", t, func() {
+		a := mustParse(`class Intel { year=2009; startWeather=0.3; };`)
+		b := mustParse(`class Intel { year=2010; month=5; };`)
+		edits := Diff(a, b)
+		Convey("All property edits are reported", func() {
+			So(len(edits), ShouldEqual, 3)
+			So(edits[0], ShouldResemble, Edit{Type: PropertyChanged, Path: "Intel/year", OldPath: "Intel/year", Old: "2009", New: "2010"})
+			So(edits[1], ShouldResemble, Edit{Type: PropertyRemoved, Path: "Intel/startWeather", OldPath: "Intel/startWeather", Old: "0.3"})
+			So(edits[2], ShouldResemble, Edit{Type: PropertyAdded, Path: "Intel/month", New: "5"})
+		})
+	})
+
+	Convey("Given items which differ only in the type of a value", t, func() {
+		a := mustParse(`class Item0 { x=1; }; class Item1 { x="1"; };`)
+		b := mustParse(`class Item0 { x="1"; }; class Item1 { x=1; };`)
+		pairs, removed, added := MatchClasses(a.Classes, b.Classes)
+		Convey("Numbers and strings are told apart", func() {
+			So(len(removed)+len(added), ShouldEqual, 0)
+			So(len(pairs), ShouldEqual, 2)
+			So(pairs[0][1] == b.Classes[1], ShouldBeTrue)
+			So(pairs[1][1] == b.Classes[0], ShouldBeTrue)
+		})
+	})
+}
@@ -9,26 +9,21 @@ const LINEBREAK = "\r\n"
 const INDENT = 1
 
 type Encoder struct {
-	w io.Writer
+	wr *Writer
 }
 
 func NewEncoder(w io.Writer) *Encoder {
 	e := &Encoder{}
-	e.w = w
+	e.wr = NewWriter(w)
 	return e
 }
 
-func (e *Encoder) writeString(s string) error {
-	_, err := e.w.Write([]byte(s))
-	return err
-}
-
 func (e *Encoder) Encode(class *Class) error {
 	return e.encodeMainClass(class, 0)
 }
 
 func (e *Encoder) encodeClass(class *Class, level int) error {
-	err := e.writeString(indent(level) + "class " + class.Name + LINEBREAK + indent(level) + "{" + LINEBREAK)
+	err := e.wr.beginClass(class.Name, level)
 	if err != nil {
 		return err
 	}
@@ -37,7 +32,7 @@ func (e *Encoder) encodeClass(class *Class, level int) error {
 		return err
 	}
 
-	err = e.writeString(indent(level) + "};" + LINEBREAK)
+	err = e.wr.endClass(level)
 	if err != nil {
 		return err
 	}
@@ -82,85 +77,11 @@ func (e *Encoder) encodeSubElements(class *Class, level int) error {
 }
 
 func (e *Encoder) encodeProperty(p *Property, level int) error {
-	var err error
-	switch p.Typ {
-	case TString:
-		err = e.writeString(indent(level) + p.Name + "=\"" + p.Value + "\";" + LINEBREAK)
-	case TNumber:
-		err = e.writeString(indent(level) + p.Name + "=" + p.Value + ";" + LINEBREAK)
-	}
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return e.wr.writeProperty(p, level)
 }
 
 func (e *Encoder) encodeArrProperty(arrProp *ArrayProperty, level int) error {
-	if arrProp.Name == "addOns" || arrProp.Name == "addOnsAuto" {
-		return e.encodeAddonsArrProperty(arrProp, level)
-	} else {
-		return e.encodeNormalArrProperty(arrProp, level)
-	}
-}
-
-func (e *Encoder) encodeAddonsArrProperty(arrProp *ArrayProperty, level int) error {
-	err := e.writeString(indent(level) + arrProp.Name + "[]=" + LINEBREAK + indent(level) + "{" + LINEBREAK)
-	if err != nil {
-		return err
-	}
-	for i, val := range arrProp.Values {
-		if i > 0 {
-			err = e.writeString("," + LINEBREAK)
-			if err != nil {
-				return err
-			}
-
-		}
-
-		err = e.writeString(indent(level+1) + "\"" + val + "\"")
-		if err != nil {
-			return err
-		}
-
-	}
-	err = e.writeString(LINEBREAK + indent(level) + "};" + LINEBREAK)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (e *Encoder) encodeNormalArrProperty(arrProp *ArrayProperty, level int) error {
-	err := e.writeString(indent(level) + arrProp.Name + "[]={")
-	if err != nil {
-		return err
-	}
-	for i, val := range arrProp.Values {
-		if i > 0 {
-			err = e.writeString(",")
-			if err != nil {
-				return err
-			}
-
-		}
-		if arrProp.Typ == TString {
-			err = e.writeString("\"" + val + "\"")
-			if err != nil {
-				return err
-			}
-		} else {
-			err := e.writeString(val)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	err = e.writeString("};" + LINEBREAK)
-	if err != nil {
-		return err
-	}
-	return nil
+	return e.wr.writeArrProperty(arrProp, level)
 }
 
 const indentCacheMax = 50
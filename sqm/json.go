@@ -0,0 +1,212 @@
+package sqm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxJSONDepth limits the nesting of classes read from JSON
+const maxJSONDepth = 256
+
+// MarshalJSON encodes the class as JSON object. Properties become strings
+// or numbers, array properties arrays and subclasses nested objects, in the
+// order array properties, properties, subclasses.
+func (c *Class) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.writeJSON(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *Class) writeJSON(buf *bytes.Buffer) error {
+	buf.WriteByte('{')
+	first := true
+	key := func(name string) {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		writeJSONString(buf, name)
+		buf.WriteByte(':')
+	}
+	for _, p := range c.Arrprops {
+		key(p.Name)
+		buf.WriteByte('[')
+		for i, v := range p.Values {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONValue(buf, p.Typ, v, p.Name); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	}
+	for _, p := range c.Props {
+		key(p.Name)
+		if err := writeJSONValue(buf, p.Typ, p.Value, p.Name); err != nil {
+			return err
+		}
+	}
+	for _, sub := range c.Classes {
+		key(sub.Name)
+		if err := sub.writeJSON(buf); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+// writeJSONValue writes strings unescaped and numbers in a form JSON accepts
+func writeJSONValue(buf *bytes.Buffer, typ PropType, value string, name string) error {
+	if typ == TString {
+		writeJSONString(buf, unescapeString(value))
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid number %q in %s", value, name)
+	}
+	if json.Valid([]byte(value)) {
+		buf.WriteString(value)
+	} else {
+		buf.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a class written by MarshalJSON. The order of the
+// entries is kept, the name of the class is not changed.
+func (c *Class) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	c.Props, c.Arrprops, c.Classes = nil, nil, nil
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	return c.readJSON(dec, 0)
+}
+
+// EncodeJSON writes the class as indented JSON object.
+func EncodeJSON(w io.Writer, class *Class) error {
+	data, err := class.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// DecodeJSON reads a class written by EncodeJSON.
+func DecodeJSON(r io.Reader) (*Class, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	class := &Class{Name: "mission"}
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	if err := class.readJSON(dec, 0); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("Expected %s but got %v", delim, tok)
+	}
+	return nil
+}
+
+// readJSON reads the entries of an object whose opening brace is consumed
+func (c *Class) readJSON(dec *json.Decoder, depth int) error {
+	if depth > maxJSONDepth {
+		return fmt.Errorf("Classes nested too deep")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name := tok.(string)
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			if v == '{' {
+				sub := &Class{Name: name, parent: c}
+				if err := sub.readJSON(dec, depth+1); err != nil {
+					return err
+				}
+				c.Classes = append(c.Classes, sub)
+				continue
+			}
+			p := &ArrayProperty{Name: name, Typ: TNumber}
+			for i := 0; dec.More(); i++ {
+				tok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				typ, value, err := jsonValue(tok, name)
+				if err != nil {
+					return err
+				}
+				if i == 0 {
+					p.Typ = typ
+				} else if typ != p.Typ {
+					return fmt.Errorf("Array %s mixes strings and numbers", name)
+				}
+				p.Values = append(p.Values, value)
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return err
+			}
+			c.Arrprops = append(c.Arrprops, p)
+		default:
+			typ, value, err := jsonValue(tok, name)
+			if err != nil {
+				return err
+			}
+			c.Props = append(c.Props, &Property{name, typ, value})
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// jsonValue converts a JSON string or number to a property value
+func jsonValue(tok json.Token, name string) (PropType, string, error) {
+	switch v := tok.(type) {
+	case string:
+		return TString, escapeString(v), nil
+	case json.Number:
+		// the text format has no exponents
+		if strings.ContainsAny(v.String(), "eE") {
+			f, err := v.Float64()
+			return TNumber, strconv.FormatFloat(f, 'f', -1, 64), err
+		}
+		return TNumber, v.String(), nil
+	}
+	return TNumber, "", fmt.Errorf("Unsupported value %v in %s", tok, name)
+}
@@ -0,0 +1,60 @@
+package sqm
+
+import (
+	"bytes"
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func encodeText(c *Class) string {
+	var buf bytes.Buffer
+	NewEncoder(&buf).Encode(c)
+	return buf.String()
+}
+
+func TestJSON(t *testing.T) {
+	Convey("Given a class", t, func() {
+		class := mustParse(binarySource)
+
+		Convey("It is encoded as nested objects", func() {
+			data, err := json.Marshal(class.Classes[0].Classes[0])
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"position":[1.5,-2,300000],"empty":[],"text":"say \"hi\"","skill":0.60000002}`)
+		})
+		Convey("It decodes to the same class", func() {
+			var buf bytes.Buffer
+			So(EncodeJSON(&buf, class), ShouldBeNil)
+			decoded, err := DecodeJSON(&buf)
+			So(err, ShouldBeNil)
+			So(encodeText(decoded), ShouldEqual, encodeText(class))
+			So(decoded.Classes[0].Classes[0].Path(), ShouldEqual, "Mission/Item0")
+		})
+		Convey("Invalid input is rejected", func() {
+			for _, input := range []string{`[]`, `{"a":true}`, `{"a":[1,"b"]}`, `{"a":[[1]]}`, `{"a":{`, `{"a":1`} {
+				_, err := DecodeJSON(strings.NewReader(input))
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("Exponents are written without", func() {
+			decoded, err := DecodeJSON(strings.NewReader(`{"a":1e-5}`))
+			So(err, ShouldBeNil)
+			So(decoded.Props[0].Value, ShouldEqual, "0.00001")
+		})
+	})
+	Convey("Given the test mission", t, func() {
+		buf, err := ioutil.ReadFile("../testdata/mission.sqm")
+		So(err, ShouldBeNil)
+		class := mustParse(string(buf))
+
+		Convey("It survives a round trip", func() {
+			var out bytes.Buffer
+			So(EncodeJSON(&out, class), ShouldBeNil)
+			var decoded Class
+			So(json.Unmarshal(out.Bytes(), &decoded), ShouldBeNil)
+			So(encodeText(&decoded), ShouldEqual, encodeText(class))
+		})
+	})
+}
@@ -77,6 +77,7 @@ type lexer struct {
 	pos   Pos       // current position in the input
 	width Pos       // width of last rune read
 	items chan item // channel of scanned items
+	done  <-chan struct{}
 }
 
 // Starting state of state machine
@@ -93,20 +94,39 @@ func makeLexer(name, input string) *lexer {
 }
 
 // Run the lexer as a state machine until state is nil
+// or the done channel is closed
 func (l *lexer) run() {
-	for state := startState; state != nil; {
+	for state := startState; state != nil && !l.stopped(); {
 		state = state(l)
 	}
 	close(l.items) //TODO: close 'emits' null type, maybe unwanted
 }
 
+// stopped reports whether the consumer is gone and lexing should stop
+func (l *lexer) stopped() bool {
+	select {
+	case <-l.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// send passes an item to the consumer, drops it if the consumer is gone
+func (l *lexer) send(i item) {
+	select {
+	case l.items <- i:
+	case <-l.done:
+	}
+}
+
 // Emits an item
 func (l *lexer) emit(t itemType) {
-	l.items <- item{
+	l.send(item{
 		typ: t,
 		pos: l.start,
 		val: l.input[l.start:l.pos],
-	}
+	})
 	l.start = l.pos
 }
 
@@ -172,7 +192,7 @@ func (l *lexer) acceptRun(valid string) int {
 }
 
 func (l *lexer) errorf(format string, args ...interface{}) stateFn {
-	l.items <- item{itemError, l.start, fmt.Sprintf(format, args...)}
+	l.send(item{itemError, l.start, fmt.Sprintf(format, args...)})
 	return nil
 }
 
@@ -219,7 +239,7 @@ func lexIdentifier(l *lexer) stateFn {
 	if !l.accept(alphaLower + alphaUpper) {
 		return l.errorf("Identifier does not start with an alpha character")
 	}
-	l.acceptRun(alphaLower + alphaUpper + digits)
+	l.acceptRun(alphaLower + alphaUpper + digits + "_")
 	if l.input[l.start:l.pos] == "class" {
 		l.emit(itemClass)
 		return lexSpaceBeforeClassIdentifier
@@ -366,6 +386,10 @@ func doString(l *lexer) bool {
 	l.emit(itemStringDelim)
 	for {
 		r := l.next()
+		if r == eof {
+			l.errorf("Unclosed string")
+			return false
+		}
 		if r != '"' {
 			continue
 		} else {
@@ -373,10 +397,6 @@ func doString(l *lexer) bool {
 				l.next()
 				continue
 			}
-			if r == eof {
-				l.errorf("Unclosed string")
-				return false
-			}
 			l.backup()
 			l.emit(itemString)
 			break
@@ -434,7 +454,7 @@ func lexClassIdentifier(l *lexer) stateFn {
 	if !l.accept(alphaLower + alphaUpper) {
 		return l.errorf("Class identifier does not start with an alpha character")
 	}
-	l.acceptRun(alphaLower + alphaUpper + digits)
+	l.acceptRun(alphaLower + alphaUpper + digits + "_")
 	l.emit(itemIdentifier)
 	return lexClassOpenBracket
 }
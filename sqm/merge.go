@@ -0,0 +1,284 @@
+package sqm
+
+import (
+	"strconv"
+)
+
+// Conflict is a change made differently on both sides of a merge.
+// Values are formatted like in an Edit, an empty value with the
+// corresponding Has flag unset means the element does not exist.
+type Conflict struct {
+	Path      string
+	Msg       string
+	Base      string
+	Ours      string
+	Theirs    string
+	HasBase   bool
+	HasOurs   bool
+	HasTheirs bool
+}
+
+func (c Conflict) String() string {
+	return c.Path + ": " + c.Msg
+}
+
+// Merge performs a three-way merge of ours and theirs with their common
+// ancestor base. ItemN classes are handled as collections: items added on
+// either side are kept, items are matched by content like in Diff.
+// The result is renumbered, see Renumber.
+// Conflicting changes are resolved in favour of ours and reported.
+func Merge(base, ours, theirs *Class) (*Class, []Conflict) {
+	m := &merger{}
+	result := m.mergeClass(base, ours, theirs, "")
+	Renumber(result)
+	return result, m.conflicts
+}
+
+type merger struct {
+	conflicts []Conflict
+}
+
+func (m *merger) conflict(c Conflict) {
+	m.conflicts = append(m.conflicts, c)
+}
+
+func (m *merger) mergeClass(base, ours, theirs *Class, path string) *Class {
+	if base == nil {
+		base = &Class{}
+	}
+	result := &Class{Name: ours.Name}
+	m.mergeProps(base, ours, theirs, result, path)
+	m.mergeArrprops(base, ours, theirs, result, path)
+	m.mergeClasses(base, ours, theirs, result, path)
+	for _, c := range result.Classes {
+		c.parent = result
+	}
+	return result
+}
+
+func propNames(lists ...[]*Property) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, props := range lists {
+		for _, p := range props {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				names = append(names, p.Name)
+			}
+		}
+	}
+	return names
+}
+
+func equalProp(a, b *Property) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Typ == b.Typ && a.Value == b.Value
+}
+
+func propValue(p *Property) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	return p.Value, true
+}
+
+func (m *merger) mergeProps(base, ours, theirs, result *Class, path string) {
+	for _, name := range propNames(ours.Props, theirs.Props, base.Props) {
+		b := findProp(base.Props, name)
+		o := findProp(ours.Props, name)
+		t := findProp(theirs.Props, name)
+		var v *Property
+		switch {
+		case equalProp(o, t):
+			v = o
+		case equalProp(b, o):
+			v = t
+		case equalProp(b, t):
+			v = o
+		default:
+			v = o
+			// item counts are recomputed after merging
+			if name != "items" {
+				c := Conflict{Path: joinPath(path, name), Msg: "property changed on both sides"}
+				c.Base, c.HasBase = propValue(b)
+				c.Ours, c.HasOurs = propValue(o)
+				c.Theirs, c.HasTheirs = propValue(t)
+				m.conflict(c)
+			}
+		}
+		if v != nil {
+			result.Props = append(result.Props, &Property{v.Name, v.Typ, v.Value})
+		}
+	}
+}
+
+func arrpropNames(lists ...[]*ArrayProperty) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, props := range lists {
+		for _, p := range props {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				names = append(names, p.Name)
+			}
+		}
+	}
+	return names
+}
+
+func equalArrprop(a, b *ArrayProperty) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Typ == b.Typ && formatValues(a.Values) == formatValues(b.Values)
+}
+
+func arrpropValue(p *ArrayProperty) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	return formatValues(p.Values), true
+}
+
+func (m *merger) mergeArrprops(base, ours, theirs, result *Class, path string) {
+	for _, name := range arrpropNames(ours.Arrprops, theirs.Arrprops, base.Arrprops) {
+		b := findArrprop(base.Arrprops, name)
+		o := findArrprop(ours.Arrprops, name)
+		t := findArrprop(theirs.Arrprops, name)
+		var v *ArrayProperty
+		switch {
+		case equalArrprop(o, t):
+			v = o
+		case equalArrprop(b, o):
+			v = t
+		case equalArrprop(b, t):
+			v = o
+		default:
+			v = o
+			c := Conflict{Path: joinPath(path, name), Msg: "array property changed on both sides"}
+			c.Base, c.HasBase = arrpropValue(b)
+			c.Ours, c.HasOurs = arrpropValue(o)
+			c.Theirs, c.HasTheirs = arrpropValue(t)
+			m.conflict(c)
+		}
+		if v != nil {
+			values := make([]string, len(v.Values))
+			copy(values, v.Values)
+			result.Arrprops = append(result.Arrprops, &ArrayProperty{v.Name, v.Typ, values})
+		}
+	}
+}
+
+func (m *merger) mergeClasses(base, ours, theirs, result *Class, path string) {
+	baseOfOurs := matchMap(base.Classes, ours.Classes)
+	baseOfTheirs := matchMap(base.Classes, theirs.Classes)
+	theirsOfBase := make(map[*Class]*Class)
+	for t, b := range baseOfTheirs {
+		theirsOfBase[b] = t
+	}
+	usedTheirs := make(map[*Class]bool)
+	addedOurs := make(map[string]bool)
+
+	for _, o := range ours.Classes {
+		b := baseOfOurs[o]
+		var t *Class
+		if b != nil {
+			t = theirsOfBase[b]
+		} else if !IsItemName(o.Name) {
+			// added on both sides
+			for _, c := range theirs.Classes {
+				if c.Name == o.Name && baseOfTheirs[c] == nil && !usedTheirs[c] {
+					t = c
+					break
+				}
+			}
+		}
+		p := joinPath(path, o.Name)
+		switch {
+		case t != nil:
+			usedTheirs[t] = true
+			result.Classes = append(result.Classes, m.mergeClass(b, o, t, p))
+		case b != nil:
+			// deleted in theirs
+			if fingerprint(b) != fingerprint(o) {
+				m.conflict(Conflict{Path: p, Msg: "class modified in ours but deleted in theirs", HasBase: true, HasOurs: true})
+				result.Classes = append(result.Classes, copyClass(o))
+			}
+		default:
+			addedOurs[fingerprint(o)] = true
+			result.Classes = append(result.Classes, copyClass(o))
+		}
+	}
+	for _, t := range theirs.Classes {
+		if usedTheirs[t] {
+			continue
+		}
+		p := joinPath(path, t.Name)
+		if b := baseOfTheirs[t]; b != nil {
+			// deleted in ours
+			if fingerprint(b) != fingerprint(t) {
+				m.conflict(Conflict{Path: p, Msg: "class deleted in ours but modified in theirs", HasBase: true, HasTheirs: true})
+				result.Classes = append(result.Classes, copyClass(t))
+			}
+			continue
+		}
+		if IsItemName(t.Name) && addedOurs[fingerprint(t)] {
+			// same item added on both sides
+			continue
+		}
+		result.Classes = append(result.Classes, copyClass(t))
+	}
+}
+
+// matchMap maps the classes of other to their matching class in base
+func matchMap(base, other []*Class) map[*Class]*Class {
+	pairs, _, _ := MatchClasses(base, other)
+	m := make(map[*Class]*Class)
+	for _, pair := range pairs {
+		m[pair[1]] = pair[0]
+	}
+	return m
+}
+
+func copyClass(c *Class) *Class {
+	n := &Class{Name: c.Name}
+	for _, p := range c.Props {
+		n.Props = append(n.Props, &Property{p.Name, p.Typ, p.Value})
+	}
+	for _, p := range c.Arrprops {
+		values := make([]string, len(p.Values))
+		copy(values, p.Values)
+		n.Arrprops = append(n.Arrprops, &ArrayProperty{p.Name, p.Typ, values})
+	}
+	for _, sub := range c.Classes {
+		s := copyClass(sub)
+		s.parent = n
+		n.Classes = append(n.Classes, s)
+	}
+	return n
+}
+
+// Copy returns a deep copy of the class.
+func (c *Class) Copy() *Class {
+	return copyClass(c)
+}
+
+// Renumber renames the ItemN subclasses of c and all its descendants to
+// Item0, Item1, ... in their current order and updates the items counts.
+func Renumber(c *Class) {
+	n := 0
+	for _, sub := range c.Classes {
+		if IsItemName(sub.Name) {
+			sub.Name = "Item" + strconv.Itoa(n)
+			n++
+		}
+		Renumber(sub)
+	}
+	if p := findProp(c.Props, "items"); p != nil {
+		p.Value = strconv.Itoa(n)
+	} else if n > 0 {
+		c.Props = append([]*Property{&Property{"items", TNumber, strconv.Itoa(n)}}, c.Props...)
+	}
+}
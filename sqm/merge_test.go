@@ -0,0 +1,116 @@
+package sqm
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+const mergeBase = `class Intel { year=2009; hour=6; };
+class Vehicles
+{
+	items=2;
+	class Item0 { position[]={1,2,3}; text="alpha"; skill=0.5; };
+	class Item1 { position[]={4,5,6}; text="bravo"; skill=0.5; };
+};
+`
+
+func TestMerge(t *testing.T) {
+	Convey("Given items added on both sides", t, func() {
+		ours := mustParse(`class Intel { year=2009; hour=6; };
+class Vehicles
+{
+	items=3;
+	class Item0 { position[]={1,2,3}; text="alpha"; skill=0.5; };
+	class Item1 { position[]={0,0,0}; text="ours"; skill=0.5; };
+	class Item2 { position[]={4,5,6}; text="bravo"; skill=0.5; };
+};
+`)
+		theirs := mustParse(`class Intel { year=2009; hour=6; };
+class Vehicles
+{
+	items=3;
+	class Item0 { position[]={1,2,3}; text="alpha"; skill=0.5; };
+	class Item1 { position[]={4,5,6}; text="bravo"; skill=0.5; };
+	class Item2 { position[]={9,9,9}; text="theirs"; skill=0.5; };
+};
+`)
+		merged, conflicts := Merge(mustParse(mergeBase), ours, theirs)
+		Convey("There should be no conflicts", func() {
+			So(len(conflicts), ShouldEqual, 0)
+		})
+		Convey("All items are kept and renumbered", func() {
+			vehs := merged.Classes[1]
+			So(vehs.Props[0].Value, ShouldEqual, "4")
+			So(len(vehs.Classes), ShouldEqual, 4)
+			So(vehs.Classes[1].Props[0].Value, ShouldEqual, "ours")
+			So(vehs.Classes[3].Name, ShouldEqual, "Item3")
+			So(vehs.Classes[3].Props[0].Value, ShouldEqual, "theirs")
+		})
+	})
+
+	Convey("Given different properties changed on both sides", t, func() {
+		ours := mustParse(`class Intel { year=2010; hour=6; };
+class Vehicles
+{
+	items=2;
+	class Item0 { position[]={1,2,3}; text="alpha"; skill=0.5; };
+	class Item1 { position[]={4,5,6}; text="bravo"; skill=0.9; };
+};
+`)
+		theirs := mustParse(`class Intel { year=2009; hour=8; };
+class Vehicles
+{
+	items=1;
+	class Item0 { position[]={4,5,7}; text="bravo"; skill=0.5; };
+};
+`)
+		merged, conflicts := Merge(mustParse(mergeBase), ours, theirs)
+		Convey("There should be no conflicts", func() {
+			So(len(conflicts), ShouldEqual, 0)
+		})
+		Convey("Both changes are applied", func() {
+			So(merged.Classes[0].Props[0].Value, ShouldEqual, "2010")
+			So(merged.Classes[0].Props[1].Value, ShouldEqual, "8")
+			vehs := merged.Classes[1]
+			So(len(vehs.Classes), ShouldEqual, 1)
+			So(vehs.Props[0].Value, ShouldEqual, "1")
+			So(vehs.Classes[0].Arrprops[0].Values, ShouldResemble, []string{"4", "5", "7"})
+			So(vehs.Classes[0].Props[1].Value, ShouldEqual, "0.9")
+		})
+	})
+
+	Convey("Given the same property changed on both sides", t, func() {
+		ours := mustParse(`class Intel { year=2010; hour=6; };`)
+		theirs := mustParse(`class Intel { year=2011; hour=6; };`)
+		merged, conflicts := Merge(mustParse(`class Intel { year=2009; hour=6; };`), ours, theirs)
+		Convey("A conflict is reported", func() {
+			So(len(conflicts), ShouldEqual, 1)
+			So(conflicts[0], ShouldResemble, Conflict{
+				Path:      "Intel/year",
+				Msg:       "property changed on both sides",
+				Base:      "2009",
+				Ours:      "2010",
+				Theirs:    "2011",
+				HasBase:   true,
+				HasOurs:   true,
+				HasTheirs: true,
+			})
+		})
+		Convey("Ours wins", func() {
+			So(merged.Classes[0].Props[0].Value, ShouldEqual, "2010")
+		})
+	})
+}
+
+func TestRenumber(t *testing.T) {
+	Convey("Given a class with gaps in item names", t, func() {
+		c := mustParse(`class Markers { items=5; class Item3 { name="a"; }; class Item7 { name="b"; }; };`)
+		Renumber(c)
+		Convey("Items are renumbered", func() {
+			markers := c.Classes[0]
+			So(markers.Props[0].Value, ShouldEqual, "2")
+			So(markers.Classes[0].Name, ShouldEqual, "Item0")
+			So(markers.Classes[1].Name, ShouldEqual, "Item1")
+		})
+	})
+}
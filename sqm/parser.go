@@ -1,6 +1,7 @@
 package sqm
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -88,8 +89,12 @@ func (ep *parserError) Error() string {
 
 //TODO: Make better parser errors by using parserError fields
 func (p *Parser) makeParserError(s string) *parserError {
-	col, line := p.lexer.Position(p.buff.curr())
-	err := fmt.Sprintf("Input:%d:%d: %s, read: %q", line, col, s, p.buff.curr())
+	it := p.buff.curr()
+	if it == nil { // failed on the first item
+		it = p.buff.lookAhead()
+	}
+	col, line := p.lexer.Position(it)
+	err := fmt.Sprintf("Input:%d:%d: %s, read: %q", line, col, s, it)
 	return &parserError{s: err}
 }
 
@@ -207,6 +212,12 @@ func parseArrayPropertyValue(p *Parser) (pstateFn, *parserError) {
 		p.class.Arrprops = append(p.class.Arrprops, p.propBuff.arrprop)
 		p.propBuff.arrprop = nil
 		return parseInsideClass, nil
+	case itemCloseArray: //empty array
+		p.buff.next()
+		p.propBuff.arrprop.Typ = TNumber
+		p.class.Arrprops = append(p.class.Arrprops, p.propBuff.arrprop)
+		p.propBuff.arrprop = nil
+		return parseInsideClass, nil
 	default:
 		return nil, p.makeParserError("Unexpected token in array assignment")
 	}
@@ -259,6 +270,7 @@ func parseArrayPropertyNumberValues(p *Parser) *parserError {
 }
 
 func parsePropertyValue(p *Parser) (pstateFn, *parserError) {
+	p.ignoreSpace()
 	switch p.buff.lookAhead().typ {
 	case itemStringDelim:
 		p.propBuff.prop.Typ = TString
@@ -326,21 +338,35 @@ func parseInsideClass(p *Parser) (pstateFn, *parserError) {
 	return parseInsideClass, nil
 }
 
+// Run parses the input and returns the mission class.
 func (p *Parser) Run() (*Class, error) {
+	return p.RunContext(context.Background())
+}
+
+// RunContext parses the input like Run but stops as soon as ctx is done.
+// The lexer is always stopped before RunContext returns, even if parsing
+// failed early.
+func (p *Parser) RunContext(ctx context.Context) (*Class, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	l := p.lexer
+	l.done = ctx.Done()
 	go l.run()
 	var err *parserError
 
 	for state := pstartState; state != nil; {
+		if cerr := ctx.Err(); cerr != nil {
+			return nil, cerr
+		}
 		state, err = state(p)
 		if err != nil {
+			if cerr := ctx.Err(); cerr != nil {
+				return nil, cerr
+			}
 			return nil, err
-			break
 		}
 	}
-	if err != nil {
-		return nil, err
-	}
 
 	return p.class, nil
 }
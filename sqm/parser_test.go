@@ -1,8 +1,11 @@
 package sqm
 
 import (
+	"context"
 	"io/ioutil"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestStructure(t *testing.T) {
@@ -96,6 +99,34 @@ var parseTests = []parseTest{
 		},
 	},
 
+	{
+		"underscore identifiers", "class my_class { my_value = 1; };",
+		tclass{"mission",
+			[]Property{},
+			[]ArrayProperty{},
+			[]tclass{
+				{"my_class",
+					[]Property{
+						{"my_value", TNumber, "1"},
+					},
+					[]ArrayProperty{},
+					[]tclass{},
+				},
+			},
+		},
+	},
+
+	{
+		"empty array attributes", "arr[]={};",
+		tclass{"mission",
+			[]Property{},
+			[]ArrayProperty{
+				{"arr", TNumber, nil},
+			},
+			[]tclass{},
+		},
+	},
+
 	{
 		"array float attributes", "arr[]={1.2,2.3,3.4};",
 		tclass{"mission",
@@ -166,6 +197,20 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestClassPath(t *testing.T) {
+	p := MakeParser("class Mission { class Groups { class Item0 {}; }; };")
+	c, err := p.Run()
+	if err != nil {
+		t.Fatalf("Parser returned with error %q", err)
+	}
+	if path := c.Path(); path != "" {
+		t.Errorf("Base class path is %q", path)
+	}
+	if path := c.Classes[0].Classes[0].Classes[0].Path(); path != "Mission/Groups/Item0" {
+		t.Errorf("Wrong path %q", path)
+	}
+}
+
 func TestParseSimple(t *testing.T) {
 	p := MakeParser("class testclass { version=11; };")
 	c, err := p.Run()
@@ -309,3 +354,44 @@ func BenchmarkParseMissionSQMReadfile(b *testing.B) {
 		}
 	}
 }
+
+var malformedInputs = []string{
+	"class a { x=1; }; }; class b { y=2; z=3; w=4; v=5; u=6; t=7; s=8; r=9; q=10; p=11; o=12; };",
+	"class a { x=1; y=\"unclosed; z=2; w=3; };",
+	"class a { arr[]={1,\"two\",3}; b=1; c=2; d=3; e=4; f=5; g=6; h=7; i=8; j=9; k=10; };",
+	"class a { class b { x=1; };",
+	"x=1; y==2; z=3; w=4; v=5; u=6; t=7; s=8; r=9; q=10; p=11; o=12; n=13; m=14;",
+	"class { x=1; };",
+	"arr[]={1,2,; b=1; c=2; d=3; e=4; f=5; g=6; h=7; i=8; j=9; k=10; l=11;",
+}
+
+func TestParseMalformedNoGoroutineLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 5000; i++ {
+		input := malformedInputs[i%len(malformedInputs)]
+		p := MakeParser(input)
+		if _, err := p.Run(); err == nil {
+			t.Fatalf("Parser accepted malformed input %q", input)
+		}
+	}
+	// Give stopped lexers a moment to return
+	var after int
+	for i := 0; i < 100; i++ {
+		after = runtime.NumGoroutine()
+		if after <= before {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Goroutines leaked, before: %d, after: %d", before, after)
+}
+
+func TestParseCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := MakeParser("class a { x=1; };")
+	_, err := p.RunContext(ctx)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled but got %v", err)
+	}
+}
@@ -39,6 +39,18 @@ func (c Class) String() string {
 	return fmt.Sprintf("class (name: %s), props: %s, arrprops: %s, classes: %s\n", c.Name, c.Props, c.Arrprops, c.Classes)
 }
 
+// Path returns the slash separated names of the class and its parents,
+// without the base class, e.g. Mission/Groups/Item0.
+func (c *Class) Path() string {
+	if c.parent == nil {
+		return ""
+	}
+	if parent := c.parent.Path(); parent != "" {
+		return parent + "/" + c.Name
+	}
+	return c.Name
+}
+
 func (t PropType) String() string {
 	switch t {
 	case TString:
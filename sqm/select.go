@@ -0,0 +1,164 @@
+package sqm
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Selector matches classes by their path below a base class.
+//
+// A selector is a slash separated list of class name patterns in the
+// syntax of path.Match, "**" matches any number of classes. A pattern may
+// be followed by conditions in brackets: [name] requires a property or
+// array property, [name=pattern] one whose value matches the pattern.
+// Names are compared case insensitive like the game does, e.g.
+//
+//	Mission/Groups/*/Vehicles/*[player=PLAY*]
+//	**/Waypoints/*[type=SAD]
+type Selector struct {
+	steps []selectorStep
+}
+
+type selectorStep struct {
+	pattern    string
+	any        bool
+	conditions []selectorCondition
+}
+
+type selectorCondition struct {
+	name    string
+	pattern string
+	exists  bool
+}
+
+// ParseSelector parses a selector.
+func ParseSelector(s string) (*Selector, error) {
+	sel := &Selector{}
+	for _, part := range strings.Split(strings.Trim(s, "/"), "/") {
+		step := selectorStep{}
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			conds := part[i:]
+			part = part[:i]
+			for conds != "" {
+				end := strings.IndexByte(conds, ']')
+				if conds[0] != '[' || end < 0 {
+					return nil, fmt.Errorf("Invalid condition %q in selector %q", conds, s)
+				}
+				cond := selectorCondition{exists: true}
+				cond.name = strings.ToLower(conds[1:end])
+				if eq := strings.IndexByte(cond.name, '='); eq >= 0 {
+					cond.pattern = cond.name[eq+1:]
+					cond.name = cond.name[:eq]
+					cond.exists = false
+				}
+				step.conditions = append(step.conditions, cond)
+				conds = conds[end+1:]
+			}
+		}
+		step.pattern = strings.ToLower(part)
+		if step.pattern == "" {
+			return nil, fmt.Errorf("Empty class name in selector %q", s)
+		}
+		step.any = step.pattern == "**"
+		if _, err := path.Match(step.pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid pattern %q in selector %q", part, s)
+		}
+		for _, cond := range step.conditions {
+			if _, err := path.Match(cond.pattern, ""); err != nil {
+				return nil, fmt.Errorf("Invalid pattern %q in selector %q", cond.pattern, s)
+			}
+		}
+		sel.steps = append(sel.steps, step)
+	}
+	return sel, nil
+}
+
+// Select returns the classes below c matching the selector in file order.
+func Select(c *Class, selector string) ([]*Class, error) {
+	sel, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return sel.Select(c), nil
+}
+
+// Select returns the classes below c matching the selector in file order.
+func (sel *Selector) Select(c *Class) []*Class {
+	var res []*Class
+	var walk func(c *Class, chain []*Class)
+	walk = func(c *Class, chain []*Class) {
+		for _, sub := range c.Classes {
+			next := append(chain, sub)
+			if sel.matches(next, sel.steps) {
+				res = append(res, sub)
+			}
+			walk(sub, next)
+		}
+	}
+	walk(c, nil)
+	return res
+}
+
+// Matches reports whether the chain of classes from below the base class
+// to a class matches the selector.
+func (sel *Selector) Matches(chain []*Class) bool {
+	return sel.matches(chain, sel.steps)
+}
+
+func (sel *Selector) matches(chain []*Class, steps []selectorStep) bool {
+	if len(steps) == 0 {
+		return len(chain) == 0
+	}
+	step := steps[0]
+	if step.any {
+		for i := 0; i <= len(chain); i++ {
+			if sel.matches(chain[i:], steps[1:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(chain) == 0 || !step.match(chain[0]) {
+		return false
+	}
+	return sel.matches(chain[1:], steps[1:])
+}
+
+func (step selectorStep) match(c *Class) bool {
+	if ok, _ := path.Match(step.pattern, strings.ToLower(c.Name)); !ok {
+		return false
+	}
+	for _, cond := range step.conditions {
+		if !cond.match(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (cond selectorCondition) match(c *Class) bool {
+	for _, p := range c.Props {
+		if strings.ToLower(p.Name) == cond.name {
+			if cond.exists {
+				return true
+			}
+			ok, _ := path.Match(cond.pattern, strings.ToLower(unescapeString(p.Value)))
+			return ok
+		}
+	}
+	for _, p := range c.Arrprops {
+		if strings.ToLower(p.Name) == cond.name {
+			if cond.exists {
+				return true
+			}
+			for _, v := range p.Values {
+				if ok, _ := path.Match(cond.pattern, strings.ToLower(unescapeString(v))); ok {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package sqm
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func paths(classes []*Class) []string {
+	var res []string
+	for _, c := range classes {
+		res = append(res, c.Path())
+	}
+	return res
+}
+
+func TestSelect(t *testing.T) {
+	Convey("Given a mission", t, func() {
+		class := mustParse(`class Mission {
+	class Groups {
+		class Item0 {
+			side="WEST";
+			class Vehicles {
+				class Item0 { player="PLAY CDG"; text="lead"; };
+				class Item1 { text="medic"; };
+			};
+			class Waypoints { class Item0 { type="SAD"; }; };
+		};
+	};
+	class Markers { class Item0 { name="obj"; }; };
+};`)
+
+		Convey("Patterns match class names case insensitive", func() {
+			res, err := Select(class, "mission/Groups/*/vehicles/*")
+			So(err, ShouldBeNil)
+			So(paths(res), ShouldResemble, []string{"Mission/Groups/Item0/Vehicles/Item0", "Mission/Groups/Item0/Vehicles/Item1"})
+		})
+		Convey("Double stars match any depth", func() {
+			res, _ := Select(class, "**/Item0")
+			So(res, ShouldHaveLength, 4)
+			res, _ = Select(class, "Mission/**")
+			So(res, ShouldHaveLength, 10)
+		})
+		Convey("Conditions filter by properties", func() {
+			res, _ := Select(class, "**/Vehicles/*[player=play*]")
+			So(paths(res), ShouldResemble, []string{"Mission/Groups/Item0/Vehicles/Item0"})
+			res, _ = Select(class, "**/*[text][player]")
+			So(res, ShouldHaveLength, 1)
+			res, _ = Select(class, "**/*[side=EAST]")
+			So(res, ShouldBeEmpty)
+		})
+		Convey("Invalid selectors are rejected", func() {
+			for _, s := range []string{"Mission//Groups", "Mission/[", "Mission/*[side", "Mission/a[b=[]"} {
+				_, err := Select(class, s)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
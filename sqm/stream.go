@@ -0,0 +1,334 @@
+package sqm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Handler receives the events of a StreamParser in document order.
+// Returning an error from any method aborts parsing with that error.
+type Handler interface {
+	// StartClass is called for every class definition.
+	StartClass(name string) error
+	// Property is called for every string or number property.
+	Property(name string, typ PropType, value string) error
+	// ArrayProperty is called for every array property.
+	ArrayProperty(name string, typ PropType, values []string) error
+	// EndClass is called when the last opened class is closed.
+	EndClass() error
+}
+
+// StreamParser reads sqm input in chunks and reports its contents to a
+// Handler instead of building a Class tree. Memory usage is independent
+// of the input size.
+// The implicit main class is not reported, events start with its contents.
+type StreamParser struct {
+	r     *bufio.Reader
+	h     Handler
+	line  int
+	col   int
+	depth int
+	buf   bytes.Buffer
+}
+
+// NewStreamParser creates a StreamParser reading from r.
+func NewStreamParser(r io.Reader, h Handler) *StreamParser {
+	return &StreamParser{
+		r:    bufio.NewReader(r),
+		h:    h,
+		line: 1,
+	}
+}
+
+// StreamError is returned for malformed input.
+type StreamError struct {
+	Line int
+	Col  int
+	Msg  string
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("Input:%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+func (s *StreamParser) errorf(format string, args ...interface{}) error {
+	return &StreamError{
+		Line: s.line,
+		Col:  s.col,
+		Msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Run reads the whole input and reports it to the handler.
+func (s *StreamParser) Run() error {
+	return s.RunContext(context.Background())
+}
+
+// RunContext reads the input like Run but stops as soon as ctx is done.
+func (s *StreamParser) RunContext(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.skipSpace()
+		r, err := s.next()
+		if err == io.EOF {
+			if s.depth > 0 {
+				return s.errorf("Unexpected EOF, unclosed class")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case isAlpha(r):
+			s.backup()
+			if err := s.parseStatement(); err != nil {
+				return err
+			}
+		case r == '}':
+			if err := s.parseClassClose(); err != nil {
+				return err
+			}
+		default:
+			return s.errorf("unrecognized character inside class: %#U", r)
+		}
+	}
+}
+
+func (s *StreamParser) next() (rune, error) {
+	r, _, err := s.r.ReadRune()
+	if err != nil {
+		return eof, err
+	}
+	if r == '\n' {
+		s.line++
+		s.col = 0
+	} else {
+		s.col++
+	}
+	return r, nil
+}
+
+// backup unreads the last rune, newlines are never unread
+func (s *StreamParser) backup() {
+	s.r.UnreadRune()
+	s.col--
+}
+
+func (s *StreamParser) peek() rune {
+	r, _, err := s.r.ReadRune()
+	if err != nil {
+		return eof
+	}
+	s.r.UnreadRune()
+	return r
+}
+
+func (s *StreamParser) skipSpace() {
+	for isSpace(s.peek()) {
+		s.next()
+	}
+}
+
+func (s *StreamParser) expect(want rune, msg string) error {
+	s.skipSpace()
+	if r, _ := s.next(); r != want {
+		return s.errorf(msg)
+	}
+	return nil
+}
+
+func (s *StreamParser) identifier() (string, error) {
+	s.buf.Reset()
+	r, _ := s.next()
+	if !isAlpha(r) {
+		return "", s.errorf("Identifier does not start with an alpha character")
+	}
+	s.buf.WriteRune(r)
+	for {
+		r := s.peek()
+		if !isAlpha(r) && strings.IndexRune(digits+"_", r) < 0 {
+			break
+		}
+		s.next()
+		s.buf.WriteRune(r)
+	}
+	return s.buf.String(), nil
+}
+
+func (s *StreamParser) parseStatement() error {
+	name, err := s.identifier()
+	if err != nil {
+		return err
+	}
+	if name == "class" && isSpace(s.peek()) {
+		return s.parseClassOpen()
+	}
+	if s.peek() == '[' {
+		s.next()
+		if r, _ := s.next(); r != ']' {
+			return s.errorf("Missing closing array bracket")
+		}
+		return s.parseArrayProperty(name)
+	}
+	return s.parseProperty(name)
+}
+
+func (s *StreamParser) parseClassOpen() error {
+	s.skipSpace()
+	name, err := s.identifier()
+	if err != nil {
+		return s.errorf("Class identifier does not start with an alpha character")
+	}
+	if err := s.expect('{', "Missing class opening bracket"); err != nil {
+		return err
+	}
+	s.depth++
+	return s.h.StartClass(name)
+}
+
+func (s *StreamParser) parseClassClose() error {
+	if s.depth == 0 {
+		return s.errorf("Closing base class not allowed, unclosed class")
+	}
+	if err := s.expect(';', "Missing semicolon after closing bracket"); err != nil {
+		return err
+	}
+	s.depth--
+	return s.h.EndClass()
+}
+
+func (s *StreamParser) parseProperty(name string) error {
+	if err := s.expect('=', "assignment missing equals"); err != nil {
+		return err
+	}
+	s.skipSpace()
+	var typ PropType
+	var value string
+	var err error
+	switch r := s.peek(); {
+	case r == '"':
+		typ = TString
+		value, err = s.stringValue()
+	case isNumber(r):
+		typ = TNumber
+		value, err = s.numberValue()
+	default:
+		return s.errorf("unrecognized character in assignment value: %#U", r)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.expect(';', "Unclosed assignment"); err != nil {
+		return err
+	}
+	return s.h.Property(name, typ, value)
+}
+
+func (s *StreamParser) parseArrayProperty(name string) error {
+	if err := s.expect('=', "Array assignment missing equals"); err != nil {
+		return err
+	}
+	if err := s.expect('{', "Missing array open curly bracket"); err != nil {
+		return err
+	}
+	typ := TNumber
+	var values []string
+	for i := 0; ; i++ {
+		s.skipSpace()
+		r := s.peek()
+		if r == '}' && i == 0 {
+			s.next()
+			break
+		}
+		var value string
+		var err error
+		switch {
+		case r == '"':
+			if i > 0 && typ != TString {
+				return s.errorf("Mixed types inside array")
+			}
+			typ = TString
+			value, err = s.stringValue()
+		case isNumber(r):
+			if i > 0 && typ != TNumber {
+				return s.errorf("Mixed types inside array")
+			}
+			value, err = s.numberValue()
+		default:
+			return s.errorf("unrecognized character inside array: %#U", r)
+		}
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+		s.skipSpace()
+		r, _ = s.next()
+		if r == '}' {
+			break
+		}
+		if r != ',' {
+			return s.errorf("Missing array seperator")
+		}
+	}
+	if err := s.expect(';', "Missing array closing semicolon"); err != nil {
+		return err
+	}
+	return s.h.ArrayProperty(name, typ, values)
+}
+
+// stringValue reads a quoted string, doubled quotes are kept escaped
+// like the Parser does.
+func (s *StreamParser) stringValue() (string, error) {
+	s.next() // opening quote
+	s.buf.Reset()
+	for {
+		r, err := s.next()
+		if err != nil {
+			return "", s.errorf("Unclosed string")
+		}
+		if r == '"' {
+			if s.peek() != '"' {
+				break
+			}
+			s.next()
+			s.buf.WriteString("\"\"")
+			continue
+		}
+		s.buf.WriteRune(r)
+	}
+	return s.buf.String(), nil
+}
+
+func (s *StreamParser) numberValue() (string, error) {
+	s.buf.Reset()
+	if r := s.peek(); r == '+' || r == '-' {
+		s.next()
+		s.buf.WriteRune(r)
+	}
+	hasDigits := false
+	for strings.IndexRune(digits, s.peek()) >= 0 {
+		r, _ := s.next()
+		s.buf.WriteRune(r)
+		hasDigits = true
+	}
+	if s.peek() == '.' {
+		s.next()
+		s.buf.WriteRune('.')
+		for strings.IndexRune(digits, s.peek()) >= 0 {
+			r, _ := s.next()
+			s.buf.WriteRune(r)
+			hasDigits = true
+		}
+	}
+	if !hasDigits {
+		return "", s.errorf("Missing number")
+	}
+	return s.buf.String(), nil
+}
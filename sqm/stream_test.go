@@ -0,0 +1,107 @@
+package sqm
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// treeHandler builds a class tree from stream events
+type treeHandler struct {
+	class *Class
+}
+
+func (h *treeHandler) StartClass(name string) error {
+	c := &Class{Name: name, parent: h.class}
+	h.class.Classes = append(h.class.Classes, c)
+	h.class = c
+	return nil
+}
+
+func (h *treeHandler) Property(name string, typ PropType, value string) error {
+	h.class.Props = append(h.class.Props, &Property{name, typ, value})
+	return nil
+}
+
+func (h *treeHandler) ArrayProperty(name string, typ PropType, values []string) error {
+	h.class.Arrprops = append(h.class.Arrprops, &ArrayProperty{name, typ, values})
+	return nil
+}
+
+func (h *treeHandler) EndClass() error {
+	h.class = h.class.parent
+	return nil
+}
+
+func TestStreamParse(t *testing.T) {
+	for _, test := range parseTests {
+		h := &treeHandler{class: &Class{Name: "mission"}}
+		s := NewStreamParser(strings.NewReader(test.input), h)
+		if err := s.Run(); err != nil {
+			t.Errorf("%s: Stream parser returned with error %q", test.name, err)
+			continue
+		}
+		testClass(t, test.class, h.class)
+	}
+}
+
+func TestStreamParseMissionSQM(t *testing.T) {
+	buf, err := ioutil.ReadFile("../testdata/mission.sqm")
+	if err != nil {
+		t.Fatalf("Could not open mission.sqm")
+	}
+	p := MakeParser(string(buf))
+	expected, err := p.Run()
+	if err != nil {
+		t.Fatalf("Parser returned with error %q", err)
+	}
+	h := &treeHandler{class: &Class{Name: "mission"}}
+	s := NewStreamParser(iotest.OneByteReader(strings.NewReader(string(buf))), h)
+	if err := s.Run(); err != nil {
+		t.Fatalf("Stream parser returned with error %q", err)
+	}
+	if !reflect.DeepEqual(expected, h.class) {
+		t.Errorf("Stream parser result differs from parser result")
+	}
+}
+
+func TestStreamParseEmptyArray(t *testing.T) {
+	h := &treeHandler{class: &Class{Name: "mission"}}
+	s := NewStreamParser(strings.NewReader("synchronizations[]={};"), h)
+	if err := s.Run(); err != nil {
+		t.Fatalf("Stream parser returned with error %q", err)
+	}
+	if len(h.class.Arrprops) != 1 || len(h.class.Arrprops[0].Values) != 0 {
+		t.Errorf("Empty array not parsed: %v", h.class.Arrprops)
+	}
+}
+
+func TestStreamParseErrors(t *testing.T) {
+	for _, input := range malformedInputs {
+		h := &treeHandler{class: &Class{Name: "mission"}}
+		s := NewStreamParser(strings.NewReader(input), h)
+		err := s.Run()
+		if err == nil {
+			t.Errorf("Stream parser accepted malformed input %q", input)
+			continue
+		}
+		if _, ok := err.(*StreamError); !ok {
+			t.Errorf("Expected StreamError but got %T", err)
+		}
+	}
+}
+
+func TestStreamParseErrorPosition(t *testing.T) {
+	h := &treeHandler{class: &Class{Name: "mission"}}
+	s := NewStreamParser(strings.NewReader("class a\n{\n  x=?;\n};"), h)
+	err := s.Run()
+	serr, ok := err.(*StreamError)
+	if !ok {
+		t.Fatalf("Expected StreamError but got %v", err)
+	}
+	if serr.Line != 3 {
+		t.Errorf("Line wrong %d", serr.Line)
+	}
+}
@@ -0,0 +1,178 @@
+package sqm
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	// ErrNoOpenClass is returned by EndClass if there is no class to close.
+	ErrNoOpenClass = errors.New("No open class to close")
+	// ErrUnclosedClass is returned by Close if classes are still open.
+	ErrUnclosedClass = errors.New("Unclosed class")
+)
+
+// Writer writes sqm output incrementally without building a Class tree.
+// The output is identical to the one of an Encoder for a class whose
+// array properties, properties and subclasses are written in this order.
+// After the first error all further calls return that error.
+type Writer struct {
+	w     io.Writer
+	level int
+	err   error
+}
+
+// NewWriter creates a Writer writing to w, starting inside the main class.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// BeginClass opens a new class, all following calls write into it
+// until it is closed by EndClass.
+func (w *Writer) BeginClass(name string) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.beginClass(name, w.level)
+	w.level++
+	return w.err
+}
+
+// EndClass closes the last opened class.
+func (w *Writer) EndClass() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.level == 0 {
+		w.err = ErrNoOpenClass
+		return w.err
+	}
+	w.level--
+	w.err = w.endClass(w.level)
+	return w.err
+}
+
+// WriteProperty writes a string or number property.
+func (w *Writer) WriteProperty(name string, typ PropType, value string) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.writeProperty(&Property{name, typ, value}, w.level)
+	return w.err
+}
+
+// WriteArray writes an array property.
+func (w *Writer) WriteArray(name string, typ PropType, values []string) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.writeArrProperty(&ArrayProperty{name, typ, values}, w.level)
+	return w.err
+}
+
+// Close checks that all classes were closed.
+// It does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.level != 0 {
+		return ErrUnclosedClass
+	}
+	return nil
+}
+
+func (w *Writer) writeString(s string) error {
+	_, err := w.w.Write([]byte(s))
+	return err
+}
+
+func (w *Writer) beginClass(name string, level int) error {
+	return w.writeString(indent(level) + "class " + name + LINEBREAK + indent(level) + "{" + LINEBREAK)
+}
+
+func (w *Writer) endClass(level int) error {
+	return w.writeString(indent(level) + "};" + LINEBREAK)
+}
+
+func (w *Writer) writeProperty(p *Property, level int) error {
+	var err error
+	switch p.Typ {
+	case TString:
+		err = w.writeString(indent(level) + p.Name + "=\"" + p.Value + "\";" + LINEBREAK)
+	case TNumber:
+		err = w.writeString(indent(level) + p.Name + "=" + p.Value + ";" + LINEBREAK)
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (w *Writer) writeArrProperty(arrProp *ArrayProperty, level int) error {
+	if arrProp.Name == "addOns" || arrProp.Name == "addOnsAuto" {
+		return w.writeAddonsArrProperty(arrProp, level)
+	} else {
+		return w.writeNormalArrProperty(arrProp, level)
+	}
+}
+
+func (w *Writer) writeAddonsArrProperty(arrProp *ArrayProperty, level int) error {
+	err := w.writeString(indent(level) + arrProp.Name + "[]=" + LINEBREAK + indent(level) + "{" + LINEBREAK)
+	if err != nil {
+		return err
+	}
+	for i, val := range arrProp.Values {
+		if i > 0 {
+			err = w.writeString("," + LINEBREAK)
+			if err != nil {
+				return err
+			}
+
+		}
+
+		err = w.writeString(indent(level+1) + "\"" + val + "\"")
+		if err != nil {
+			return err
+		}
+
+	}
+	err = w.writeString(LINEBREAK + indent(level) + "};" + LINEBREAK)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) writeNormalArrProperty(arrProp *ArrayProperty, level int) error {
+	err := w.writeString(indent(level) + arrProp.Name + "[]={")
+	if err != nil {
+		return err
+	}
+	for i, val := range arrProp.Values {
+		if i > 0 {
+			err = w.writeString(",")
+			if err != nil {
+				return err
+			}
+
+		}
+		if arrProp.Typ == TString {
+			err = w.writeString("\"" + val + "\"")
+			if err != nil {
+				return err
+			}
+		} else {
+			err := w.writeString(val)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = w.writeString("};" + LINEBREAK)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package sqm
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"testing"
+)
+
+func writeClass(w *Writer, class *Class) error {
+	for _, arrProp := range class.Arrprops {
+		if err := w.WriteArray(arrProp.Name, arrProp.Typ, arrProp.Values); err != nil {
+			return err
+		}
+	}
+	for _, prop := range class.Props {
+		if err := w.WriteProperty(prop.Name, prop.Typ, prop.Value); err != nil {
+			return err
+		}
+	}
+	for _, subclass := range class.Classes {
+		if err := w.BeginClass(subclass.Name); err != nil {
+			return err
+		}
+		if err := writeClass(w, subclass); err != nil {
+			return err
+		}
+		if err := w.EndClass(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	Convey("Given a fresh writer", t, func() {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		Convey("Writing a class with properties", func() {
+			So(w.WriteProperty("version", TNumber, "11"), ShouldBeNil)
+			So(w.BeginClass("myclass"), ShouldBeNil)
+			So(w.WriteArray("position", TNumber, []string{"1", "2", "3"}), ShouldBeNil)
+			So(w.WriteProperty("key", TString, "value"), ShouldBeNil)
+			So(w.EndClass(), ShouldBeNil)
+			So(w.Close(), ShouldBeNil)
+			Convey("Should write correct string", func() {
+				So(buf.String(), ShouldEqual,
+					"version=11;"+LINEBREAK+
+						"class myclass"+LINEBREAK+
+						"{"+LINEBREAK+
+						indent(1)+"position[]={1,2,3};"+LINEBREAK+
+						indent(1)+`key="value";`+LINEBREAK+
+						"};"+LINEBREAK)
+			})
+		})
+		Convey("Closing a class which was not opened", func() {
+			err := w.EndClass()
+			Convey("Should fail", func() {
+				So(err, ShouldEqual, ErrNoOpenClass)
+			})
+			Convey("Should keep failing", func() {
+				So(w.WriteProperty("key", TNumber, "1"), ShouldEqual, ErrNoOpenClass)
+			})
+		})
+		Convey("Closing the writer with an open class", func() {
+			So(w.BeginClass("myclass"), ShouldBeNil)
+			Convey("Should fail", func() {
+				So(w.Close(), ShouldEqual, ErrUnclosedClass)
+			})
+		})
+	})
+
+	Convey("Given the parsed mission.sqm", t, func() {
+		buf, err := ioutil.ReadFile("../testdata/mission.sqm")
+		So(err, ShouldBeNil)
+		class, err := MakeParser(string(buf)).Run()
+		So(err, ShouldBeNil)
+		Convey("Writer output should equal encoder output", func() {
+			var encBuf, wBuf bytes.Buffer
+			So(NewEncoder(&encBuf).Encode(class), ShouldBeNil)
+			w := NewWriter(&wBuf)
+			So(writeClass(w, class), ShouldBeNil)
+			So(w.Close(), ShouldBeNil)
+			So(wBuf.String(), ShouldEqual, encBuf.String())
+		})
+	})
+}
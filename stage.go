@@ -0,0 +1,375 @@
+package gosqm
+
+import (
+	"fmt"
+)
+
+// Names of the stages of a mission file.
+const (
+	StageMission    = "Mission"
+	StageIntro      = "Intro"
+	StageOutroWin   = "OutroWin"
+	StageOutroLoose = "OutroLoose"
+)
+
+// StageNames are the names of all stages in file order.
+var StageNames = []string{StageMission, StageIntro, StageOutroWin, StageOutroLoose}
+
+// Stage is a named stage of a mission file.
+type Stage struct {
+	Name    string
+	Mission *Mission
+}
+
+func (mf *MissionFile) stage(name string) **Mission {
+	switch name {
+	case StageMission:
+		return &mf.Mission
+	case StageIntro:
+		return &mf.Intro
+	case StageOutroWin:
+		return &mf.OutroWin
+	case StageOutroLoose:
+		return &mf.OutroLoose
+	}
+	return nil
+}
+
+// Stage returns the stage named name, nil if it is not set or the name is
+// unknown.
+func (mf *MissionFile) Stage(name string) *Mission {
+	if s := mf.stage(name); s != nil {
+		return *s
+	}
+	return nil
+}
+
+// SetStage sets the stage named name, nil removes it.
+func (mf *MissionFile) SetStage(name string, m *Mission) error {
+	s := mf.stage(name)
+	if s == nil {
+		return fmt.Errorf("Unknown stage %q", name)
+	}
+	*s = m
+	return nil
+}
+
+// Stages returns the stages which are set in file order.
+func (mf *MissionFile) Stages() []Stage {
+	var stages []Stage
+	for _, name := range StageNames {
+		if m := mf.Stage(name); m != nil {
+			stages = append(stages, Stage{name, m})
+		}
+	}
+	return stages
+}
+
+// IsEmpty reports whether the mission contains no groups, vehicles,
+// markers or sensors.
+func (m *Mission) IsEmpty() bool {
+	return len(m.Groups) == 0 && len(m.Vehicles) == 0 && len(m.Markers) == 0 && len(m.Sensors) == 0
+}
+
+// Entities is a selection of entities of a stage. Units are selected with
+// their group, Vehicles are empty vehicles.
+type Entities struct {
+	Groups   []*Group
+	Vehicles []*Vehicle
+	Markers  []*Marker
+	Sensors  []*Sensor
+}
+
+// Entities returns all entities of the mission.
+func (m *Mission) Entities() Entities {
+	return Entities{
+		Groups:   append([]*Group(nil), m.Groups...),
+		Vehicles: append([]*Vehicle(nil), m.Vehicles...),
+		Markers:  append([]*Marker(nil), m.Markers...),
+		Sensors:  append([]*Sensor(nil), m.Sensors...),
+	}
+}
+
+// CopyEntities appends copies of e to the mission and returns them.
+// References between the copied entities point to the copies, references
+// to other entities are kept if they are part of the mission and dropped
+// otherwise.
+func (m *Mission) CopyEntities(e Entities) Entities {
+	known := m.references()
+	copies := make(map[interface{}]interface{})
+	var res Entities
+	var waypoints []*Waypoint
+	var units []*Vehicle
+	for _, g := range e.Groups {
+		c := copyGroup(g, copies)
+		res.Groups = append(res.Groups, c)
+		waypoints = append(waypoints, g.Waypoints...)
+		units = append(units, g.Units...)
+	}
+	for _, v := range e.Vehicles {
+		c := copyVehicle(v)
+		copies[v] = c
+		res.Vehicles = append(res.Vehicles, c)
+	}
+	units = append(units, e.Vehicles...)
+	for _, marker := range e.Markers {
+		c := *marker
+		copies[marker] = &c
+		res.Markers = append(res.Markers, &c)
+	}
+	for _, s := range e.Sensors {
+		c := *s
+		if s.Effects != nil {
+			effects := *s.Effects
+			c.Effects = &effects
+		}
+		c.SyncWaypoints = nil
+		c.SyncSensors = nil
+		copies[s] = &c
+		res.Sensors = append(res.Sensors, &c)
+	}
+
+	// target returns the copy of an entity or the entity itself if it
+	// is part of the mission
+	target := func(entity interface{}) (interface{}, bool) {
+		if c, found := copies[entity]; found {
+			return c, true
+		}
+		return entity, known[entity]
+	}
+	for _, v := range units {
+		c := copies[v].(*Vehicle)
+		c.Markers = nil
+		for _, marker := range v.Markers {
+			if t, ok := target(marker); ok {
+				c.Markers = append(c.Markers, t.(*Marker))
+			}
+		}
+	}
+	for _, w := range waypoints {
+		c := copies[w].(*Waypoint)
+		if t, ok := target(w.Vehicle); ok && w.Vehicle != nil {
+			c.Vehicle = t.(*Vehicle)
+		} else {
+			c.Vehicle = nil
+		}
+		for _, o := range w.SyncWaypoints {
+			if t, ok := target(o); ok {
+				c.SyncWaypoint(t.(*Waypoint))
+			}
+		}
+		for _, s := range w.SyncSensors {
+			if t, ok := target(s); ok {
+				c.SyncSensor(t.(*Sensor))
+			}
+		}
+	}
+	for _, s := range e.Sensors {
+		c := copies[s].(*Sensor)
+		if t, ok := target(s.Vehicle); ok && s.Vehicle != nil {
+			c.Vehicle = t.(*Vehicle)
+		} else {
+			c.Vehicle = nil
+		}
+		for _, o := range s.SyncSensors {
+			if t, ok := target(o); ok {
+				c.SyncSensor(t.(*Sensor))
+			}
+		}
+		for _, w := range s.SyncWaypoints {
+			if t, ok := target(w); ok {
+				c.SyncWaypoint(t.(*Waypoint))
+			}
+		}
+	}
+
+	m.Groups = append(m.Groups, res.Groups...)
+	m.Vehicles = append(m.Vehicles, res.Vehicles...)
+	m.Markers = append(m.Markers, res.Markers...)
+	m.Sensors = append(m.Sensors, res.Sensors...)
+	return res
+}
+
+// MoveEntities moves e from the mission to dst. References between the
+// moved entities and the ones left behind are removed on both sides,
+// together with all other dangling references of both missions.
+func (m *Mission) MoveEntities(dst *Mission, e Entities) {
+	moved := make(map[interface{}]bool)
+	for _, g := range e.Groups {
+		moved[g] = true
+	}
+	for _, v := range e.Vehicles {
+		moved[v] = true
+	}
+	for _, marker := range e.Markers {
+		moved[marker] = true
+	}
+	for _, s := range e.Sensors {
+		moved[s] = true
+	}
+	var groups []*Group
+	for _, g := range m.Groups {
+		if moved[g] {
+			dst.Groups = append(dst.Groups, g)
+		} else {
+			groups = append(groups, g)
+		}
+	}
+	var vehicles []*Vehicle
+	for _, v := range m.Vehicles {
+		if moved[v] {
+			dst.Vehicles = append(dst.Vehicles, v)
+		} else {
+			vehicles = append(vehicles, v)
+		}
+	}
+	var markers []*Marker
+	for _, marker := range m.Markers {
+		if moved[marker] {
+			dst.Markers = append(dst.Markers, marker)
+		} else {
+			markers = append(markers, marker)
+		}
+	}
+	var sensors []*Sensor
+	for _, s := range m.Sensors {
+		if moved[s] {
+			dst.Sensors = append(dst.Sensors, s)
+		} else {
+			sensors = append(sensors, s)
+		}
+	}
+	m.Groups, m.Vehicles, m.Markers, m.Sensors = groups, vehicles, markers, sensors
+	m.RemoveDanglingReferences()
+	dst.RemoveDanglingReferences()
+}
+
+// RemoveDanglingReferences removes all references to vehicles, markers,
+// waypoints and sensors which are not part of the mission.
+func (m *Mission) RemoveDanglingReferences() {
+	known := m.references()
+	for _, v := range m.allVehicles() {
+		var markers []*Marker
+		for _, marker := range v.Markers {
+			if known[marker] {
+				markers = append(markers, marker)
+			}
+		}
+		v.Markers = markers
+	}
+	for _, g := range m.Groups {
+		for _, w := range g.Waypoints {
+			if !known[w.Vehicle] {
+				w.Vehicle = nil
+			}
+			w.SyncWaypoints = filterWaypoints(w.SyncWaypoints, known)
+			w.SyncSensors = filterSensors(w.SyncSensors, known)
+		}
+	}
+	for _, s := range m.Sensors {
+		if !known[s.Vehicle] {
+			s.Vehicle = nil
+		}
+		s.SyncWaypoints = filterWaypoints(s.SyncWaypoints, known)
+		s.SyncSensors = filterSensors(s.SyncSensors, known)
+	}
+}
+
+// references returns the set of all entities which can be referenced
+// inside the mission
+func (m *Mission) references() map[interface{}]bool {
+	known := make(map[interface{}]bool)
+	for _, v := range m.allVehicles() {
+		known[v] = true
+	}
+	for _, g := range m.Groups {
+		for _, w := range g.Waypoints {
+			known[w] = true
+		}
+	}
+	for _, marker := range m.Markers {
+		known[marker] = true
+	}
+	for _, s := range m.Sensors {
+		known[s] = true
+	}
+	return known
+}
+
+func filterWaypoints(list []*Waypoint, known map[interface{}]bool) []*Waypoint {
+	var res []*Waypoint
+	for _, w := range list {
+		if known[w] {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+func filterSensors(list []*Sensor, known map[interface{}]bool) []*Sensor {
+	var res []*Sensor
+	for _, s := range list {
+		if known[s] {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// copyGroup copies a group with its units and waypoints and records the
+// copies, references are not remapped
+func copyGroup(g *Group, copies map[interface{}]interface{}) *Group {
+	c := *g
+	c.Units = nil
+	c.Waypoints = nil
+	c.ExtraProps = nil
+	c.ExtraArrprops = nil
+	c.ExtraClasses = nil
+	for _, v := range g.Units {
+		u := copyVehicle(v)
+		copies[v] = u
+		c.Units = append(c.Units, u)
+	}
+	for _, w := range g.Waypoints {
+		wc := *w
+		if w.Effects != nil {
+			effects := *w.Effects
+			wc.Effects = &effects
+		}
+		wc.SyncWaypoints = nil
+		wc.SyncSensors = nil
+		copies[w] = &wc
+		c.Waypoints = append(c.Waypoints, &wc)
+	}
+	for _, prop := range g.ExtraProps {
+		p := *prop
+		c.ExtraProps = append(c.ExtraProps, &p)
+	}
+	for _, arrprop := range g.ExtraArrprops {
+		p := *arrprop
+		p.Values = append([]string(nil), arrprop.Values...)
+		c.ExtraArrprops = append(c.ExtraArrprops, &p)
+	}
+	for _, class := range g.ExtraClasses {
+		c.ExtraClasses = append(c.ExtraClasses, class.Copy())
+	}
+	return &c
+}
+
+func copyVehicle(v *Vehicle) *Vehicle {
+	c := *v
+	c.Skill = copyNumber(v.Skill)
+	c.Health = copyNumber(v.Health)
+	c.Fuel = copyNumber(v.Fuel)
+	c.Ammo = copyNumber(v.Ammo)
+	c.Markers = append([]*Marker(nil), v.Markers...)
+	return &c
+}
+
+// copyNumber returns a copy of an optional number, nil stays nil
+func copyNumber(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	return Number(*f)
+}
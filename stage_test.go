@@ -0,0 +1,160 @@
+package gosqm
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+func stageClassNames(mf *MissionFile, options EncoderOptions) []string {
+	var names []string
+	for _, c := range NewClassEncoder(options).EncodeToClass(mf).Classes {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestStages(t *testing.T) {
+	Convey("Given a mission file with a main mission and an empty outro", t, func() {
+		mission := &Mission{Groups: []*Group{&Group{Side: SideWest}}}
+		mf := &MissionFile{Version: "11", Mission: mission, OutroWin: &Mission{}}
+
+		Convey("Stages are found by name", func() {
+			So(mf.Stage(StageMission), ShouldEqual, mission)
+			So(mf.Stage(StageIntro), ShouldBeNil)
+			So(mf.Stage("Unknown"), ShouldBeNil)
+		})
+		Convey("Set stages are iterated in file order", func() {
+			stages := mf.Stages()
+			So(stages, ShouldHaveLength, 2)
+			So(stages[0].Name, ShouldEqual, StageMission)
+			So(stages[1].Name, ShouldEqual, StageOutroWin)
+		})
+		Convey("Stages can be set and removed", func() {
+			intro := &Mission{}
+			So(mf.SetStage(StageIntro, intro), ShouldBeNil)
+			So(mf.Intro, ShouldEqual, intro)
+			So(mf.SetStage(StageOutroWin, nil), ShouldBeNil)
+			So(mf.OutroWin, ShouldBeNil)
+			So(mf.SetStage("Unknown", intro), ShouldNotBeNil)
+		})
+		Convey("All stages are encoded by default", func() {
+			So(stageClassNames(mf, EncoderOptions{}), ShouldResemble, StageNames)
+		})
+		Convey("Empty stages can be omitted", func() {
+			So(stageClassNames(mf, EncoderOptions{OmitEmptyStages: true}), ShouldResemble, []string{StageMission})
+		})
+		Convey("Stages without intel are encoded without intel class", func() {
+			class := NewClassEncoder().EncodeToClass(mf)
+			for _, stage := range class.Classes {
+				for _, c := range stage.Classes {
+					So(c.Name, ShouldNotEqual, "Intel")
+				}
+			}
+		})
+	})
+}
+
+func TestDecodeStages(t *testing.T) {
+	Convey("Given a file with only a main mission", t, func() {
+		mf, err := NewDecoder(strings.NewReader("version=11;class Mission{};")).Decode()
+		So(err, ShouldBeNil)
+
+		Convey("Only the main mission is set", func() {
+			So(mf.Mission, ShouldNotBeNil)
+			So(mf.Intro, ShouldBeNil)
+			So(mf.OutroWin, ShouldBeNil)
+			So(mf.OutroLoose, ShouldBeNil)
+		})
+	})
+}
+
+func TestCopyEntities(t *testing.T) {
+	Convey("Given a mission with linked entities", t, func() {
+		marker := &Marker{Name: "m"}
+		leader := &Vehicle{Name: "leader", IsLeader: true, Markers: []*Marker{marker}}
+		car := &Vehicle{Name: "car", Skill: Number(0.6), Fuel: Number(1)}
+		w := &Waypoint{Type: WaypointGetIn, Vehicle: car}
+		other := &Waypoint{Type: WaypointMove}
+		s := &Sensor{Name: "trigger", Vehicle: leader}
+		w.SyncSensor(s)
+		w.SyncWaypoint(other)
+		g := &Group{Side: SideWest, Units: []*Vehicle{leader}, Waypoints: []*Waypoint{w, other}}
+		mission := &Mission{Groups: []*Group{g}, Vehicles: []*Vehicle{car}, Markers: []*Marker{marker}, Sensors: []*Sensor{s}}
+
+		Convey("When copying the group and the sensor to another stage", func() {
+			intro := &Mission{}
+			copies := intro.CopyEntities(Entities{Groups: []*Group{g}, Sensors: []*Sensor{s}})
+
+			Convey("The copies are added to the stage", func() {
+				So(intro.Groups, ShouldResemble, copies.Groups)
+				So(intro.Sensors, ShouldResemble, copies.Sensors)
+				So(copies.Groups[0] == g, ShouldBeFalse)
+				So(copies.Groups[0].Units[0] == leader, ShouldBeFalse)
+				So(copies.Groups[0].Units[0].Name, ShouldEqual, "leader")
+			})
+			Convey("References between copies are remapped", func() {
+				cw := copies.Groups[0].Waypoints[0]
+				cs := copies.Sensors[0]
+				So(cw.SyncSensors, ShouldHaveLength, 1)
+				So(cw.SyncSensors[0] == cs, ShouldBeTrue)
+				So(cw.SyncWaypoints[0] == copies.Groups[0].Waypoints[1], ShouldBeTrue)
+				So(cs.Vehicle == copies.Groups[0].Units[0], ShouldBeTrue)
+			})
+			Convey("References to entities not copied are dropped", func() {
+				So(copies.Groups[0].Waypoints[0].Vehicle, ShouldBeNil)
+				So(copies.Groups[0].Units[0].Markers, ShouldBeEmpty)
+				So(intro.DanglingReferences(), ShouldBeEmpty)
+			})
+			Convey("The originals are unchanged", func() {
+				So(w.SyncSensors, ShouldHaveLength, 1)
+				So(w.SyncSensors[0] == s, ShouldBeTrue)
+				So(s.SyncWaypoints, ShouldHaveLength, 1)
+				So(leader.Markers, ShouldHaveLength, 1)
+			})
+		})
+		Convey("When copying the group inside the mission", func() {
+			copies := mission.CopyEntities(Entities{Groups: []*Group{g}})
+
+			Convey("References to entities of the mission are kept", func() {
+				cw := copies.Groups[0].Waypoints[0]
+				So(cw.Vehicle == car, ShouldBeTrue)
+				So(cw.SyncSensors[0] == s, ShouldBeTrue)
+				So(s.SyncWaypoints, ShouldHaveLength, 2)
+				So(copies.Groups[0].Units[0].Markers[0] == marker, ShouldBeTrue)
+			})
+		})
+		Convey("When copying a vehicle and editing the copy", func() {
+			copies := mission.CopyEntities(Entities{Vehicles: []*Vehicle{car}})
+			c := copies.Vehicles[0]
+			*c.Skill = 0.9
+			*c.Fuel = 0
+
+			Convey("The source vehicle is unchanged", func() {
+				So(*car.Skill, ShouldEqual, 0.6)
+				So(*car.Fuel, ShouldEqual, 1)
+				So(c.Health, ShouldBeNil)
+			})
+		})
+		Convey("When moving the group to another stage", func() {
+			intro := &Mission{}
+			mission.MoveEntities(intro, Entities{Groups: []*Group{g}})
+
+			Convey("The group is moved", func() {
+				So(mission.Groups, ShouldBeEmpty)
+				So(intro.Groups, ShouldHaveLength, 1)
+				So(intro.Groups[0] == g, ShouldBeTrue)
+			})
+			Convey("References across the stages are removed on both sides", func() {
+				So(w.SyncSensors, ShouldBeEmpty)
+				So(s.SyncWaypoints, ShouldBeEmpty)
+				So(s.Vehicle, ShouldBeNil)
+				So(w.Vehicle, ShouldBeNil)
+				So(leader.Markers, ShouldBeEmpty)
+				So(w.SyncWaypoints[0] == other, ShouldBeTrue)
+				So(mission.DanglingReferences(), ShouldBeEmpty)
+				So(intro.DanglingReferences(), ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,130 @@
+// Package stats summarizes mission files: entity counts per side, class,
+// stage and addon, player slots, group sizes, sensors, markers and the size
+// of init scripts.
+package stats
+
+import (
+	"github.com/blang/gosqm"
+	"github.com/blang/gosqm/orbat"
+)
+
+// Unmapped is the addon key of vehicles whose classname is not in the
+// addon database.
+const Unmapped = "(unmapped)"
+
+// Stats is the summary of a mission file, counts include all stages.
+type Stats struct {
+	Stages           map[string]*Stage `json:"stages"`
+	Sides            map[string]int    `json:"sides"`
+	Classnames       map[string]int    `json:"classnames"`
+	Addons           map[string]int    `json:"addons,omitempty"`
+	PlayerSlots      int               `json:"playerSlots"`
+	Groups           int               `json:"groups"`
+	Units            int               `json:"units"`
+	Vehicles         int               `json:"vehicles"`
+	Waypoints        int               `json:"waypoints"`
+	AverageGroupSize float64           `json:"averageGroupSize"`
+	AverageWaypoints float64           `json:"averageWaypoints"`
+	Markers          map[string]int    `json:"markers"`
+	Sensors          map[string]int    `json:"sensors"`
+	SensorTypes      map[string]int    `json:"sensorTypes"`
+	InitLength       int               `json:"initLength"`
+}
+
+// Stage holds the entity counts of one stage.
+type Stage struct {
+	Groups    int `json:"groups"`
+	Units     int `json:"units"`
+	Vehicles  int `json:"vehicles"`
+	Waypoints int `json:"waypoints"`
+	Markers   int `json:"markers"`
+	Sensors   int `json:"sensors"`
+}
+
+// Options configure the statistics. Units and vehicles are counted per
+// addon if AddonDB is set.
+type Options struct {
+	AddonDB gosqm.AddonDB
+}
+
+// New computes the statistics of a mission file.
+// Sides counts group members by the side of their group and empty vehicles
+// by their own side, Classnames and Addons count both. Markers are counted
+// by type, area markers without type by shape. Sensors are counted by
+// activation and SensorTypes by activation type.
+func New(mf *gosqm.MissionFile, options ...Options) *Stats {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	s := &Stats{
+		Stages:      make(map[string]*Stage),
+		Sides:       make(map[string]int),
+		Classnames:  make(map[string]int),
+		Markers:     make(map[string]int),
+		Sensors:     make(map[string]int),
+		SensorTypes: make(map[string]int),
+	}
+	if opts.AddonDB != nil {
+		s.Addons = make(map[string]int)
+	}
+	vehicle := func(v *gosqm.Vehicle, side gosqm.Side) {
+		s.Sides[side.String()]++
+		s.Classnames[v.Classname]++
+		s.InitLength += len(v.Init)
+		if s.Addons != nil {
+			addon, found := opts.AddonDB.Lookup(v.Classname)
+			if !found {
+				addon = Unmapped
+			}
+			s.Addons[addon]++
+		}
+	}
+	for _, stage := range mf.Stages() {
+		m := stage.Mission
+		st := &Stage{
+			Groups:   len(m.Groups),
+			Vehicles: len(m.Vehicles),
+			Markers:  len(m.Markers),
+			Sensors:  len(m.Sensors),
+		}
+		s.Stages[stage.Name] = st
+		for _, g := range m.Groups {
+			st.Units += len(g.Units)
+			st.Waypoints += len(g.Waypoints)
+			for _, v := range g.Units {
+				vehicle(v, g.Side)
+				if orbat.IsPlayable(v) {
+					s.PlayerSlots++
+				}
+			}
+		}
+		for _, v := range m.Vehicles {
+			side := v.Side
+			if side == "" {
+				side = gosqm.SideEmpty
+			}
+			vehicle(v, side)
+		}
+		for _, marker := range m.Markers {
+			key := marker.Type
+			if key == "" {
+				key = marker.MarkerType.String()
+			}
+			s.Markers[key]++
+		}
+		for _, sensor := range m.Sensors {
+			s.Sensors[sensor.ActivationBy.String()]++
+			s.SensorTypes[sensor.ActivationType]++
+		}
+		s.Groups += st.Groups
+		s.Units += st.Units
+		s.Vehicles += st.Vehicles
+		s.Waypoints += st.Waypoints
+	}
+	if s.Groups > 0 {
+		s.AverageGroupSize = float64(s.Units) / float64(s.Groups)
+		s.AverageWaypoints = float64(s.Waypoints) / float64(s.Groups)
+	}
+	return s
+}
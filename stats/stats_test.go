@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"bytes"
+	"github.com/blang/gosqm"
+	. "github.com/smartystreets/goconvey/convey"
+	"os"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	Convey("Given a mission file", t, func() {
+		m := &gosqm.Mission{
+			Groups: []*gosqm.Group{
+				&gosqm.Group{Side: gosqm.SideWest, Units: []*gosqm.Vehicle{
+					&gosqm.Vehicle{Classname: "USMC_Soldier", Player: "PLAYER COMMANDER", Init: "this setDamage 0;"},
+					&gosqm.Vehicle{Classname: "USMC_Soldier", Player: "PLAY CDG"},
+				}, Waypoints: []*gosqm.Waypoint{&gosqm.Waypoint{}, &gosqm.Waypoint{}, &gosqm.Waypoint{}}},
+				&gosqm.Group{Side: gosqm.SideEast, Units: []*gosqm.Vehicle{&gosqm.Vehicle{Classname: "RU_Soldier"}}},
+			},
+			Vehicles: []*gosqm.Vehicle{&gosqm.Vehicle{Classname: "HMMWV", Init: "clearWeaponCargo this;"}},
+			Markers: []*gosqm.Marker{
+				&gosqm.Marker{Type: "mil_dot"},
+				&gosqm.Marker{MarkerType: gosqm.MarkerShapeRectangle},
+			},
+			Sensors: []*gosqm.Sensor{&gosqm.Sensor{ActivationBy: gosqm.ActivationBy("WEST"), ActivationType: "PRESENT"}},
+		}
+		intro := &gosqm.Mission{Groups: []*gosqm.Group{&gosqm.Group{Side: gosqm.SideWest, Units: []*gosqm.Vehicle{&gosqm.Vehicle{Classname: "USMC_Soldier"}}}}}
+		mf := &gosqm.MissionFile{Mission: m, Intro: intro}
+
+		Convey("Entities are counted", func() {
+			s := New(mf)
+			So(s.Sides, ShouldResemble, map[string]int{"WEST": 3, "EAST": 1, "EMPTY": 1})
+			So(s.Classnames["USMC_Soldier"], ShouldEqual, 3)
+			So(s.Stages["Mission"].Units, ShouldEqual, 3)
+			So(s.Stages["Intro"].Groups, ShouldEqual, 1)
+			So(s.Stages["OutroWin"], ShouldBeNil)
+			So(s.PlayerSlots, ShouldEqual, 2)
+			So(s.Groups, ShouldEqual, 3)
+			So(s.AverageGroupSize, ShouldAlmostEqual, 4.0/3, 1e-9)
+			So(s.AverageWaypoints, ShouldEqual, 1)
+			So(s.Markers, ShouldResemble, map[string]int{"mil_dot": 1, "RECTANGLE": 1})
+			So(s.Sensors, ShouldResemble, map[string]int{"WEST": 1})
+			So(s.SensorTypes, ShouldResemble, map[string]int{"PRESENT": 1})
+			So(s.InitLength, ShouldEqual, 39)
+			So(s.Addons, ShouldBeNil)
+		})
+		Convey("Addons are counted with a database", func() {
+			db := gosqm.AddonDB{}
+			db.Add("USMC_Soldier", "CACharacters2")
+			s := New(mf, Options{AddonDB: db})
+			So(s.Addons, ShouldResemble, map[string]int{"CACharacters2": 3, Unmapped: 2})
+		})
+		Convey("Statistics are written as text and JSON", func() {
+			s := New(mf)
+			var buf bytes.Buffer
+			So(s.WriteText(&buf), ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, "Groups: 3 (1.33 units, 1 waypoints on average)\n")
+			buf.Reset()
+			So(s.WriteJSON(&buf), ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, `"playerSlots": 2`)
+		})
+	})
+	Convey("Given the test mission", t, func() {
+		f, err := os.Open("../testdata/mission.sqm")
+		So(err, ShouldBeNil)
+		defer f.Close()
+		mf, err := gosqm.NewDecoder(f).Decode()
+		So(err, ShouldBeNil)
+		So(New(mf).PlayerSlots, ShouldBeGreaterThan, 0)
+	})
+}
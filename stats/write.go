@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/blang/gosqm"
+	"io"
+	"sort"
+)
+
+// WriteJSON writes the statistics as indented JSON.
+func (s *Stats) WriteJSON(w io.Writer) error {
+	buf, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
+
+// WriteText writes a human readable summary, counts are sorted by name.
+func (s *Stats) WriteText(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	printf("Player slots: %d\n", s.PlayerSlots)
+	printf("Groups: %d (%s units, %s waypoints on average)\n", s.Groups, gosqm.FormatNumber(round(s.AverageGroupSize)), gosqm.FormatNumber(round(s.AverageWaypoints)))
+	printf("Units: %d\nVehicles: %d\nWaypoints: %d\n", s.Units, s.Vehicles, s.Waypoints)
+	printf("Init scripts: %d characters\n", s.InitLength)
+
+	printf("Stages:\n")
+	for _, name := range gosqm.StageNames {
+		if st, found := s.Stages[name]; found {
+			printf("  %-12s %d groups, %d units, %d vehicles, %d waypoints, %d markers, %d sensors\n", name, st.Groups, st.Units, st.Vehicles, st.Waypoints, st.Markers, st.Sensors)
+		}
+	}
+	counts := func(title string, m map[string]int) {
+		if len(m) == 0 {
+			return
+		}
+		printf("%s:\n", title)
+		var keys []string
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if k == "" {
+				printf("  %-30s %d\n", "(none)", m[k])
+			} else {
+				printf("  %-30s %d\n", k, m[k])
+			}
+		}
+	}
+	counts("Sides", s.Sides)
+	counts("Classnames", s.Classnames)
+	counts("Addons", s.Addons)
+	counts("Markers", s.Markers)
+	counts("Sensors", s.Sensors)
+	counts("Sensor types", s.SensorTypes)
+	return err
+}
+
+// round rounds to two decimals
+func round(f float64) float64 {
+	return float64(int(f*100+0.5)) / 100
+}
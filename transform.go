@@ -0,0 +1,177 @@
+package gosqm
+
+import (
+	"math"
+)
+
+// Transform is an affine transform of the map plane. Positions are mapped
+// to Matrix * [X, Y] + Offset, heights are only moved by Offset.Z since
+// they are relative to the terrain.
+// Angles are azimuths in degrees, clockwise from north.
+type Transform struct {
+	Matrix [2][2]float64
+	Offset Vec3
+}
+
+// Identity returns the transform which keeps every position.
+func Identity() Transform {
+	return Transform{Matrix: [2][2]float64{{1, 0}, {0, 1}}}
+}
+
+// Translation returns the transform which moves positions by v.
+func Translation(v Vec3) Transform {
+	t := Identity()
+	t.Offset = v
+	return t
+}
+
+// Rotation returns the transform which rotates positions clockwise by angle
+// degrees around center.
+func Rotation(center Vec3, angle float64) Transform {
+	sin, cos := sinCos(angle)
+	return around(center, [2][2]float64{{cos, sin}, {-sin, cos}})
+}
+
+// Mirror returns the transform which mirrors positions at the axis through
+// p with the given azimuth, e.g. 0 mirrors east and west.
+func Mirror(p Vec3, azimuth float64) Transform {
+	sin, cos := sinCos(2 * azimuth)
+	return around(p, [2][2]float64{{-cos, sin}, {sin, cos}})
+}
+
+// Scaling returns the transform which scales positions by f relative to
+// center.
+func Scaling(center Vec3, f float64) Transform {
+	return around(center, [2][2]float64{{f, 0}, {0, f}})
+}
+
+// around returns the transform applying m relative to center
+func around(center Vec3, m [2][2]float64) Transform {
+	t := Transform{Matrix: m}
+	c := t.mul(center.XY())
+	t.Offset = Vec3{center.X - c.X, center.Y - c.Y, 0}
+	return t
+}
+
+// sinCos returns sine and cosine of an angle in degrees, exact for
+// multiples of 90
+func sinCos(angle float64) (float64, float64) {
+	switch wrapAngle(angle) {
+	case 0:
+		return 0, 1
+	case 90:
+		return 1, 0
+	case 180:
+		return 0, -1
+	case 270:
+		return -1, 0
+	}
+	return math.Sincos(angle * math.Pi / 180)
+}
+
+// Then returns the transform which applies t followed by o.
+func (t Transform) Then(o Transform) Transform {
+	var res Transform
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			res.Matrix[i][j] = o.Matrix[i][0]*t.Matrix[0][j] + o.Matrix[i][1]*t.Matrix[1][j]
+		}
+	}
+	off := o.mul(t.Offset.XY())
+	res.Offset = Vec3{off.X + o.Offset.X, off.Y + o.Offset.Y, t.Offset.Z + o.Offset.Z}
+	return res
+}
+
+// mul multiplies v with the matrix of the transform
+func (t Transform) mul(v Vec2) Vec2 {
+	return Vec2{
+		t.Matrix[0][0]*v.X + t.Matrix[0][1]*v.Y,
+		t.Matrix[1][0]*v.X + t.Matrix[1][1]*v.Y,
+	}
+}
+
+// Apply returns the transformed position.
+func (t Transform) Apply(p Vec3) Vec3 {
+	v := t.mul(p.XY())
+	return Vec3{v.X + t.Offset.X, v.Y + t.Offset.Y, p.Z + t.Offset.Z}
+}
+
+// ApplyAngle returns the transformed azimuth in the range [0, 360).
+func (t Transform) ApplyAngle(angle float64) float64 {
+	sin, cos := sinCos(angle)
+	return azimuth(t.mul(Vec2{sin, cos}))
+}
+
+// ApplyArea returns the transformed size and angle of a marker or sensor
+// area. Size is the extent along the area's own axes, the sizes are scaled
+// by the length of the transformed axes. The result is exact for rotations,
+// mirrors and uniform scalings.
+func (t Transform) ApplyArea(size Vec2, angle float64) (Vec2, float64) {
+	sin, cos := sinCos(angle)
+	right := t.mul(Vec2{cos, -sin})
+	up := t.mul(Vec2{sin, cos})
+	return Vec2{size.X * right.Len(), size.Y * up.Len()}, azimuth(up)
+}
+
+// azimuth returns the direction of v in degrees, rounded to remove the
+// floating point error of the transform
+func azimuth(v Vec2) float64 {
+	if v.X == 0 && v.Y == 0 {
+		return 0
+	}
+	a := math.Atan2(v.X, v.Y) * 180 / math.Pi
+	return wrapAngle(math.Round(a*1e9) / 1e9)
+}
+
+// wrapAngle returns the angle in degrees in the range [0, 360)
+func wrapAngle(a float64) float64 {
+	a = math.Mod(a, 360)
+	if a < 0 {
+		a += 360
+	}
+	return a
+}
+
+// Transform moves the vehicle and turns it.
+func (v *Vehicle) Transform(t Transform) {
+	v.Position = t.Apply(v.Position)
+	v.Angle = t.ApplyAngle(v.Angle)
+}
+
+// Transform moves the units and waypoints of the group.
+func (g *Group) Transform(t Transform) {
+	for _, v := range g.Units {
+		v.Transform(t)
+	}
+	for _, w := range g.Waypoints {
+		w.Position = t.Apply(w.Position)
+	}
+}
+
+// Transform moves the marker and transforms its area.
+func (m *Marker) Transform(t Transform) {
+	m.Position = t.Apply(m.Position)
+	m.Size, m.Angle = t.ApplyArea(m.Size, m.Angle)
+}
+
+// Transform moves the sensor and transforms its area.
+func (s *Sensor) Transform(t Transform) {
+	s.Position = t.Apply(s.Position)
+	s.Size, s.Angle = t.ApplyArea(s.Size, s.Angle)
+}
+
+// Transform applies t to all entities.
+func (e Entities) Transform(t Transform) {
+	for _, g := range e.Groups {
+		g.Transform(t)
+	}
+	for _, v := range e.Vehicles {
+		v.Transform(t)
+	}
+	for _, m := range e.Markers {
+		m.Transform(t)
+	}
+	for _, s := range e.Sensors {
+		s.Transform(t)
+	}
+}
@@ -0,0 +1,77 @@
+package gosqm
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	Convey("Given a position", t, func() {
+		p := Vec3{110, 100, 5}
+		center := Vec3{100, 100, 0}
+
+		Convey("Translations move it", func() {
+			So(Translation(Vec3{1, 2, 3}).Apply(p), ShouldResemble, Vec3{111, 102, 8})
+		})
+		Convey("Rotations are clockwise around the center", func() {
+			So(Rotation(center, 90).Apply(p), ShouldResemble, Vec3{100, 90, 5})
+			So(Rotation(center, -90).Apply(p), ShouldResemble, Vec3{100, 110, 5})
+		})
+		Convey("Mirrors flip at the axis", func() {
+			So(Mirror(center, 0).Apply(p), ShouldResemble, Vec3{90, 100, 5})
+			So(Mirror(center, 90).Apply(Vec3{100, 120, 0}), ShouldResemble, Vec3{100, 80, 0})
+		})
+		Convey("Scalings keep the height", func() {
+			So(Scaling(center, 2).Apply(p), ShouldResemble, Vec3{120, 100, 5})
+		})
+		Convey("Transforms are applied in order", func() {
+			t := Rotation(Vec3{}, 90).Then(Translation(Vec3{10, 0, 0}))
+			So(t.Apply(Vec3{0, 1, 0}), ShouldResemble, Vec3{11, 0, 0})
+			So(Identity().Then(t), ShouldResemble, t)
+		})
+	})
+	Convey("Given azimuths", t, func() {
+		Convey("Rotations wrap around 360", func() {
+			So(Rotation(Vec3{}, 90).ApplyAngle(300), ShouldEqual, 30)
+			So(Rotation(Vec3{}, -45).ApplyAngle(10), ShouldEqual, 325)
+		})
+		Convey("Mirrors reflect the direction", func() {
+			So(Mirror(Vec3{}, 0).ApplyAngle(45), ShouldEqual, 315)
+			So(Mirror(Vec3{}, 90).ApplyAngle(45), ShouldEqual, 135)
+		})
+		Convey("Scalings keep the direction", func() {
+			So(Scaling(Vec3{}, 3).ApplyAngle(123.5), ShouldAlmostEqual, 123.5, 1e-9)
+		})
+		Convey("Areas are rotated and scaled", func() {
+			size, angle := Rotation(Vec3{}, 30).Then(Scaling(Vec3{}, 2)).ApplyArea(Vec2{10, 20}, 340)
+			So(size.X, ShouldAlmostEqual, 20, 1e-9)
+			So(size.Y, ShouldAlmostEqual, 40, 1e-9)
+			So(angle, ShouldEqual, 10)
+		})
+		Convey("Angles are wrapped into [0, 360)", func() {
+			So(wrapAngle(370), ShouldEqual, 10)
+			So(wrapAngle(-90), ShouldEqual, 270)
+			So(wrapAngle(360), ShouldEqual, 0)
+		})
+	})
+	Convey("Given entities", t, func() {
+		v := &Vehicle{Position: Vec3{10, 0, 0}, Angle: 90}
+		g := &Group{Units: []*Vehicle{v}, Waypoints: []*Waypoint{&Waypoint{Position: Vec3{0, 10, 0}}}}
+		m := &Marker{Position: Vec3{0, -10, 0}, Size: Vec2{5, 1}, Angle: 0}
+		s := &Sensor{Position: Vec3{-10, 0, 0}, Size: Vec2{50, 50}, Angle: 180}
+		Entities{Groups: []*Group{g}, Markers: []*Marker{m}, Sensors: []*Sensor{s}}.Transform(Mirror(Vec3{}, 0))
+
+		Convey("Every position is transformed", func() {
+			So(v.Position, ShouldResemble, Vec3{-10, 0, 0})
+			So(g.Waypoints[0].Position, ShouldResemble, Vec3{0, 10, 0})
+			So(m.Position, ShouldResemble, Vec3{0, -10, 0})
+			So(s.Position, ShouldResemble, Vec3{10, 0, 0})
+		})
+		Convey("Every angle is transformed", func() {
+			So(v.Angle, ShouldEqual, 270)
+			So(m.Angle, ShouldEqual, 0)
+			So(m.Size, ShouldResemble, Vec2{5, 1})
+			So(s.Angle, ShouldEqual, 180)
+		})
+	})
+}
@@ -0,0 +1,210 @@
+package gosqm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Vec3 is a position in metres. X points east, Y north and Z up.
+// Mission files store positions in the order [X, Z, Y].
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+// Vec2 is a position or extent on the map plane.
+type Vec2 struct {
+	X, Y float64
+}
+
+func (v Vec3) Add(o Vec3) Vec3 {
+	return Vec3{v.X + o.X, v.Y + o.Y, v.Z + o.Z}
+}
+
+func (v Vec3) Sub(o Vec3) Vec3 {
+	return Vec3{v.X - o.X, v.Y - o.Y, v.Z - o.Z}
+}
+
+func (v Vec3) Scale(f float64) Vec3 {
+	return Vec3{v.X * f, v.Y * f, v.Z * f}
+}
+
+// Len returns the length of the vector.
+func (v Vec3) Len() float64 {
+	return math.Sqrt(v.X*v.X + v.Y*v.Y + v.Z*v.Z)
+}
+
+// Dist returns the distance between two positions.
+func (v Vec3) Dist(o Vec3) float64 {
+	return v.Sub(o).Len()
+}
+
+// Dist2D returns the distance between two positions on the map plane,
+// ignoring the height.
+func (v Vec3) Dist2D(o Vec3) float64 {
+	return v.XY().Dist(o.XY())
+}
+
+// XY returns the position on the map plane.
+func (v Vec3) XY() Vec2 {
+	return Vec2{v.X, v.Y}
+}
+
+func (v Vec3) String() string {
+	return fmt.Sprintf("[%s, %s, %s]", FormatNumber(v.X), FormatNumber(v.Y), FormatNumber(v.Z))
+}
+
+func (v Vec2) Add(o Vec2) Vec2 {
+	return Vec2{v.X + o.X, v.Y + o.Y}
+}
+
+func (v Vec2) Sub(o Vec2) Vec2 {
+	return Vec2{v.X - o.X, v.Y - o.Y}
+}
+
+func (v Vec2) Scale(f float64) Vec2 {
+	return Vec2{v.X * f, v.Y * f}
+}
+
+// Len returns the length of the vector.
+func (v Vec2) Len() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+// Dist returns the distance between two positions.
+func (v Vec2) Dist(o Vec2) float64 {
+	return v.Sub(o).Len()
+}
+
+func (v Vec2) String() string {
+	return fmt.Sprintf("[%s, %s]", FormatNumber(v.X), FormatNumber(v.Y))
+}
+
+// parseVec3 parses a position array in file order [X, Z, Y]
+func parseVec3(values []string) (Vec3, error) {
+	if len(values) != 3 {
+		return Vec3{}, fmt.Errorf("Expected 3 values but got %d", len(values))
+	}
+	var f [3]float64
+	for i, val := range values {
+		var err error
+		if f[i], err = parseNumber(val); err != nil {
+			return Vec3{}, err
+		}
+	}
+	return Vec3{X: f[0], Z: f[1], Y: f[2]}, nil
+}
+
+// formatVec3 formats a position in file order [X, Z, Y]
+func formatVec3(v Vec3) []string {
+	return []string{FormatNumber(v.X), FormatNumber(v.Z), FormatNumber(v.Y)}
+}
+
+// Number returns a pointer to f, for the optional number fields of
+// entities like Vehicle.Fuel.
+func Number(f float64) *float64 {
+	return &f
+}
+
+// numberDigits is the number of significant digits written by the editor
+const numberDigits = 8
+
+// FormatNumber formats a number the way the mission editor does: single
+// precision printed with up to 8 significant digits, trailing zeros removed
+// and a three digit exponent for very small or large values.
+// Numbers read from a file which already have this form are written back
+// unchanged.
+func FormatNumber(f float64) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "0"
+	}
+	digits, exp, neg := decimal(strconv.FormatFloat(f, 'e', -1, 64))
+	if len(digits) > numberDigits {
+		single := float64(float32(f))
+		if math.IsInf(single, 0) {
+			single = f
+		}
+		digits, exp, neg = decimal(strconv.FormatFloat(single, 'e', 40, 64))
+		digits, exp = roundHalfAway(digits, exp, numberDigits)
+	}
+	digits = strings.TrimRight(digits, "0")
+	if digits == "" {
+		return "0"
+	}
+
+	var s string
+	if exp < -4 || exp >= numberDigits {
+		s = digits[:1]
+		if len(digits) > 1 {
+			s += "." + digits[1:]
+		}
+		sign := "+"
+		if exp < 0 {
+			sign = "-"
+			exp = -exp
+		}
+		s += fmt.Sprintf("e%s%03d", sign, exp)
+	} else if exp < 0 {
+		s = "0." + strings.Repeat("0", -exp-1) + digits
+	} else if len(digits) <= exp+1 {
+		s = digits + strings.Repeat("0", exp+1-len(digits))
+	} else {
+		s = digits[:exp+1] + "." + digits[exp+1:]
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// decimal splits a number in %e format into its significant digits and
+// the decimal exponent of the first digit
+func decimal(s string) (digits string, exp int, neg bool) {
+	if s[0] == '-' {
+		neg = true
+		s = s[1:]
+	}
+	e := strings.IndexByte(s, 'e')
+	exp, _ = strconv.Atoi(s[e+1:])
+	digits = strings.Replace(s[:e], ".", "", 1)
+	return
+}
+
+// roundHalfAway rounds digits to n significant digits, halves are rounded
+// away from zero like the C runtime does
+func roundHalfAway(digits string, exp int, n int) (string, int) {
+	if len(digits) <= n {
+		return digits, exp
+	}
+	round := digits[n] >= '5'
+	b := []byte(digits[:n])
+	if round {
+		i := n - 1
+		for ; i >= 0; i-- {
+			if b[i] == '9' {
+				b[i] = '0'
+				continue
+			}
+			b[i]++
+			break
+		}
+		if i < 0 {
+			b = append([]byte{'1'}, b[:n-1]...)
+			exp++
+		}
+	}
+	return string(b), exp
+}
+
+// parseNumber parses a number property, an empty value is zero
+func parseNumber(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return 0, fmt.Errorf("Number %q is not finite", s)
+	}
+	return f, err
+}
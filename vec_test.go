@@ -0,0 +1,68 @@
+package gosqm
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"regexp"
+	"testing"
+)
+
+func TestVec(t *testing.T) {
+	Convey("Given two positions", t, func() {
+		a := Vec3{X: 1, Y: 2, Z: 3}
+		b := Vec3{X: 4, Y: 6, Z: 15}
+		Convey("Arithmetic is component wise", func() {
+			So(a.Add(b), ShouldResemble, Vec3{5, 8, 18})
+			So(b.Sub(a), ShouldResemble, Vec3{3, 4, 12})
+			So(a.Scale(2), ShouldResemble, Vec3{2, 4, 6})
+		})
+		Convey("Distances are correct", func() {
+			So(a.Dist(b), ShouldEqual, 13)
+			So(a.Dist2D(b), ShouldEqual, 5)
+			So(a.XY(), ShouldResemble, Vec2{1, 2})
+			So(Vec2{3, 4}.Len(), ShouldEqual, 5)
+		})
+	})
+	Convey("Given a position array", t, func() {
+		Convey("Values are read in order X, Z, Y", func() {
+			v, err := parseVec3([]string{"1", "2", "3"})
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, Vec3{X: 1, Y: 3, Z: 2})
+			So(formatVec3(v), ShouldResemble, []string{"1", "2", "3"})
+		})
+		Convey("Invalid arrays return an error", func() {
+			_, err := parseVec3([]string{"1", "2"})
+			So(err, ShouldNotBeNil)
+			_, err = parseVec3([]string{"1", "a", "2"})
+			So(err, ShouldNotBeNil)
+			_, err = parseVec3([]string{"1", "NaN", "Inf"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestFormatNumber(t *testing.T) {
+	Convey("Numbers are formatted like the editor does", t, func() {
+		So(FormatNumber(0), ShouldEqual, "0")
+		So(FormatNumber(1), ShouldEqual, "1")
+		So(FormatNumber(-12.5), ShouldEqual, "-12.5")
+		So(FormatNumber(0.3), ShouldEqual, "0.3")
+		So(FormatNumber(1e8), ShouldEqual, "1e+008")
+		So(FormatNumber(0.00001), ShouldEqual, "1e-005")
+		So(FormatNumber(8148.78125), ShouldEqual, "8148.7813")
+		So(FormatNumber(1.0/3), ShouldEqual, "0.33333334")
+		So(FormatNumber(float64(float32(0.6))), ShouldEqual, "0.60000002")
+		So(FormatNumber(1.234567891e300), ShouldEqual, "1.2345679e+300")
+	})
+	Convey("Numbers in a mission file round-trip unchanged", t, func() {
+		b, err := ioutil.ReadFile("testdata/mission.sqm")
+		So(err, ShouldBeNil)
+		numbers := regexp.MustCompile(`-?[0-9]+(\.[0-9]+)?(e[-+][0-9]+)?`).FindAllString(string(b), -1)
+		So(numbers, ShouldNotBeEmpty)
+		for _, s := range numbers {
+			f, err := parseNumber(s)
+			So(err, ShouldBeNil)
+			So(FormatNumber(f), ShouldEqual, s)
+		}
+	})
+}